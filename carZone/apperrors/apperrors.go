@@ -0,0 +1,129 @@
+package apperrors
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Sentinel errors describing the kind of failure. Every *Error wraps exactly
+// one of them so callers can use errors.Is to decide how to react.
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrValidation   = errors.New("validation failed")
+	ErrConflict     = errors.New("conflict")
+	ErrForeignKey   = errors.New("foreign key violation")
+	ErrBadRequest   = errors.New("bad request")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrPrecondition = errors.New("precondition failed")
+	ErrMediaType    = errors.New("unsupported media type")
+	ErrInternal     = errors.New("internal error")
+)
+
+// Error is the typed error produced by the store and service layers.
+type Error struct {
+	Kind    error
+	Message string
+	Field   string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	msg := e.Message
+	if e.Field != "" {
+		msg = fmt.Sprintf("%s: %s", e.Field, msg)
+	}
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", msg, e.Err)
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() []error {
+	if e.Err != nil {
+		return []error{e.Kind, e.Err}
+	}
+	return []error{e.Kind}
+}
+
+// Code returns the machine readable code for the error kind.
+func (e *Error) Code() string {
+	return codeOf(e.Kind)
+}
+
+func NotFound(message string) *Error {
+	return &Error{Kind: ErrNotFound, Message: message}
+}
+
+func Validation(field, message string) *Error {
+	return &Error{Kind: ErrValidation, Message: message, Field: field}
+}
+
+func Conflict(message string, err error) *Error {
+	return &Error{Kind: ErrConflict, Message: message, Err: err}
+}
+
+func ForeignKey(field, message string) *Error {
+	return &Error{Kind: ErrForeignKey, Message: message, Field: field}
+}
+
+func BadRequest(message string, err error) *Error {
+	return &Error{Kind: ErrBadRequest, Message: message, Err: err}
+}
+
+func Unauthorized(message string) *Error {
+	return &Error{Kind: ErrUnauthorized, Message: message}
+}
+
+func Forbidden(message string) *Error {
+	return &Error{Kind: ErrForbidden, Message: message}
+}
+
+// PreconditionFailed reports that the row changed since the version the
+// caller based its request on.
+func PreconditionFailed(message string) *Error {
+	return &Error{Kind: ErrPrecondition, Message: message}
+}
+
+func UnsupportedMediaType(message string) *Error {
+	return &Error{Kind: ErrMediaType, Message: message}
+}
+
+func Internal(err error) *Error {
+	return &Error{Kind: ErrInternal, Message: "internal server error", Err: err}
+}
+
+// As extracts the *Error from err. Errors that are not typed are reported as
+// internal errors.
+func As(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return Internal(err)
+}
+
+func codeOf(kind error) string {
+	switch kind {
+	case ErrNotFound:
+		return "NOT_FOUND"
+	case ErrValidation:
+		return "VALIDATION_FAILED"
+	case ErrConflict:
+		return "CONFLICT"
+	case ErrForeignKey:
+		return "FOREIGN_KEY_VIOLATION"
+	case ErrBadRequest:
+		return "BAD_REQUEST"
+	case ErrUnauthorized:
+		return "UNAUTHORIZED"
+	case ErrForbidden:
+		return "FORBIDDEN"
+	case ErrPrecondition:
+		return "PRECONDITION_FAILED"
+	case ErrMediaType:
+		return "UNSUPPORTED_MEDIA_TYPE"
+	default:
+		return "INTERNAL"
+	}
+}
@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/ayushi-khandal09/carZone/models"
+)
+
+// Principal is the authenticated caller of a request. Users logging in with a
+// token have a Role, machine clients using an API key have explicit Scopes.
+type Principal struct {
+	Subject  string
+	Username string
+	Role     models.Role
+	Scopes   []string
+}
+
+// HasScope reports whether the principal may perform actions guarded by
+// scope. A role grants the scopes returned by Role.Scopes.
+func (p Principal) HasScope(scope string) bool {
+	if p.Role != "" {
+		return models.HasScope(p.Role.Scopes(), scope)
+	}
+	return models.HasScope(p.Scopes, scope)
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFrom returns the principal stored in ctx, if any.
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
@@ -0,0 +1,100 @@
+// Package auth issues and verifies the JWT access tokens used by the API and
+// carries the authenticated principal through request contexts.
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ayushi-khandal09/carZone/models"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type Claims struct {
+	Username string      `json:"username"`
+	Role     models.Role `json:"role"`
+	jwt.RegisteredClaims
+}
+
+// TokenManager signs and verifies access tokens with either an HMAC secret
+// (HS256) or an RSA key pair (RS256).
+type TokenManager struct {
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+	issuer    string
+	ttl       time.Duration
+}
+
+func NewHS256(secret []byte, issuer string, ttl time.Duration) (*TokenManager, error) {
+	if len(secret) < 32 {
+		return nil, errors.New("HS256 secret must be at least 32 bytes")
+	}
+	return &TokenManager{
+		method:    jwt.SigningMethodHS256,
+		signKey:   secret,
+		verifyKey: secret,
+		issuer:    issuer,
+		ttl:       ttl,
+	}, nil
+}
+
+// NewRS256 builds a manager from a PEM encoded RSA private key. The public
+// half of the key is used to verify tokens.
+func NewRS256(privateKeyPEM []byte, issuer string, ttl time.Duration) (*TokenManager, error) {
+	key, err := jwt.ParseRSAPrivateKeyFromPEM(privateKeyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("parsing RSA private key: %w", err)
+	}
+	return &TokenManager{
+		method:    jwt.SigningMethodRS256,
+		signKey:   key,
+		verifyKey: &key.PublicKey,
+		issuer:    issuer,
+		ttl:       ttl,
+	}, nil
+}
+
+// TTL is how long issued tokens stay valid.
+func (m *TokenManager) TTL() time.Duration {
+	return m.ttl
+}
+
+func (m *TokenManager) Issue(user models.User) (string, error) {
+	if m.signKey == nil {
+		return "", errors.New("token manager cannot sign tokens")
+	}
+	now := time.Now()
+	claims := Claims{
+		Username: user.Username,
+		Role:     user.Role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   user.ID.String(),
+			Issuer:    m.issuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(m.ttl)),
+		},
+	}
+	return jwt.NewWithClaims(m.method, claims).SignedString(m.signKey)
+}
+
+// Verify checks the signature, algorithm, issuer and expiry of token.
+func (m *TokenManager) Verify(token string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+		return m.verifyKey, nil
+	},
+		jwt.WithValidMethods([]string{m.method.Alg()}),
+		jwt.WithIssuer(m.issuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if !claims.Role.Valid() {
+		return nil, errors.New("token has an unknown role")
+	}
+	return claims, nil
+}
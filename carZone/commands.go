@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/ayushi-khandal09/carZone/migrate"
+)
+
+const migrateUsage = "usage: carzone migrate up|down [steps]|status|redo"
+
+// runMigrate implements the `carzone migrate` subcommand.
+func runMigrate(ctx context.Context, migrator *migrate.Migrator, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("No pending migrations")
+		}
+		for _, m := range applied {
+			fmt.Printf("Applied %d_%s\n", m.Version, m.Name)
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n <= 0 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+			steps = n
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		if len(reverted) == 0 {
+			fmt.Println("No applied migrations")
+		}
+		for _, m := range reverted {
+			fmt.Printf("Reverted %d_%s\n", m.Version, m.Name)
+		}
+	case "redo":
+		m, err := migrator.Redo(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Redone %d_%s\n", m.Version, m.Name)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, s := range statuses {
+			state, appliedAt := "pending", ""
+			if s.Applied {
+				state = "applied"
+				appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if s.Modified {
+				state = "modified"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt)
+		}
+		return w.Flush()
+	default:
+		return errors.New(migrateUsage)
+	}
+	return nil
+}
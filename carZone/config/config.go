@@ -0,0 +1,318 @@
+// Package config loads the service configuration from defaults, an optional
+// YAML or TOML file named by CONFIG_FILE, and the environment, in increasing
+// order of precedence. The .env file is read into the environment first and
+// never overrides variables that are already set.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/ayushi-khandal09/carZone/logging"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	StoreBackend string         `yaml:"store_backend" toml:"store_backend" env:"STORE_BACKEND"`
+	Server       ServerConfig   `yaml:"server" toml:"server"`
+	Database     DatabaseConfig `yaml:"database" toml:"database"`
+	Log          LogConfig      `yaml:"log" toml:"log"`
+	Tracing      TracingConfig  `yaml:"tracing" toml:"tracing"`
+	Auth         AuthConfig     `yaml:"auth" toml:"auth"`
+	Trash        TrashConfig    `yaml:"trash" toml:"trash"`
+	Exchange     ExchangeConfig `yaml:"exchange" toml:"exchange"`
+	Batch        BatchConfig    `yaml:"batch" toml:"batch"`
+	Features     FeatureConfig  `yaml:"features" toml:"features"`
+}
+
+type ServerConfig struct {
+	Port              int           `yaml:"port" toml:"port" env:"PORT"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" toml:"read_timeout" env:"HTTP_READ_TIMEOUT"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" toml:"read_header_timeout" env:"HTTP_READ_HEADER_TIMEOUT"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" toml:"write_timeout" env:"HTTP_WRITE_TIMEOUT"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" toml:"idle_timeout" env:"HTTP_IDLE_TIMEOUT"`
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`
+}
+
+func (s ServerConfig) Addr() string {
+	return fmt.Sprintf(":%d", s.Port)
+}
+
+type DatabaseConfig struct {
+	Host            string        `yaml:"host" toml:"host" env:"DB_HOST"`
+	Port            int           `yaml:"port" toml:"port" env:"DB_PORT"`
+	User            string        `yaml:"user" toml:"user" env:"DB_USER"`
+	Password        string        `yaml:"password" toml:"password" env:"DB_PASSWORD" secret:"true"`
+	Name            string        `yaml:"name" toml:"name" env:"DB_NAME"`
+	SSLMode         string        `yaml:"ssl_mode" toml:"ssl_mode" env:"DB_SSLMODE"`
+	MaxOpenConns    int           `yaml:"max_open_conns" toml:"max_open_conns" env:"DB_MAX_OPEN_CONNS"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" toml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" toml:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME"`
+
+	// ConnectTimeout bounds how long start-up waits for the database, retrying
+	// with a backoff that starts at ConnectBackoff and doubles up to
+	// ConnectMaxBackoff.
+	ConnectTimeout    time.Duration `yaml:"connect_timeout" toml:"connect_timeout" env:"DB_CONNECT_TIMEOUT"`
+	ConnectBackoff    time.Duration `yaml:"connect_backoff" toml:"connect_backoff" env:"DB_CONNECT_BACKOFF"`
+	ConnectMaxBackoff time.Duration `yaml:"connect_max_backoff" toml:"connect_max_backoff" env:"DB_CONNECT_MAX_BACKOFF"`
+}
+
+// DSN returns the lib/pq keyword/value connection string.
+func (d DatabaseConfig) DSN() string {
+	parts := []string{
+		"host=" + quoteDSN(d.Host),
+		fmt.Sprintf("port=%d", d.Port),
+		"user=" + quoteDSN(d.User),
+		"password=" + quoteDSN(d.Password),
+		"dbname=" + quoteDSN(d.Name),
+		"sslmode=" + quoteDSN(d.SSLMode),
+	}
+	return strings.Join(parts, " ")
+}
+
+// quoteDSN quotes a connection string value so spaces, quotes and
+// backslashes in passwords survive.
+func quoteDSN(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
+
+type LogConfig struct {
+	Level  string `yaml:"level" toml:"level" env:"LOG_LEVEL"`
+	Format string `yaml:"format" toml:"format" env:"LOG_FORMAT"`
+}
+
+type TracingConfig struct {
+	Exporter    string `yaml:"exporter" toml:"exporter" env:"OTEL_TRACES_EXPORTER"`
+	ServiceName string `yaml:"service_name" toml:"service_name" env:"OTEL_SERVICE_NAME"`
+}
+
+type AuthConfig struct {
+	JWTAlgorithm      string        `yaml:"jwt_algorithm" toml:"jwt_algorithm" env:"JWT_ALGORITHM"`
+	JWTSecret         string        `yaml:"jwt_secret" toml:"jwt_secret" env:"JWT_SECRET" secret:"true"`
+	JWTPrivateKeyFile string        `yaml:"jwt_private_key_file" toml:"jwt_private_key_file" env:"JWT_PRIVATE_KEY_FILE"`
+	JWTIssuer         string        `yaml:"jwt_issuer" toml:"jwt_issuer" env:"JWT_ISSUER"`
+	JWTTTL            time.Duration `yaml:"jwt_ttl" toml:"jwt_ttl" env:"JWT_TTL"`
+	AdminUsername     string        `yaml:"admin_username" toml:"admin_username" env:"AUTH_ADMIN_USERNAME"`
+	AdminPassword     string        `yaml:"admin_password" toml:"admin_password" env:"AUTH_ADMIN_PASSWORD" secret:"true"`
+}
+
+// TrashConfig controls how long deleted cars and engines can be restored.
+// A PurgeInterval of 0 turns the purge job off.
+type TrashConfig struct {
+	Retention     time.Duration `yaml:"retention" toml:"retention" env:"TRASH_RETENTION"`
+	PurgeInterval time.Duration `yaml:"purge_interval" toml:"purge_interval" env:"TRASH_PURGE_INTERVAL"`
+}
+
+// ExchangeConfig points at the exchange rates used to convert listed prices.
+// Without a RatesFile prices are only shown in their own currency.
+type ExchangeConfig struct {
+	RatesFile string `yaml:"rates_file" toml:"rates_file" env:"EXCHANGE_RATES_FILE"`
+}
+
+// BatchConfig bounds POST /cars/batch.
+type BatchConfig struct {
+	MaxOperations int `yaml:"max_operations" toml:"max_operations" env:"BATCH_MAX_OPERATIONS"`
+}
+
+// FeatureConfig switches optional parts of the API on and off.
+type FeatureConfig struct {
+	Metrics bool `yaml:"metrics" toml:"metrics" env:"FEATURE_METRICS"`
+	Search  bool `yaml:"search" toml:"search" env:"FEATURE_SEARCH"`
+	APIKeys bool `yaml:"api_keys" toml:"api_keys" env:"FEATURE_API_KEYS"`
+}
+
+// Default returns the configuration used when nothing is set.
+func Default() Config {
+	return Config{
+		StoreBackend: "postgres",
+		Server: ServerConfig{
+			Port:              8081,
+			ReadTimeout:       15 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       120 * time.Second,
+			ShutdownTimeout:   30 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Host:              "localhost",
+			Port:              5432,
+			User:              "postgres",
+			Name:              "postgres",
+			SSLMode:           "disable",
+			MaxOpenConns:      25,
+			MaxIdleConns:      25,
+			ConnMaxLifetime:   30 * time.Minute,
+			ConnMaxIdleTime:   5 * time.Minute,
+			ConnectTimeout:    time.Minute,
+			ConnectBackoff:    500 * time.Millisecond,
+			ConnectMaxBackoff: 10 * time.Second,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			ServiceName: "carzone",
+		},
+		Auth: AuthConfig{
+			JWTAlgorithm: "HS256",
+			JWTIssuer:    "carzone",
+			JWTTTL:       time.Hour,
+		},
+		Trash: TrashConfig{
+			Retention:     30 * 24 * time.Hour,
+			PurgeInterval: time.Hour,
+		},
+		Batch: BatchConfig{
+			MaxOperations: 100,
+		},
+		Features: FeatureConfig{
+			Metrics: true,
+			Search:  true,
+			APIKeys: true,
+		},
+	}
+}
+
+// Load reads the configuration and validates it. The error lists every
+// problem found, naming the environment variable that sets each value.
+func Load() (Config, error) {
+	cfg := Default()
+
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return cfg, fmt.Errorf("reading .env: %w", err)
+	}
+
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := loadFile(path, &cfg); err != nil {
+			return cfg, err
+		}
+	}
+
+	if err := loadEnv(&cfg); err != nil {
+		return cfg, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+func loadFile(path string, cfg *Config) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading CONFIG_FILE: %w", err)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(raw))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("parsing %s: %w", path, err)
+		}
+	case ".toml":
+		meta, err := toml.Decode(string(raw), cfg)
+		if err != nil {
+			return fmt.Errorf("parsing %s: %w", path, err)
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("parsing %s: unknown key %s", path, undecoded[0])
+		}
+	default:
+		return fmt.Errorf("CONFIG_FILE %s must end in .yaml, .yml or .toml", path)
+	}
+	return nil
+}
+
+// Validate checks every value and reports all problems at once.
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, env, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: %s", env, fmt.Sprintf(format, args...)))
+		}
+	}
+
+	check(c.StoreBackend == "postgres" || c.StoreBackend == "memory",
+		"STORE_BACKEND", "must be postgres or memory, got %q", c.StoreBackend)
+
+	check(c.Server.Port > 0 && c.Server.Port <= 65535, "PORT", "must be between 1 and 65535, got %d", c.Server.Port)
+	check(c.Server.ReadTimeout > 0, "HTTP_READ_TIMEOUT", "must be positive")
+	check(c.Server.ReadHeaderTimeout > 0, "HTTP_READ_HEADER_TIMEOUT", "must be positive")
+	check(c.Server.WriteTimeout > 0, "HTTP_WRITE_TIMEOUT", "must be positive")
+	check(c.Server.IdleTimeout > 0, "HTTP_IDLE_TIMEOUT", "must be positive")
+	check(c.Server.ShutdownTimeout > 0, "SHUTDOWN_TIMEOUT", "must be positive")
+
+	if c.StoreBackend == "postgres" {
+		db := c.Database
+		check(db.Host != "", "DB_HOST", "is required")
+		check(db.Port > 0 && db.Port <= 65535, "DB_PORT", "must be between 1 and 65535, got %d", db.Port)
+		check(db.User != "", "DB_USER", "is required")
+		check(db.Name != "", "DB_NAME", "is required")
+		check(isOneOf(db.SSLMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full"),
+			"DB_SSLMODE", "must be one of disable, allow, prefer, require, verify-ca or verify-full, got %q", db.SSLMode)
+		check(db.MaxOpenConns >= 0, "DB_MAX_OPEN_CONNS", "must not be negative")
+		check(db.MaxIdleConns >= 0, "DB_MAX_IDLE_CONNS", "must not be negative")
+		check(db.MaxOpenConns == 0 || db.MaxIdleConns <= db.MaxOpenConns,
+			"DB_MAX_IDLE_CONNS", "must not exceed DB_MAX_OPEN_CONNS (%d)", db.MaxOpenConns)
+		check(db.ConnMaxLifetime >= 0, "DB_CONN_MAX_LIFETIME", "must not be negative")
+		check(db.ConnMaxIdleTime >= 0, "DB_CONN_MAX_IDLE_TIME", "must not be negative")
+		check(db.ConnectTimeout > 0, "DB_CONNECT_TIMEOUT", "must be positive")
+		check(db.ConnectBackoff > 0, "DB_CONNECT_BACKOFF", "must be positive")
+		check(db.ConnectMaxBackoff >= db.ConnectBackoff,
+			"DB_CONNECT_MAX_BACKOFF", "must not be less than DB_CONNECT_BACKOFF (%s)", db.ConnectBackoff)
+	}
+
+	_, err := logging.ParseLevel(c.Log.Level)
+	check(err == nil, "LOG_LEVEL", "must be debug, info, warn or error, got %q", c.Log.Level)
+	check(isOneOf(strings.ToLower(c.Log.Format), "json", "text"), "LOG_FORMAT", "must be json or text, got %q", c.Log.Format)
+
+	check(isOneOf(strings.ToLower(c.Tracing.Exporter), "none", "otlp", "console", "stdout"),
+		"OTEL_TRACES_EXPORTER", "must be otlp, console or none, got %q", c.Tracing.Exporter)
+	check(c.Tracing.ServiceName != "", "OTEL_SERVICE_NAME", "is required")
+
+	switch c.Auth.JWTAlgorithm {
+	case "HS256":
+		check(len(c.Auth.JWTSecret) >= 32, "JWT_SECRET", "must be at least 32 bytes with HS256")
+	case "RS256":
+		check(c.Auth.JWTPrivateKeyFile != "", "JWT_PRIVATE_KEY_FILE", "is required with RS256")
+	default:
+		check(false, "JWT_ALGORITHM", "must be HS256 or RS256, got %q", c.Auth.JWTAlgorithm)
+	}
+	check(c.Auth.JWTIssuer != "", "JWT_ISSUER", "is required")
+	check(c.Auth.JWTTTL > 0, "JWT_TTL", "must be positive")
+	check(c.Auth.AdminUsername == "" || c.Auth.AdminPassword != "",
+		"AUTH_ADMIN_PASSWORD", "is required when AUTH_ADMIN_USERNAME is set")
+
+	check(c.Trash.Retention > 0, "TRASH_RETENTION", "must be positive")
+	check(c.Trash.PurgeInterval >= 0, "TRASH_PURGE_INTERVAL", "must not be negative")
+
+	check(c.Batch.MaxOperations > 0, "BATCH_MAX_OPERATIONS", "must be positive")
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+	return nil
+}
+
+func isOneOf(value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
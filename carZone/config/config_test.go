@@ -0,0 +1,68 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadPrecedence(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "carzone.yaml")
+	err := os.WriteFile(file, []byte("server:\n  port: 9000\n  write_timeout: 1m\ndatabase:\n  host: file-host\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", file)
+	t.Setenv("DB_HOST", "env-host")
+	t.Setenv("JWT_SECRET", strings.Repeat("x", 32))
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Server.Port != 9000 {
+		t.Errorf("Server.Port = %d, want 9000 from the file", cfg.Server.Port)
+	}
+	if cfg.Server.WriteTimeout != time.Minute {
+		t.Errorf("Server.WriteTimeout = %s, want 1m from the file", cfg.Server.WriteTimeout)
+	}
+	if cfg.Database.Host != "env-host" {
+		t.Errorf("Database.Host = %q, want the environment to win", cfg.Database.Host)
+	}
+	if cfg.Database.SSLMode != "disable" {
+		t.Errorf("Database.SSLMode = %q, want the default", cfg.Database.SSLMode)
+	}
+}
+
+func TestLoadReportsEveryProblem(t *testing.T) {
+	t.Setenv("PORT", "http")
+	t.Setenv("DB_SSLMODE", "sometimes")
+	t.Setenv("JWT_SECRET", "short")
+
+	_, err := Load()
+	if err == nil {
+		t.Fatal("Load succeeded, want an error")
+	}
+	if !strings.Contains(err.Error(), `PORT: "http" is not a whole number`) {
+		t.Errorf("error %q does not name PORT", err)
+	}
+
+	t.Setenv("PORT", "8081")
+	_, err = Load()
+	for _, want := range []string{"DB_SSLMODE", "JWT_SECRET"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("error %v does not name %s", err, want)
+		}
+	}
+}
+
+func TestDSNQuotesValues(t *testing.T) {
+	cfg := Default().Database
+	cfg.Password = `it's a \secret`
+	want := `host='localhost' port=5432 user='postgres' password='it\'s a \\secret' dbname='postgres' sslmode='disable'`
+	if got := cfg.DSN(); got != want {
+		t.Errorf("DSN() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// loadEnv overrides the fields of cfg tagged with env from the environment.
+func loadEnv(cfg *Config) error {
+	var errs []error
+	walk(reflect.ValueOf(cfg).Elem(), "", func(field reflect.StructField, value reflect.Value, _ string) {
+		name := field.Tag.Get("env")
+		raw, ok := os.LookupEnv(name)
+		if name == "" || !ok || raw == "" {
+			return
+		}
+		if err := setValue(value, strings.TrimSpace(raw)); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	})
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+	return nil
+}
+
+func setValue(value reflect.Value, raw string) error {
+	switch {
+	case value.Type() == durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration such as 30s or 5m", raw)
+		}
+		value.SetInt(int64(d))
+	case value.Kind() == reflect.String:
+		value.SetString(raw)
+	case value.Kind() == reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a whole number", raw)
+		}
+		value.SetInt(int64(n))
+	case value.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not true or false", raw)
+		}
+		value.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported field type %s", value.Type())
+	}
+	return nil
+}
+
+// walk calls fn for every leaf field of the struct v with its dotted yaml
+// path, such as database.port.
+func walk(v reflect.Value, prefix string, fn func(field reflect.StructField, value reflect.Value, path string)) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		path := prefix + field.Tag.Get("yaml")
+		if field.Type.Kind() == reflect.Struct && field.Type != durationType {
+			walk(v.Field(i), path+".", fn)
+			continue
+		}
+		fn(field, v.Field(i), path)
+	}
+}
+
+// LogValue logs the effective configuration with secrets redacted, so the
+// config can be passed to a logger as is.
+func (c Config) LogValue() slog.Value {
+	return groupValue(reflect.ValueOf(c))
+}
+
+func groupValue(v reflect.Value) slog.Value {
+	attrs := make([]slog.Attr, 0, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		key := field.Tag.Get("yaml")
+		value := v.Field(i)
+
+		switch {
+		case field.Type.Kind() == reflect.Struct && field.Type != durationType:
+			attrs = append(attrs, slog.Attr{Key: key, Value: groupValue(value)})
+		case field.Tag.Get("secret") == "true":
+			redacted := ""
+			if !value.IsZero() {
+				redacted = "[REDACTED]"
+			}
+			attrs = append(attrs, slog.String(key, redacted))
+		case field.Type == durationType:
+			attrs = append(attrs, slog.String(key, time.Duration(value.Int()).String()))
+		default:
+			attrs = append(attrs, slog.Any(key, value.Interface()))
+		}
+	}
+	return slog.GroupValue(attrs...)
+}
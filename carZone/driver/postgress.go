@@ -1,56 +1,104 @@
 package driver
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"log"
-	"os"
+	"log/slog"
+	"math/rand/v2"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/ayushi-khandal09/carZone/config"
+	"github.com/lib/pq"
 )
 
-var db *sql.DB
-
-func InitDB() {
-	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		os.Getenv("DB_HOST"),
-		os.Getenv("DB_PORT"),
-		os.Getenv("DB_USER"),
-		os.Getenv("DB_PASSWORD"),
-		os.Getenv("DB_NAME"),
-	)
-	fmt.Println("Wait for the database start up...")
-	time.Sleep(5 * time.Second)
-
-	var err error
-	db, err = sql.Open("postgres", connStr)
+// DB is the Postgres connection pool handed to the stores.
+type DB struct {
+	*sql.DB
+	logger *slog.Logger
+}
+
+// InitDB opens the connection pool described by cfg and pings the database
+// with exponential backoff until it answers, cfg.ConnectTimeout passes or ctx
+// is done. Errors retrying cannot fix, such as a wrong password, are returned
+// straight away.
+func InitDB(ctx context.Context, cfg config.DatabaseConfig, logger *slog.Logger) (*DB, error) {
+	connector, err := pq.NewConnector(cfg.DSN())
 	if err != nil {
-		log.Fatalf("Error opening database: %v", err)
+		return nil, fmt.Errorf("parsing database settings: %w", err)
 	}
 
-	err = db.Ping()
+	sqlDB := sql.OpenDB(tracedConnector{connector})
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.ConnectTimeout)
+	defer cancel()
+
+	err = retry(ctx, cfg.ConnectBackoff, cfg.ConnectMaxBackoff, func(attempt int, err error, wait time.Duration) {
+		logger.Warn("database not reachable, retrying",
+			"attempt", attempt, "retry_in", wait.String(), "host", cfg.Host, "port", cfg.Port, "error", err)
+	}, sqlDB.PingContext)
 	if err != nil {
-		log.Fatalf("Error Connecting to the database: %v", err)
+		sqlDB.Close()
+		return nil, fmt.Errorf("connecting to the database at %s:%d: %w", cfg.Host, cfg.Port, err)
 	}
-	fmt.Println("Succesfully Connectd to the database")
+
+	logger.Info("connected to the database", "host", cfg.Host, "name", cfg.Name)
+	return &DB{DB: sqlDB, logger: logger}, nil
 }
 
-func GetDB() *sql.DB {
-	if db == nil {
-		log.Println("Warning: Database connection is not initialized")
+// Close closes the pool, waiting for queries in flight to finish.
+func (db *DB) Close() error {
+	if err := db.DB.Close(); err != nil {
+		return err
 	}
-	return db
+	db.logger.Info("database connection closed")
+	return nil
 }
 
-func CloseDB() {
-	if db != nil {
-		if err := db.Close(); err != nil {
-			log.Printf("Error closing database: %v", err)
-		} else {
-			fmt.Println("Database connection closed")
+// retry calls fn until it succeeds, returns a permanent error or ctx is done,
+// sleeping between attempts for a delay that starts at initial and doubles up
+// to max, with jitter so restarted replicas don't retry in lockstep.
+func retry(ctx context.Context, initial, max time.Duration, onRetry func(attempt int, err error, wait time.Duration), fn func(context.Context) error) error {
+	delay := initial
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+		if permanent(err) {
+			return err
+		}
+
+		wait := delay/2 + rand.N(delay/2+1)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		}
+		onRetry(attempt, err, wait)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		case <-time.After(wait):
 		}
-	} else {
-		fmt.Println("Warning: Attempted to close an uninitialized database connection")
+		delay = min(delay*2, max)
+	}
+}
+
+// permanent reports whether err is a Postgres error about credentials or the
+// database name, which retrying will not fix.
+func permanent(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	switch pqErr.Code.Class() {
+	case "28", "3D": // invalid authorization, invalid catalog name
+		return true
 	}
+	return false
 }
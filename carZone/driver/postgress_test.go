@@ -0,0 +1,59 @@
+package driver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+func TestRetryBacksOffUntilSuccess(t *testing.T) {
+	var waits []time.Duration
+	calls := 0
+	err := retry(context.Background(), time.Millisecond, 4*time.Millisecond,
+		func(attempt int, err error, wait time.Duration) { waits = append(waits, wait) },
+		func(context.Context) error {
+			calls++
+			if calls < 5 {
+				return errors.New("connection refused")
+			}
+			return nil
+		})
+	if err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if calls != 5 {
+		t.Errorf("fn called %d times, want 5", calls)
+	}
+	for i, max := range []time.Duration{time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond, 4 * time.Millisecond} {
+		if waits[i] < max/2 || waits[i] > max {
+			t.Errorf("wait %d = %s, want between %s and %s", i, waits[i], max/2, max)
+		}
+	}
+}
+
+func TestRetryStopsAtDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	refused := errors.New("connection refused")
+	err := retry(ctx, time.Millisecond, 5*time.Millisecond, func(int, error, time.Duration) {},
+		func(context.Context) error { return refused })
+	if !errors.Is(err, refused) {
+		t.Fatalf("retry = %v, want the last attempt's error", err)
+	}
+}
+
+func TestRetryGivesUpOnBadCredentials(t *testing.T) {
+	calls := 0
+	err := retry(context.Background(), time.Millisecond, time.Millisecond, func(int, error, time.Duration) {},
+		func(context.Context) error {
+			calls++
+			return &pq.Error{Code: "28P01", Message: "password authentication failed"}
+		})
+	if err == nil || calls != 1 {
+		t.Fatalf("retry = %v after %d calls, want the error after one call", err, calls)
+	}
+}
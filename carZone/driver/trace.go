@@ -0,0 +1,91 @@
+package driver
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+
+	"github.com/ayushi-khandal09/carZone/tracing"
+	"go.opentelemetry.io/otel"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/ayushi-khandal09/carZone/driver")
+
+// pqConn is the set of driver interfaces lib/pq connections implement.
+// Embedding it keeps the optional interfaces visible to database/sql.
+type pqConn interface {
+	driver.Conn
+	driver.ConnBeginTx
+	driver.ConnPrepareContext
+	driver.ExecerContext
+	driver.QueryerContext
+	driver.Pinger
+	driver.SessionResetter
+	driver.Validator
+}
+
+// tracedConnector hands out connections that record a span for every
+// QueryContext and ExecContext made inside a traced request, whether on the
+// pool or in a transaction.
+type tracedConnector struct {
+	driver.Connector
+}
+
+func (c tracedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pq, ok := conn.(pqConn)
+	if !ok {
+		conn.Close()
+		return nil, fmt.Errorf("driver connection %T does not support contexts", conn)
+	}
+	return tracedConn{pq}, nil
+}
+
+type tracedConn struct {
+	pqConn
+}
+
+func (c tracedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	ctx, span, ok := startQuery(ctx, query)
+	if !ok {
+		return c.pqConn.QueryContext(ctx, query, args)
+	}
+	rows, err := c.pqConn.QueryContext(ctx, query, args)
+	tracing.End(span, err)
+	return rows, err
+}
+
+func (c tracedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	ctx, span, ok := startQuery(ctx, query)
+	if !ok {
+		return c.pqConn.ExecContext(ctx, query, args)
+	}
+	result, err := c.pqConn.ExecContext(ctx, query, args)
+	tracing.End(span, err)
+	return result, err
+}
+
+// startQuery starts a client span for query when ctx already carries a span,
+// so that migrations and other background statements don't each become a
+// trace of their own.
+func startQuery(ctx context.Context, query string) (context.Context, trace.Span, bool) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx, nil, false
+	}
+	operation := strings.ToUpper(strings.SplitN(strings.TrimSpace(query), " ", 2)[0])
+	ctx, span := tracer.Start(ctx, operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationName(operation),
+			semconv.DBQueryText(query),
+		),
+	)
+	return ctx, span, true
+}
@@ -0,0 +1,115 @@
+// Package exchange converts prices between currencies. Rates come from a
+// Provider, so a live rate service can replace the static table without
+// touching the callers.
+package exchange
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/ayushi-khandal09/carZone/apperrors"
+	"github.com/ayushi-khandal09/carZone/models"
+	"github.com/shopspring/decimal"
+	"gopkg.in/yaml.v3"
+)
+
+// Provider returns how many units of the to currency one unit of the from
+// currency is worth.
+type Provider interface {
+	Rate(ctx context.Context, from, to string) (decimal.Decimal, error)
+}
+
+// Convert converts amount from one currency to another and rounds it to the
+// minor unit of the target currency.
+func Convert(ctx context.Context, rates Provider, amount decimal.Decimal, from, to string) (decimal.Decimal, error) {
+	if from == to {
+		return amount, nil
+	}
+	rate, err := rates.Rate(ctx, from, to)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	return models.RoundPrice(amount.Mul(rate), to), nil
+}
+
+// Static serves fixed rates, each the value of one unit of the base currency
+// in another currency.
+type Static struct {
+	base  string
+	rates map[string]decimal.Decimal
+}
+
+// NewStatic returns a provider for the given rates against base. The rate of
+// base itself is always 1.
+func NewStatic(base string, rates map[string]decimal.Decimal) (*Static, error) {
+	if _, ok := models.MinorUnits(base); !ok {
+		return nil, fmt.Errorf("base currency %q is not supported", base)
+	}
+	s := &Static{base: base, rates: map[string]decimal.Decimal{base: decimal.NewFromInt(1)}}
+	for currency, rate := range rates {
+		if _, ok := models.MinorUnits(currency); !ok {
+			return nil, fmt.Errorf("currency %q is not supported", currency)
+		}
+		if !rate.IsPositive() {
+			return nil, fmt.Errorf("rate of %s must be positive, got %s", currency, rate)
+		}
+		if currency == base && !rate.Equal(decimal.NewFromInt(1)) {
+			return nil, fmt.Errorf("rate of the base currency %s must be 1, got %s", base, rate)
+		}
+		s.rates[currency] = rate
+	}
+	return s, nil
+}
+
+// staticFile is the layout of a rates file. JSON is valid YAML, so both
+// formats are read the same way.
+//
+//	base: USD
+//	rates:
+//	  EUR: 0.92
+//	  GBP: 0.79
+type staticFile struct {
+	Base  string            `yaml:"base"`
+	Rates map[string]string `yaml:"rates"`
+}
+
+// LoadStatic reads a provider from a YAML or JSON rates file.
+func LoadStatic(path string) (*Static, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading rates file: %w", err)
+	}
+	var file staticFile
+	if err := yaml.Unmarshal(raw, &file); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	rates := make(map[string]decimal.Decimal, len(file.Rates))
+	for currency, value := range file.Rates {
+		rate, err := decimal.NewFromString(value)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: rate of %s: %w", path, currency, err)
+		}
+		rates[currency] = rate
+	}
+	s, err := NewStatic(file.Base, rates)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Rate derives the rate between any two known currencies from their rates
+// against the base currency.
+func (s *Static) Rate(ctx context.Context, from, to string) (decimal.Decimal, error) {
+	fromRate, ok := s.rates[from]
+	if !ok {
+		return decimal.Decimal{}, apperrors.Validation("currency", "no exchange rate for "+from)
+	}
+	toRate, ok := s.rates[to]
+	if !ok {
+		return decimal.Decimal{}, apperrors.Validation("currency", "no exchange rate for "+to)
+	}
+	return toRate.Div(fromRate), nil
+}
@@ -0,0 +1,107 @@
+package apikey
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/ayushi-khandal09/carZone/apperrors"
+	"github.com/ayushi-khandal09/carZone/handler"
+	"github.com/ayushi-khandal09/carZone/models"
+	"github.com/ayushi-khandal09/carZone/service"
+	"github.com/gorilla/mux"
+)
+
+type APIKeyHandler struct {
+	logger  *slog.Logger
+	service service.APIKeyServiceInterface
+}
+
+func NewAPIKeyHandler(service service.APIKeyServiceInterface, logger *slog.Logger) *APIKeyHandler {
+	return &APIKeyHandler{
+		logger:  logger,
+		service: service,
+	}
+}
+
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, apperrors.BadRequest("unable to read request body", err))
+		return
+	}
+
+	var keyReq models.APIKeyRequest
+	err = json.Unmarshal(body, &keyReq)
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, apperrors.BadRequest("malformed JSON request body", err))
+		return
+	}
+
+	created, err := h.service.CreateAPIKey(ctx, &keyReq)
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	h.writeJSON(ctx, w, http.StatusCreated, created)
+}
+
+func (h *APIKeyHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	keys, err := h.service.ListAPIKeys(ctx)
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	h.writeJSON(ctx, w, http.StatusOK, keys)
+}
+
+func (h *APIKeyHandler) RotateAPIKey(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := mux.Vars(r)["id"]
+
+	rotated, err := h.service.RotateAPIKey(ctx, id)
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	h.writeJSON(ctx, w, http.StatusOK, rotated)
+}
+
+func (h *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := mux.Vars(r)["id"]
+
+	revoked, err := h.service.RevokeAPIKey(ctx, id)
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	h.writeJSON(ctx, w, http.StatusOK, revoked)
+}
+
+func (h *APIKeyHandler) writeJSON(ctx context.Context, w http.ResponseWriter, status int, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+
+	_, err = w.Write(body)
+	if err != nil {
+		h.logger.WarnContext(ctx, "writing response", "error", err)
+	}
+}
@@ -0,0 +1,101 @@
+package audit
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ayushi-khandal09/carZone/apperrors"
+	"github.com/ayushi-khandal09/carZone/handler"
+	"github.com/ayushi-khandal09/carZone/models"
+	"github.com/ayushi-khandal09/carZone/service"
+	"github.com/google/uuid"
+)
+
+type AuditHandler struct {
+	logger  *slog.Logger
+	service service.AuditServiceInterface
+}
+
+func NewAuditHandler(service service.AuditServiceInterface, logger *slog.Logger) *AuditHandler {
+	return &AuditHandler{
+		logger:  logger,
+		service: service,
+	}
+}
+
+// ListAuditEntries serves GET /audit?entity=car&id=...&from=...&to=...
+// Times are RFC 3339, from is inclusive and to exclusive.
+func (h *AuditHandler) ListAuditEntries(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	filter, err := parseAuditFilter(r.URL.Query())
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	resp, err := h.service.ListAuditEntries(ctx, filter)
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	body, err := json.Marshal(resp)
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	_, err = w.Write(body)
+	if err != nil {
+		h.logger.WarnContext(ctx, "writing response", "error", err)
+	}
+}
+
+func parseAuditFilter(query url.Values) (models.AuditFilter, error) {
+	filter := models.AuditFilter{
+		Entity: query.Get("entity"),
+		Actor:  query.Get("actor"),
+		Cursor: query.Get("cursor"),
+	}
+
+	if raw := query.Get("id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return filter, apperrors.Validation("id", "id must be a valid UUID")
+		}
+		filter.EntityID = id
+	}
+
+	times := map[string]*time.Time{
+		"from": &filter.From,
+		"to":   &filter.To,
+	}
+	for name, target := range times {
+		if raw := query.Get(name); raw != "" {
+			value, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				return filter, apperrors.Validation(name, name+" must be an RFC 3339 time")
+			}
+			*target = value
+		}
+	}
+
+	if raw := query.Get("limit"); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil {
+			return filter, apperrors.Validation("limit", "limit must be a valid number")
+		}
+		filter.Limit = value
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/ayushi-khandal09/carZone/apperrors"
+	"github.com/ayushi-khandal09/carZone/handler"
+	"github.com/ayushi-khandal09/carZone/models"
+	"github.com/ayushi-khandal09/carZone/service"
+)
+
+type AuthHandler struct {
+	logger  *slog.Logger
+	service service.UserServiceInterface
+}
+
+func NewAuthHandler(service service.UserServiceInterface, logger *slog.Logger) *AuthHandler {
+	return &AuthHandler{
+		logger:  logger,
+		service: service,
+	}
+}
+
+// Token exchanges a username and password for an access token.
+func (h *AuthHandler) Token(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, apperrors.BadRequest("unable to read request body", err))
+		return
+	}
+
+	var loginReq models.LoginRequest
+	err = json.Unmarshal(body, &loginReq)
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, apperrors.BadRequest("malformed JSON request body", err))
+		return
+	}
+
+	token, err := h.service.Login(ctx, &loginReq)
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	responseBody, err := json.Marshal(token)
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	_, err = w.Write(responseBody)
+	if err != nil {
+		h.logger.WarnContext(ctx, "writing response", "error", err)
+	}
+}
@@ -0,0 +1,58 @@
+package car
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/ayushi-khandal09/carZone/apperrors"
+	"github.com/ayushi-khandal09/carZone/handler"
+	"github.com/ayushi-khandal09/carZone/models"
+)
+
+// maxBatchOperationBytes is the body size a batch may spend per operation.
+const maxBatchOperationBytes = 4 << 10
+
+// BatchCars serves POST /cars/batch. It answers 200 with the outcome of every
+// operation once some were kept, and 422 when none was.
+func (h *CarHandler) BatchCars(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, int64(h.maxBatch)*maxBatchOperationBytes))
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, apperrors.BadRequest("unable to read request body", err))
+		return
+	}
+
+	var req models.CarBatchRequest
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, apperrors.BadRequest("malformed JSON request body", err))
+		return
+	}
+
+	report, err := h.service.BatchCars(ctx, req)
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	responseBody, err := json.Marshal(report)
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	status := http.StatusOK
+	if report.Succeeded == 0 && report.Failed > 0 {
+		status = http.StatusUnprocessableEntity
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	_, err = w.Write(responseBody)
+	if err != nil {
+		h.logger.WarnContext(ctx, "writing response", "error", err)
+	}
+}
@@ -3,21 +3,33 @@ package car
 import (
 	"encoding/json"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 
+	"github.com/ayushi-khandal09/carZone/apperrors"
+	"github.com/ayushi-khandal09/carZone/handler"
 	"github.com/ayushi-khandal09/carZone/models"
 	"github.com/ayushi-khandal09/carZone/service"
 	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
 )
 
 type CarHandler struct {
-	service service.CarServiceInterface
+	logger   *slog.Logger
+	service  service.CarServiceInterface
+	maxBatch int
 }
 
-func NewCarHandler(service service.CarServiceInterface) *CarHandler {
+// NewCarHandler returns a car handler. maxBatch is the most operations a
+// batch may hold, which bounds the size of its request body.
+func NewCarHandler(service service.CarServiceInterface, maxBatch int, logger *slog.Logger) *CarHandler {
 	return &CarHandler{
-		service: service,
+		logger:   logger,
+		service:  service,
+		maxBatch: maxBatch,
 	}
 }
 
@@ -28,15 +40,21 @@ func (h *CarHandler) GetCarById(w http.ResponseWriter, r *http.Request) {
 
 	resp, err := h.service.GetCarById(ctx, id)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Println("Error : ", err)
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	// The car is served with its engine, which changes without the car.
+	etag := handler.ETag(resp.Version, resp.Engine.Version)
+	w.Header().Set("ETag", etag)
+	if handler.NotModified(r, etag) {
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 
 	body, err := json.Marshal(resp)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Println("Error : ", err)
+		handler.WriteError(ctx, h.logger, w, err)
 		return
 	}
 
@@ -45,26 +63,32 @@ func (h *CarHandler) GetCarById(w http.ResponseWriter, r *http.Request) {
 
 	_, err = w.Write(body)
 	if err != nil {
-		log.Println("Error Writing Response : ", err)
+		h.logger.WarnContext(ctx, "writing response", "error", err)
 	}
 }
 
-func (h *CarHandler) GetCarByBrand(w http.ResponseWriter, r *http.Request) {
+func (h *CarHandler) GetCarByVIN(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	brand := r.URL.Query().Get("brand")
-	isEngine := r.URL.Query().Get("isEngine") == "true"
+	vars := mux.Vars(r)
+	vin := vars["vin"]
 
-	resp, err := h.service.GetCarsByBrand(ctx, brand, isEngine)
+	resp, err := h.service.GetCarByVIN(ctx, vin)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Println("Error :", err)
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	// The car is served with its engine, which changes without the car.
+	etag := handler.ETag(resp.Version, resp.Engine.Version)
+	w.Header().Set("ETag", etag)
+	if handler.NotModified(r, etag) {
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 
 	body, err := json.Marshal(resp)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Println("Error : ", err)
+		handler.WriteError(ctx, h.logger, w, err)
 		return
 	}
 
@@ -73,7 +97,72 @@ func (h *CarHandler) GetCarByBrand(w http.ResponseWriter, r *http.Request) {
 
 	_, err = w.Write(body)
 	if err != nil {
-		log.Println("Error Writing Response : ", err)
+		h.logger.WarnContext(ctx, "writing response", "error", err)
+	}
+}
+
+func (h *CarHandler) ListCars(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	filter, err := parseCarFilter(r.URL.Query())
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	resp, err := h.service.ListCars(ctx, filter)
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	body, err := json.Marshal(resp)
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	_, err = w.Write(body)
+	if err != nil {
+		h.logger.WarnContext(ctx, "writing response", "error", err)
+	}
+}
+
+func (h *CarHandler) SearchCars(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query().Get("q")
+
+	limit := 0
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil {
+			handler.WriteError(ctx, h.logger, w, apperrors.Validation("limit", "limit must be a valid number"))
+			return
+		}
+		limit = value
+	}
+
+	resp, err := h.service.SearchCars(ctx, query, limit)
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	body, err := json.Marshal(resp)
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	_, err = w.Write(body)
+	if err != nil {
+		h.logger.WarnContext(ctx, "writing response", "error", err)
 	}
 }
 
@@ -82,31 +171,27 @@ func (h *CarHandler) CreateCar(w http.ResponseWriter, r *http.Request) {
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		log.Println("Error while reading request body :", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		handler.WriteError(ctx, h.logger, w, apperrors.BadRequest("unable to read request body", err))
 		return
 	}
 
 	var carReq models.CarRequest
 	err = json.Unmarshal(body, &carReq)
 	if err != nil {
-		log.Println("Error while Unmarshalling Request body", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		handler.WriteError(ctx, h.logger, w, apperrors.BadRequest("malformed JSON request body", err))
 		return
 	}
 
 	createdCar, err := h.service.CreateCar(ctx, &carReq)
 	if err != nil {
-		log.Println("Error creating car :", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		handler.WriteError(ctx, h.logger, w, err)
 		return
 	}
 
 	responseBody, err := json.Marshal(createdCar)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Println("Error while marshalling:", err)
-		return 
+		handler.WriteError(ctx, h.logger, w, err)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -114,7 +199,7 @@ func (h *CarHandler) CreateCar(w http.ResponseWriter, r *http.Request) {
 
 	_, err = w.Write(responseBody)
 	if err != nil {
-		log.Println("Error Writing Response : ", err)
+		h.logger.WarnContext(ctx, "writing response", "error", err)
 	}
 }
 
@@ -123,33 +208,35 @@ func (h *CarHandler) UpdateCar(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	id := params["id"]
 
+	version, err := handler.IfMatch(r)
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		log.Println("Error while reading request body :", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		handler.WriteError(ctx, h.logger, w, apperrors.BadRequest("unable to read request body", err))
 		return
 	}
 
 	var carReq models.CarRequest
 	err = json.Unmarshal(body, &carReq)
 	if err != nil {
-		log.Println("Error while Unmarshalling Request body", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		handler.WriteError(ctx, h.logger, w, apperrors.BadRequest("malformed JSON request body", err))
 		return
 	}
 
-	updatedCar, err := h.service.UpdateCar(ctx, id, &carReq)
+	updatedCar, err := h.service.UpdateCar(ctx, id, &carReq, version)
 	if err != nil {
-		log.Println("Error updating car :", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		handler.WriteError(ctx, h.logger, w, err)
 		return
 	}
 
 	responseBody, err := json.Marshal(updatedCar)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Println("Error while marshalling:", err)
-		return 
+		handler.WriteError(ctx, h.logger, w, err)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -157,7 +244,7 @@ func (h *CarHandler) UpdateCar(w http.ResponseWriter, r *http.Request) {
 
 	_, err = w.Write(responseBody)
 	if err != nil {
-		log.Println("Error Writing Response : ", err)
+		h.logger.WarnContext(ctx, "writing response", "error", err)
 	}
 }
 
@@ -166,18 +253,22 @@ func (h *CarHandler) DeleteCar(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	id := params["id"]
 
-	deleteCar, err := h.service.DeleteCar(ctx, id)
+	version, err := handler.IfMatch(r)
 	if err != nil {
-		log.Println("Error deleting car :", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	deleteCar, err := h.service.DeleteCar(ctx, id, version)
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
 		return
 	}
 
 	body, err := json.Marshal(deleteCar)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Println("Error while marshalling:", err)
-		return 
+		handler.WriteError(ctx, h.logger, w, err)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -185,6 +276,236 @@ func (h *CarHandler) DeleteCar(w http.ResponseWriter, r *http.Request) {
 
 	_, err = w.Write(body)
 	if err != nil {
-		log.Println("Error Writing Response : ", err)
+		h.logger.WarnContext(ctx, "writing response", "error", err)
+	}
+}
+
+// PatchCar accepts an RFC 7396 merge patch, or an RFC 6902 JSON Patch when
+// sent as application/json-patch+json.
+func (h *CarHandler) PatchCar(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	params := mux.Vars(r)
+	id := params["id"]
+
+	version, err := handler.IfMatch(r)
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	// A JSON Patch is applied to the car as it is now, so the update must
+	// not go through if the car changes in the meantime.
+	mergePatch, err := handler.ReadPatch(r, func() ([]byte, error) {
+		car, err := h.service.GetCarById(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if version == 0 {
+			version = car.Version
+		}
+		return json.Marshal(car)
+	})
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	patch, err := models.DecodeCarPatch(mergePatch)
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	patchedCar, err := h.service.PatchCar(ctx, id, patch, version)
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	responseBody, err := json.Marshal(patchedCar)
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	_, err = w.Write(responseBody)
+	if err != nil {
+		h.logger.WarnContext(ctx, "writing response", "error", err)
 	}
 }
+
+// ListDeletedCars returns the cars in the trash.
+func (h *CarHandler) ListDeletedCars(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	resp, err := h.service.ListDeletedCars(ctx)
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	body, err := json.Marshal(resp)
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	_, err = w.Write(body)
+	if err != nil {
+		h.logger.WarnContext(ctx, "writing response", "error", err)
+	}
+}
+
+func (h *CarHandler) RestoreCar(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	params := mux.Vars(r)
+	id := params["id"]
+
+	restoredCar, err := h.service.RestoreCar(ctx, id)
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	body, err := json.Marshal(restoredCar)
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	_, err = w.Write(body)
+	if err != nil {
+		h.logger.WarnContext(ctx, "writing response", "error", err)
+	}
+}
+
+// GetPriceHistory returns the current price of a car and its changes.
+func (h *CarHandler) GetPriceHistory(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := mux.Vars(r)["id"]
+
+	resp, err := h.service.GetPriceHistory(ctx, id)
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	body, err := json.Marshal(resp)
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	_, err = w.Write(body)
+	if err != nil {
+		h.logger.WarnContext(ctx, "writing response", "error", err)
+	}
+}
+
+// GetPriceStats serves GET /cars/price-stats?group_by=brand|fuel_type|year,
+// optionally narrowed down by brand and fuel_type.
+func (h *CarHandler) GetPriceStats(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	resp, err := h.service.GetPriceStats(ctx, models.PriceStatsFilter{
+		GroupBy:  query.Get("group_by"),
+		Brand:    query.Get("brand"),
+		FuelType: query.Get("fuel_type"),
+	})
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	body, err := json.Marshal(resp)
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	_, err = w.Write(body)
+	if err != nil {
+		h.logger.WarnContext(ctx, "writing response", "error", err)
+	}
+}
+
+// parseCarFilter reads the listing filters from the query string. A leading
+// "-" on sort orders descending, e.g. ?sort=-price.
+func parseCarFilter(query url.Values) (models.CarFilter, error) {
+	filter := models.CarFilter{
+		Brand:    query.Get("brand"),
+		FuelType: query.Get("fuel_type"),
+		Cursor:   query.Get("cursor"),
+		IsEngine: query.Get("isEngine") == "true",
+		Currency: query.Get("currency"),
+	}
+
+	sort := query.Get("sort")
+	if strings.HasPrefix(sort, "-") {
+		filter.SortDesc = true
+		sort = strings.TrimPrefix(sort, "-")
+	}
+	filter.SortBy = sort
+
+	ints := map[string]*int{
+		"year_from": &filter.YearFrom,
+		"year_to":   &filter.YearTo,
+		"limit":     &filter.Limit,
+	}
+	for name, target := range ints {
+		if raw := query.Get(name); raw != "" {
+			value, err := strconv.Atoi(raw)
+			if err != nil {
+				return filter, apperrors.Validation(name, name+" must be a valid number")
+			}
+			*target = value
+		}
+	}
+
+	int64s := map[string]*int64{
+		"displacement_min": &filter.DisplacementMin,
+		"displacement_max": &filter.DisplacementMax,
+		"cylinders":        &filter.Cylinders,
+	}
+	for name, target := range int64s {
+		if raw := query.Get(name); raw != "" {
+			value, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil {
+				return filter, apperrors.Validation(name, name+" must be a valid number")
+			}
+			*target = value
+		}
+	}
+
+	decimals := map[string]*decimal.Decimal{
+		"price_min": &filter.PriceMin,
+		"price_max": &filter.PriceMax,
+	}
+	for name, target := range decimals {
+		if raw := query.Get(name); raw != "" {
+			value, err := decimal.NewFromString(raw)
+			if err != nil {
+				return filter, apperrors.Validation(name, name+" must be a valid number")
+			}
+			*target = value
+		}
+	}
+
+	return filter, nil
+}
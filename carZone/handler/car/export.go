@@ -0,0 +1,269 @@
+package car
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ayushi-khandal09/carZone/apperrors"
+	"github.com/ayushi-khandal09/carZone/handler"
+	"github.com/ayushi-khandal09/carZone/models"
+	"github.com/xuri/excelize/v2"
+)
+
+// exportFormats maps the format parameter of an export to its media type and
+// the extension of the file it is served as.
+var exportFormats = map[string][2]string{
+	"csv":    {"text/csv; charset=utf-8", "csv"},
+	"ndjson": {"application/x-ndjson", "ndjson"},
+	"xlsx":   {"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", "xlsx"},
+}
+
+// ExportCars serves GET /export/cars?format=csv|ndjson|xlsx with the filters
+// and sort of the listing, without paging. isEngine=true adds the engine
+// columns. Rows are written as they are read from the store.
+func (h *CarHandler) ExportCars(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	media, ok := exportFormats[format]
+	if !ok {
+		handler.WriteError(ctx, h.logger, w, apperrors.Validation("format", "format must be csv, ndjson or xlsx"))
+		return
+	}
+	filter, err := parseCarFilter(r.URL.Query())
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+	// The server's write timeout is meant for ordinary responses and would
+	// cut a large export off in the middle of the stream.
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		h.logger.WarnContext(ctx, "clearing the write deadline of an export", "error", err)
+	}
+
+	// The headers go out with the first row, so an error that comes before
+	// it is still answered with a proper error response.
+	var out carWriter
+	start := func() error {
+		w.Header().Set("Content-Type", media[0])
+		w.Header().Set("Content-Disposition", `attachment; filename="cars.`+media[1]+`"`)
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+
+		var err error
+		out, err = newCarWriter(w, format, filter.IsEngine)
+		return err
+	}
+
+	err = h.service.ExportCars(ctx, filter, func(car models.Car) error {
+		if out == nil {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		return out.Write(car)
+	})
+	if err == nil && out == nil {
+		err = start()
+	}
+	if err == nil {
+		err = out.Close()
+	}
+	if err != nil && out == nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+	if err != nil {
+		// Part of the file is already on its way. Breaking the connection
+		// keeps the client from taking it for the whole export.
+		h.logger.ErrorContext(ctx, "exporting cars", "format", format, "error", err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// carWriter writes cars one by one in an export format. Close completes the
+// file and must be called after the last car.
+type carWriter interface {
+	Write(car models.Car) error
+	Close() error
+}
+
+func newCarWriter(w io.Writer, format string, withEngine bool) (carWriter, error) {
+	header := []string{"id", "vin", "name", "year", "brand", "fuel_type", "price", "currency", "engine_id", "created_at", "updated_at", "version"}
+	if withEngine {
+		header = append(header, "displacement", "no_of_cylinders", "car_range")
+	}
+
+	switch format {
+	case "ndjson":
+		buf := bufio.NewWriter(w)
+		return &ndjsonCarWriter{buf: buf, encoder: json.NewEncoder(buf)}, nil
+	case "xlsx":
+		file := excelize.NewFile()
+		stream, err := file.NewStreamWriter("Sheet1")
+		if err != nil {
+			return nil, err
+		}
+		// Number format 22 shows a date and time, m/d/yy h:mm.
+		dates, err := file.NewStyle(&excelize.Style{NumFmt: 22})
+		if err != nil {
+			return nil, err
+		}
+		out := &xlsxCarWriter{w: w, file: file, stream: stream, dates: dates, prices: map[int32]int{}, withEngine: withEngine, row: 1}
+		cells := make([]interface{}, len(header))
+		for i, name := range header {
+			cells[i] = name
+		}
+		return out, out.setRow(cells)
+	default:
+		out := &csvCarWriter{csv: csv.NewWriter(w), withEngine: withEngine}
+		return out, out.csv.Write(header)
+	}
+}
+
+// cellText keeps a spreadsheet from reading text a client entered as a
+// formula, by quoting it when it starts like one.
+func cellText(text string) string {
+	if text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
+
+type csvCarWriter struct {
+	csv        *csv.Writer
+	withEngine bool
+}
+
+func (c *csvCarWriter) Write(car models.Car) error {
+	record := []string{
+		car.ID.String(),
+		car.VIN,
+		cellText(car.Name),
+		car.Year,
+		cellText(car.Brand),
+		cellText(car.FuelType),
+		car.Price.String(),
+		car.Currency,
+		car.Engine.EngineID.String(),
+		car.CreatedAt.UTC().Format(time.RFC3339),
+		car.UpdatedAt.UTC().Format(time.RFC3339),
+		strconv.FormatInt(car.Version, 10),
+	}
+	if c.withEngine {
+		record = append(record,
+			strconv.FormatInt(car.Engine.Displacement, 10),
+			strconv.FormatInt(car.Engine.NoOfCyclinders, 10),
+			strconv.FormatInt(car.Engine.CarRange, 10),
+		)
+	}
+	return c.csv.Write(record)
+}
+
+func (c *csvCarWriter) Close() error {
+	c.csv.Flush()
+	return c.csv.Error()
+}
+
+// ndjsonCarWriter writes every car as it is served by GET /cars/{id}.
+type ndjsonCarWriter struct {
+	buf     *bufio.Writer
+	encoder *json.Encoder
+}
+
+func (n *ndjsonCarWriter) Write(car models.Car) error {
+	return n.encoder.Encode(car)
+}
+
+func (n *ndjsonCarWriter) Close() error {
+	return n.buf.Flush()
+}
+
+// xlsxCarWriter streams rows into a worksheet, which excelize keeps on disk
+// once it grows large. The workbook can only be written out as a whole, so
+// it reaches the client on Close.
+type xlsxCarWriter struct {
+	w          io.Writer
+	file       *excelize.File
+	stream     *excelize.StreamWriter
+	dates      int
+	prices     map[int32]int
+	withEngine bool
+	row        int
+}
+
+func (x *xlsxCarWriter) Write(car models.Car) error {
+	prices, err := x.priceStyle(car.Currency)
+	if err != nil {
+		return err
+	}
+	cells := []interface{}{
+		car.ID.String(),
+		car.VIN,
+		cellText(car.Name),
+		car.Year,
+		cellText(car.Brand),
+		cellText(car.FuelType),
+		excelize.Cell{StyleID: prices, Value: car.Price.InexactFloat64()},
+		car.Currency,
+		car.Engine.EngineID.String(),
+		excelize.Cell{StyleID: x.dates, Value: car.CreatedAt.UTC()},
+		excelize.Cell{StyleID: x.dates, Value: car.UpdatedAt.UTC()},
+		car.Version,
+	}
+	if x.withEngine {
+		cells = append(cells, car.Engine.Displacement, car.Engine.NoOfCyclinders, car.Engine.CarRange)
+	}
+	return x.setRow(cells)
+}
+
+// priceStyle returns the style of a price in currency, which shows the
+// decimal places of the currency. Prices have at most 13 significant digits,
+// so the shortest text of their double, which is what the file holds, is
+// the stored decimal itself.
+func (x *xlsxCarWriter) priceStyle(currency string) (int, error) {
+	digits, ok := models.MinorUnits(currency)
+	if !ok {
+		digits = 2
+	}
+	if style, ok := x.prices[digits]; ok {
+		return style, nil
+	}
+	format := "0"
+	if digits > 0 {
+		format += "." + strings.Repeat("0", int(digits))
+	}
+	style, err := x.file.NewStyle(&excelize.Style{CustomNumFmt: &format})
+	if err != nil {
+		return 0, err
+	}
+	x.prices[digits] = style
+	return style, nil
+}
+
+func (x *xlsxCarWriter) setRow(cells []interface{}) error {
+	cell, err := excelize.CoordinatesToCellName(1, x.row)
+	if err != nil {
+		return err
+	}
+	x.row++
+	return x.stream.SetRow(cell, cells)
+}
+
+func (x *xlsxCarWriter) Close() error {
+	defer x.file.Close()
+	if err := x.stream.Flush(); err != nil {
+		return err
+	}
+	_, err := x.file.WriteTo(x.w)
+	return err
+}
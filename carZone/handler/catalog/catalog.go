@@ -0,0 +1,203 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/ayushi-khandal09/carZone/apperrors"
+	"github.com/ayushi-khandal09/carZone/handler"
+	"github.com/ayushi-khandal09/carZone/models"
+	"github.com/ayushi-khandal09/carZone/service"
+	"github.com/gorilla/mux"
+)
+
+// CatalogHandler serves the brands under /brands and their models under
+// /brands/{id}/models. {id} and {model} take an id or a name.
+type CatalogHandler struct {
+	logger  *slog.Logger
+	service service.CatalogServiceInterface
+}
+
+func NewCatalogHandler(service service.CatalogServiceInterface, logger *slog.Logger) *CatalogHandler {
+	return &CatalogHandler{
+		logger:  logger,
+		service: service,
+	}
+}
+
+func (h *CatalogHandler) ListBrands(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	brands, err := h.service.ListBrands(ctx)
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	h.writeJSON(ctx, w, http.StatusOK, brands)
+}
+
+func (h *CatalogHandler) GetBrand(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	brand, err := h.service.GetBrand(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	h.writeJSON(ctx, w, http.StatusOK, brand)
+}
+
+func (h *CatalogHandler) CreateBrand(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var brandReq models.BrandRequest
+	if err := readJSON(r, &brandReq); err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	brand, err := h.service.CreateBrand(ctx, &brandReq)
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	h.writeJSON(ctx, w, http.StatusCreated, brand)
+}
+
+func (h *CatalogHandler) UpdateBrand(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var brandReq models.BrandRequest
+	if err := readJSON(r, &brandReq); err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	brand, err := h.service.UpdateBrand(ctx, mux.Vars(r)["id"], &brandReq)
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	h.writeJSON(ctx, w, http.StatusOK, brand)
+}
+
+func (h *CatalogHandler) DeleteBrand(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	brand, err := h.service.DeleteBrand(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	h.writeJSON(ctx, w, http.StatusOK, brand)
+}
+
+func (h *CatalogHandler) ListCarModels(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	carModels, err := h.service.ListCarModels(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	h.writeJSON(ctx, w, http.StatusOK, carModels)
+}
+
+func (h *CatalogHandler) GetCarModel(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+
+	model, err := h.service.GetCarModel(ctx, vars["id"], vars["model"])
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	h.writeJSON(ctx, w, http.StatusOK, model)
+}
+
+func (h *CatalogHandler) CreateCarModel(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var modelReq models.CarModelRequest
+	if err := readJSON(r, &modelReq); err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	model, err := h.service.CreateCarModel(ctx, mux.Vars(r)["id"], &modelReq)
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	h.writeJSON(ctx, w, http.StatusCreated, model)
+}
+
+func (h *CatalogHandler) UpdateCarModel(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+
+	var modelReq models.CarModelRequest
+	if err := readJSON(r, &modelReq); err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	model, err := h.service.UpdateCarModel(ctx, vars["id"], vars["model"], &modelReq)
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	h.writeJSON(ctx, w, http.StatusOK, model)
+}
+
+func (h *CatalogHandler) DeleteCarModel(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+
+	model, err := h.service.DeleteCarModel(ctx, vars["id"], vars["model"])
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	h.writeJSON(ctx, w, http.StatusOK, model)
+}
+
+func readJSON(r *http.Request, v interface{}) error {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return apperrors.BadRequest("unable to read request body", err)
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return apperrors.BadRequest("malformed JSON request body", err)
+	}
+	return nil
+}
+
+func (h *CatalogHandler) writeJSON(ctx context.Context, w http.ResponseWriter, status int, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	_, err = w.Write(body)
+	if err != nil {
+		h.logger.WarnContext(ctx, "writing response", "error", err)
+	}
+}
@@ -3,21 +3,24 @@ package engine
 import (
 	"encoding/json"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 
+	"github.com/ayushi-khandal09/carZone/apperrors"
+	"github.com/ayushi-khandal09/carZone/handler"
 	"github.com/ayushi-khandal09/carZone/models"
 	"github.com/ayushi-khandal09/carZone/service"
-	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 )
 
 type EngineHandler struct {
+	logger  *slog.Logger
 	service service.EngineServiceInterface
 }
 
-func NewEngineHandler(service service.EngineServiceInterface) *EngineHandler {
+func NewEngineHandler(service service.EngineServiceInterface, logger *slog.Logger) *EngineHandler {
 	return &EngineHandler{
+		logger:  logger,
 		service: service,
 	}
 }
@@ -29,15 +32,20 @@ func (e *EngineHandler) GetEngineById(w http.ResponseWriter, r *http.Request) {
 
 	resp, err := e.service.GetEngineById(ctx, id)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Println(err)
+		handler.WriteError(ctx, e.logger, w, err)
+		return
+	}
+
+	etag := handler.ETag(resp.Version)
+	w.Header().Set("ETag", etag)
+	if handler.NotModified(r, etag) {
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 
 	body, err := json.Marshal(resp)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Print(err)
+		handler.WriteError(ctx, e.logger, w, err)
 		return
 	}
 
@@ -46,7 +54,7 @@ func (e *EngineHandler) GetEngineById(w http.ResponseWriter, r *http.Request) {
 
 	_, err = w.Write(body)
 	if err != nil {
-		log.Println("Error writing Response:", err)
+		e.logger.WarnContext(ctx, "writing response", "error", err)
 	}
 }
 
@@ -55,30 +63,26 @@ func (e *EngineHandler) CreateEngine(w http.ResponseWriter, r *http.Request) {
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		log.Println("Error reading request body:", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		handler.WriteError(ctx, e.logger, w, apperrors.BadRequest("unable to read request body", err))
 		return
 	}
 
 	var engineReq models.EngineRequest
 	err = json.Unmarshal(body, &engineReq)
 	if err != nil {
-		log.Println("Error Unmarshalling the engine request body:", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		handler.WriteError(ctx, e.logger, w, apperrors.BadRequest("malformed JSON request body", err))
 		return
 	}
 
 	createdEngine, err := e.service.CreateEngine(ctx, &engineReq)
 	if err != nil {
-		log.Println("Error while creating Engine:", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		handler.WriteError(ctx, e.logger, w, err)
 		return
 	}
 
 	respBody, err := json.Marshal(createdEngine)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Print(err)
+		handler.WriteError(ctx, e.logger, w, err)
 		return
 	}
 
@@ -92,35 +96,39 @@ func (e *EngineHandler) UpdateEngine(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	params := mux.Vars(r)
 	id := params["id"]
+
+	version, err := handler.IfMatch(r)
+	if err != nil {
+		handler.WriteError(ctx, e.logger, w, err)
+		return
+	}
+
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		log.Println("Error reading request body:", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		handler.WriteError(ctx, e.logger, w, apperrors.BadRequest("unable to read request body", err))
 		return
 	}
 
 	var engineReq models.EngineRequest
 	err = json.Unmarshal(body, &engineReq)
 	if err != nil {
-		log.Println("Error Unmarshalling the engine request body:", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		handler.WriteError(ctx, e.logger, w, apperrors.BadRequest("malformed JSON request body", err))
 		return
 	}
 
-	updatedEngine, err := e.service.UpdateEngine(ctx, id, &engineReq)
+	updatedEngine, err := e.service.UpdateEngine(ctx, id, &engineReq, version)
 	if err != nil {
-		log.Println("Error while updating Engine:", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		handler.WriteError(ctx, e.logger, w, err)
 		return
 	}
 
 	respBody, err := json.Marshal(updatedEngine)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Print(err)
+		handler.WriteError(ctx, e.logger, w, err)
 		return
 	}
 
+	w.Header().Set("ETag", handler.ETag(updatedEngine.Version))
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
@@ -132,30 +140,21 @@ func (e *EngineHandler) DeleteEngine(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	id := params["id"]
 
-	deleteEngine, err := e.service.DeleteEngine(ctx, id)
+	version, err := handler.IfMatch(r)
 	if err != nil {
-		log.Println("Error while deleting Engine:", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		response := map[string]string{"error": "Invalid ID or Engine not Found"}
-		jsonResponse, _ := json.Marshal(response)
-		_, _ = w.Write(jsonResponse)
+		handler.WriteError(ctx, e.logger, w, err)
 		return
 	}
 
-	if deleteEngine.EngineID == uuid.Nil {
-		w.WriteHeader(http.StatusNotFound)
-		response := map[string]string{"error": "Engine Not Found"}
-		jsonResponse, _ := json.Marshal(response)
-		_, _ = w.Write(jsonResponse)
-		return 
+	deleteEngine, err := e.service.DeleteEngine(ctx, id, version)
+	if err != nil {
+		handler.WriteError(ctx, e.logger, w, err)
+		return
 	}
+
 	jsonResponse, err := json.Marshal(deleteEngine)
 	if err != nil {
-		log.Print("Error while marshalling delete engine response:", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		response := map[string]string {"error": "Internal server error"}
-		jsonResponse, _ := json.Marshal(response)
-		_, _ = w.Write(jsonResponse)
+		handler.WriteError(ctx, e.logger, w, err)
 		return
 	}
 
@@ -164,3 +163,105 @@ func (e *EngineHandler) DeleteEngine(w http.ResponseWriter, r *http.Request) {
 
 	_, _ = w.Write(jsonResponse)
 }
+
+// PatchEngine accepts an RFC 7396 merge patch, or an RFC 6902 JSON Patch when
+// sent as application/json-patch+json.
+func (e *EngineHandler) PatchEngine(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	params := mux.Vars(r)
+	id := params["id"]
+
+	version, err := handler.IfMatch(r)
+	if err != nil {
+		handler.WriteError(ctx, e.logger, w, err)
+		return
+	}
+
+	// A JSON Patch is applied to the engine as it is now, so the update must
+	// not go through if the engine changes in the meantime.
+	mergePatch, err := handler.ReadPatch(r, func() ([]byte, error) {
+		engine, err := e.service.GetEngineById(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if version == 0 {
+			version = engine.Version
+		}
+		return json.Marshal(engine)
+	})
+	if err != nil {
+		handler.WriteError(ctx, e.logger, w, err)
+		return
+	}
+
+	patch, err := models.DecodeEnginePatch(mergePatch)
+	if err != nil {
+		handler.WriteError(ctx, e.logger, w, err)
+		return
+	}
+
+	patchedEngine, err := e.service.PatchEngine(ctx, id, patch, version)
+	if err != nil {
+		handler.WriteError(ctx, e.logger, w, err)
+		return
+	}
+
+	respBody, err := json.Marshal(patchedEngine)
+	if err != nil {
+		handler.WriteError(ctx, e.logger, w, err)
+		return
+	}
+
+	w.Header().Set("ETag", handler.ETag(patchedEngine.Version))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	_, _ = w.Write(respBody)
+}
+
+// ListDeletedEngines returns the engines in the trash.
+func (e *EngineHandler) ListDeletedEngines(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	resp, err := e.service.ListDeletedEngines(ctx)
+	if err != nil {
+		handler.WriteError(ctx, e.logger, w, err)
+		return
+	}
+
+	body, err := json.Marshal(resp)
+	if err != nil {
+		handler.WriteError(ctx, e.logger, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	_, _ = w.Write(body)
+}
+
+// RestoreEngine brings back the engine and the cars deleted with it.
+func (e *EngineHandler) RestoreEngine(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	params := mux.Vars(r)
+	id := params["id"]
+
+	restoredEngine, err := e.service.RestoreEngine(ctx, id)
+	if err != nil {
+		handler.WriteError(ctx, e.logger, w, err)
+		return
+	}
+
+	respBody, err := json.Marshal(restoredEngine)
+	if err != nil {
+		handler.WriteError(ctx, e.logger, w, err)
+		return
+	}
+
+	w.Header().Set("ETag", handler.ETag(restoredEngine.Version))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	_, _ = w.Write(respBody)
+}
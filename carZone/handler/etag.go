@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ayushi-khandal09/carZone/apperrors"
+)
+
+// ETag returns the strong entity tag for a row version. A representation
+// that joins other rows passes their versions too, so that a change to any
+// of them changes the tag.
+func ETag(version int64, joined ...int64) string {
+	tag := strconv.FormatInt(version, 10)
+	for _, v := range joined {
+		tag += "-" + strconv.FormatInt(v, 10)
+	}
+	return `"` + tag + `"`
+}
+
+// IfMatch returns the row version the If-Match header of r requires, or 0
+// when the request is unconditional. Only a single strong tag or * is
+// accepted. If-Match only covers the row that is written: the versions of
+// joined rows in a tag are ignored, so a car write still goes through after
+// its engine changed. Writes of such rows send no ETag, as their responses
+// do not carry the joined rows the tag of a read stands for.
+func IfMatch(r *http.Request) (int64, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+	if strings.HasPrefix(header, "W/") {
+		return 0, apperrors.PreconditionFailed("If-Match requires a strong entity tag")
+	}
+	tag := strings.Trim(header, `"`)
+	if i := strings.IndexByte(tag, '-'); i >= 0 {
+		if _, err := strconv.ParseUint(tag[i+1:], 10, 64); err != nil {
+			return 0, apperrors.BadRequest("If-Match must be a single entity tag such as \"3\"", err)
+		}
+		tag = tag[:i]
+	}
+	version, err := strconv.ParseInt(tag, 10, 64)
+	if err != nil || version <= 0 || !strings.HasPrefix(header, `"`) || !strings.HasSuffix(header, `"`) {
+		return 0, apperrors.BadRequest("If-Match must be a single entity tag such as \"3\"", err)
+	}
+	return version, nil
+}
+
+// NotModified reports whether the If-None-Match header of r lists etag, using
+// the weak comparison RFC 9110 prescribes for GET.
+func NotModified(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// checkTimeout bounds each readiness check so a hung database cannot hold up
+// the probe.
+const checkTimeout = 2 * time.Second
+
+// Check reports an error when a dependency the service needs is unusable.
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+type HealthHandler struct {
+	logger   *slog.Logger
+	checks   []namedCheck
+	draining atomic.Bool
+}
+
+func NewHealthHandler(logger *slog.Logger) *HealthHandler {
+	return &HealthHandler{
+		logger: logger,
+	}
+}
+
+// AddCheck registers a readiness check. Checks must be added before the
+// server starts.
+func (h *HealthHandler) AddCheck(name string, check Check) {
+	h.checks = append(h.checks, namedCheck{name: name, check: check})
+}
+
+// Drain makes the readiness probe fail so the orchestrator stops routing new
+// requests while in-flight ones finish.
+func (h *HealthHandler) Drain() {
+	h.draining.Store(true)
+}
+
+type response struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Liveness reports that the process is up and serving HTTP.
+func (h *HealthHandler) Liveness(w http.ResponseWriter, r *http.Request) {
+	h.write(r.Context(), w, http.StatusOK, response{Status: "ok"})
+}
+
+// Readiness runs every check and reports 503 when one fails or the server is
+// shutting down.
+func (h *HealthHandler) Readiness(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if h.draining.Load() {
+		h.write(ctx, w, http.StatusServiceUnavailable, response{Status: "draining"})
+		return
+	}
+
+	status := http.StatusOK
+	resp := response{Status: "ready", Checks: map[string]string{}}
+	for _, c := range h.checks {
+		checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+		err := c.check(checkCtx)
+		cancel()
+		if err != nil {
+			h.logger.WarnContext(ctx, "readiness check failed", "check", c.name, "error", err)
+			status = http.StatusServiceUnavailable
+			resp.Status = "not ready"
+			// The probe is public, so the details only go to the log.
+			resp.Checks[c.name] = "failing"
+			continue
+		}
+		resp.Checks[c.name] = "ok"
+	}
+	h.write(ctx, w, status, resp)
+}
+
+func (h *HealthHandler) write(ctx context.Context, w http.ResponseWriter, status int, resp response) {
+	body, err := json.Marshal(resp)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "encoding health response", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+
+	_, err = w.Write(body)
+	if err != nil {
+		h.logger.WarnContext(ctx, "writing response", "error", err)
+	}
+}
@@ -0,0 +1,131 @@
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/ayushi-khandal09/carZone/apperrors"
+	"github.com/ayushi-khandal09/carZone/handler"
+	"github.com/ayushi-khandal09/carZone/models"
+	"github.com/ayushi-khandal09/carZone/service"
+)
+
+// maxImportBytes bounds the size of an import request body.
+const maxImportBytes = 10 << 20
+
+type ImportHandler struct {
+	logger  *slog.Logger
+	service service.ImportServiceInterface
+}
+
+func NewImportHandler(service service.ImportServiceInterface, logger *slog.Logger) *ImportHandler {
+	return &ImportHandler{
+		logger:  logger,
+		service: service,
+	}
+}
+
+// ImportCars serves POST /import/cars?dry_run=true&mode=best_effort. The body
+// is text/csv with a header line, or application/x-ndjson with one car per
+// line.
+func (h *ImportHandler) ImportCars(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	options, format, err := parseImportRequest(r)
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	rows, err := models.DecodeCarImport(http.MaxBytesReader(w, r.Body, maxImportBytes), format)
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	report, err := h.service.ImportCars(ctx, rows, options)
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+	h.writeReport(ctx, w, report)
+}
+
+// ImportEngines serves POST /import/engines, like ImportCars.
+func (h *ImportHandler) ImportEngines(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	options, format, err := parseImportRequest(r)
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	rows, err := models.DecodeEngineImport(http.MaxBytesReader(w, r.Body, maxImportBytes), format)
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	report, err := h.service.ImportEngines(ctx, rows, options)
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+	h.writeReport(ctx, w, report)
+}
+
+// writeReport answers 201 when rows were imported, 200 for a dry run that
+// would import some, and 422 when no row made it.
+func (h *ImportHandler) writeReport(ctx context.Context, w http.ResponseWriter, report *models.ImportReport) {
+	body, err := json.Marshal(report)
+	if err != nil {
+		handler.WriteError(ctx, h.logger, w, err)
+		return
+	}
+
+	status := http.StatusCreated
+	switch {
+	case report.Imported == 0 && report.Failed > 0:
+		status = http.StatusUnprocessableEntity
+	case report.DryRun:
+		status = http.StatusOK
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	_, err = w.Write(body)
+	if err != nil {
+		h.logger.WarnContext(ctx, "writing response", "error", err)
+	}
+}
+
+func parseImportRequest(r *http.Request) (models.ImportOptions, string, error) {
+	query := r.URL.Query()
+	options := models.ImportOptions{Mode: query.Get("mode")}
+	if raw := query.Get("dry_run"); raw != "" {
+		dryRun, err := strconv.ParseBool(raw)
+		if err != nil {
+			return options, "", apperrors.Validation("dry_run", "dry_run must be true or false")
+		}
+		options.DryRun = dryRun
+	}
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return options, "", apperrors.UnsupportedMediaType("imports accept text/csv or application/x-ndjson")
+	}
+	switch mediaType {
+	case "text/csv":
+		return options, models.ImportCSV, nil
+	case "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return options, models.ImportNDJSON, nil
+	default:
+		return options, "", apperrors.UnsupportedMediaType("imports accept text/csv or application/x-ndjson")
+	}
+}
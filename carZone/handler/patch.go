@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/ayushi-khandal09/carZone/apperrors"
+	jsonpatch "github.com/evanphx/json-patch/v5"
+)
+
+const (
+	MergePatchType = "application/merge-patch+json"
+	JSONPatchType  = "application/json-patch+json"
+)
+
+// ReadPatch returns the body of a PATCH request as an RFC 7396 merge patch.
+// An RFC 6902 JSON Patch is applied to the document returned by current and
+// converted into the equivalent merge patch, so the caller only has to deal
+// with one format.
+func ReadPatch(r *http.Request, current func() ([]byte, error)) ([]byte, error) {
+	mediaType := MergePatchType
+	if header := r.Header.Get("Content-Type"); header != "" {
+		parsed, _, err := mime.ParseMediaType(header)
+		if err != nil {
+			return nil, apperrors.BadRequest("malformed Content-Type header", err)
+		}
+		mediaType = parsed
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, apperrors.BadRequest("unable to read request body", err)
+	}
+
+	switch mediaType {
+	case MergePatchType, "application/json":
+		return body, nil
+	case JSONPatchType:
+		patch, err := jsonpatch.DecodePatch(body)
+		if err != nil {
+			return nil, apperrors.BadRequest("malformed JSON Patch request body", err)
+		}
+		original, err := current()
+		if err != nil {
+			return nil, err
+		}
+		modified, err := patch.Apply(original)
+		if err != nil {
+			return nil, apperrors.Conflict("JSON Patch cannot be applied: "+err.Error(), nil)
+		}
+		return jsonpatch.CreateMergePatch(original, modified)
+	default:
+		return nil, apperrors.UnsupportedMediaType("PATCH accepts " + MergePatchType + " or " + JSONPatchType)
+	}
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/ayushi-khandal09/carZone/apperrors"
+)
+
+type ErrorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Field   string `json:"field,omitempty"`
+}
+
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+// StatusCode maps a domain error to the HTTP status returned to clients.
+func StatusCode(err error) int {
+	switch {
+	case errors.Is(err, apperrors.ErrBadRequest):
+		return http.StatusBadRequest
+	case errors.Is(err, apperrors.ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, apperrors.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, apperrors.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, apperrors.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, apperrors.ErrPrecondition):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, apperrors.ErrMediaType):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, apperrors.ErrValidation), errors.Is(err, apperrors.ErrForeignKey):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// WriteError writes err as a JSON error body. Internal errors are logged and
+// their details are hidden from the client. A nil logger logs to
+// slog.Default().
+func WriteError(ctx context.Context, logger *slog.Logger, w http.ResponseWriter, err error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	appErr := apperrors.As(err)
+	status := StatusCode(appErr)
+
+	message := appErr.Message
+	if status == http.StatusInternalServerError {
+		logger.ErrorContext(ctx, "request failed", "error", err)
+		message = "internal server error"
+	}
+
+	body, marshalErr := json.Marshal(ErrorResponse{Error: ErrorBody{
+		Code:    appErr.Code(),
+		Message: message,
+		Field:   appErr.Field,
+	}})
+	if marshalErr != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		logger.ErrorContext(ctx, "marshalling error response", "error", marshalErr)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	_, err = w.Write(body)
+	if err != nil {
+		logger.WarnContext(ctx, "writing response", "error", err)
+	}
+}
@@ -1,93 +1,367 @@
 package main
 
 import (
-	"database/sql"
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 
+	"github.com/ayushi-khandal09/carZone/auth"
+	"github.com/ayushi-khandal09/carZone/config"
 	"github.com/ayushi-khandal09/carZone/driver"
+	"github.com/ayushi-khandal09/carZone/exchange"
+	apiKeyHandler "github.com/ayushi-khandal09/carZone/handler/apikey"
+	auditHandler "github.com/ayushi-khandal09/carZone/handler/audit"
+	authHandler "github.com/ayushi-khandal09/carZone/handler/auth"
 	carHandler "github.com/ayushi-khandal09/carZone/handler/car"
+	catalogHandler "github.com/ayushi-khandal09/carZone/handler/catalog"
 	engineHandler "github.com/ayushi-khandal09/carZone/handler/engine"
+	healthHandler "github.com/ayushi-khandal09/carZone/handler/health"
+	importHandler "github.com/ayushi-khandal09/carZone/handler/importer"
+	"github.com/ayushi-khandal09/carZone/logging"
+	"github.com/ayushi-khandal09/carZone/metrics"
+	"github.com/ayushi-khandal09/carZone/middleware"
+	"github.com/ayushi-khandal09/carZone/migrate"
+	"github.com/ayushi-khandal09/carZone/models"
+	"github.com/ayushi-khandal09/carZone/service"
+	apiKeyService "github.com/ayushi-khandal09/carZone/service/apikey"
+	auditService "github.com/ayushi-khandal09/carZone/service/audit"
 	carService "github.com/ayushi-khandal09/carZone/service/car"
+	catalogService "github.com/ayushi-khandal09/carZone/service/catalog"
 	engineService "github.com/ayushi-khandal09/carZone/service/engine"
+	importService "github.com/ayushi-khandal09/carZone/service/importer"
+	"github.com/ayushi-khandal09/carZone/service/trash"
+	userService "github.com/ayushi-khandal09/carZone/service/user"
+	"github.com/ayushi-khandal09/carZone/store"
+	apiKeyStore "github.com/ayushi-khandal09/carZone/store/apikey"
+	auditStore "github.com/ayushi-khandal09/carZone/store/audit"
 	carStore "github.com/ayushi-khandal09/carZone/store/car"
+	catalogStore "github.com/ayushi-khandal09/carZone/store/catalog"
 	engineStore "github.com/ayushi-khandal09/carZone/store/engine"
+	"github.com/ayushi-khandal09/carZone/store/memory"
+	"github.com/ayushi-khandal09/carZone/store/migrations"
+	userStore "github.com/ayushi-khandal09/carZone/store/user"
+	"github.com/ayushi-khandal09/carZone/tracing"
 	"github.com/gorilla/mux"
-	"github.com/joho/godotenv"
 )
 
 func main() {
-	// Load environment variables
-	if err := godotenv.Load(); err != nil {
-		log.Println("Warning: No .env file found, using default environment variables")
+	// Load .env, the optional CONFIG_FILE and the environment
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	// Set up structured logging
+	level, _ := logging.ParseLevel(cfg.Log.Level)
+	logger, err := logging.New(os.Stdout, level, cfg.Log.Format)
+	if err != nil {
+		fatal(slog.Default(), "configuring logging", "error", err)
+	}
+	slog.SetDefault(logger)
+	logger.Info("loaded configuration", "config", cfg)
+
+	if err := run(cfg, logger); err != nil {
+		fatal(logger, "exiting", "error", err)
+	}
+}
+
+// run wires the service together and serves HTTP until SIGINT or SIGTERM.
+// Returning, rather than exiting, lets the deferred cleanup run.
+func run(cfg config.Config, logger *slog.Logger) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Set up tracing
+	exporter, err := tracing.NewExporter(ctx, cfg.Tracing.Exporter, os.Stdout)
+	if err != nil {
+		return fmt.Errorf("configuring tracing: %w", err)
+	}
+	tracerProvider := tracing.NewProvider(exporter, cfg.Tracing.ServiceName)
+	defer func() {
+		if err := tracerProvider.Shutdown(context.Background()); err != nil {
+			logger.Error("flushing traces", "error", err)
+		}
+	}()
+
+	// Prometheus collectors served on /metrics
+	appMetrics := metrics.New()
+
+	// Liveness and readiness probes
+	healthHandler := healthHandler.NewHealthHandler(logger)
+
+	// Initialize the storage backend
+	var carStorage store.CarStoreInterface
+	var engineStorage store.EngineStoreInterface
+	var catalogStorage store.CatalogStoreInterface
+	var userStorage store.UserStoreInterface
+	var apiKeyStorage store.APIKeyStoreInterface
+	var auditStorage store.AuditStoreInterface
+
+	switch cfg.StoreBackend {
+	case "memory":
+		if len(os.Args) > 1 && os.Args[1] == "migrate" {
+			return errors.New("the migrate command needs STORE_BACKEND=postgres")
+		}
+		logger.Warn("using the in-memory store, data will be lost on restart")
+		memoryDB := memory.NewDB()
+		carStorage = memory.NewCarStore(memoryDB)
+		engineStorage = memory.NewEngineStore(memoryDB)
+		catalogStorage = memory.NewCatalogStore(memoryDB)
+		userStorage = memory.NewUserStore(memoryDB)
+		apiKeyStorage = memory.NewAPIKeyStore(memoryDB)
+		auditStorage = memory.NewAuditStore(memoryDB)
+	case "postgres":
+		// Initialize database connection
+		pool, err := driver.InitDB(ctx, cfg.Database, logger)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := pool.Close(); err != nil {
+				logger.Error("closing database", "error", err)
+			}
+		}()
+		db := pool.DB
+
+		appMetrics.RegisterDB(db, "carzone")
+
+		migrator, err := migrate.New(db, migrations.FS)
+		if err != nil {
+			return fmt.Errorf("loading migrations: %w", err)
+		}
+
+		// carzone migrate up|down|status|redo
+		if len(os.Args) > 1 && os.Args[1] == "migrate" {
+			return runMigrate(ctx, migrator, os.Args[2:])
+		}
+
+		// Bring the schema up to date before serving requests
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return fmt.Errorf("applying migrations: %w", err)
+		}
+		for _, m := range applied {
+			logger.Info("applied migration", "version", m.Version, "name", m.Name)
+		}
+
+		healthHandler.AddCheck("database", db.PingContext)
+		healthHandler.AddCheck("migrations", migrator.CheckVersion)
+
+		carStorage = carStore.New(db, logger)
+		engineStorage = engineStore.New(db, logger)
+		catalogStorage = catalogStore.New(db, logger)
+		userStorage = userStore.New(db)
+		apiKeyStorage = apiKeyStore.New(db)
+		auditStorage = auditStore.New(db)
+	}
+
+	if cfg.Features.Metrics {
+		carStorage = metrics.InstrumentCarStore(carStorage, appMetrics)
+		engineStorage = metrics.InstrumentEngineStore(engineStorage, appMetrics)
+		catalogStorage = metrics.InstrumentCatalogStore(catalogStorage, appMetrics)
 	}
 
-	// Initialize database connection
-	driver.InitDB()
-	defer driver.CloseDB()
+	tokenManager, err := newTokenManager(cfg.Auth)
+	if err != nil {
+		return fmt.Errorf("configuring JWT: %w", err)
+	}
 
-	db := driver.GetDB()
-	if db == nil {
-		log.Fatal("Database connection failed")
+	var rates exchange.Provider
+	if cfg.Exchange.RatesFile != "" {
+		static, err := exchange.LoadStatic(cfg.Exchange.RatesFile)
+		if err != nil {
+			return fmt.Errorf("loading EXCHANGE_RATES_FILE: %w", err)
+		}
+		rates = static
 	}
 
 	// Initialize services & handlers
-	carStorage := carStore.New(db)
-	carService := carService.NewCarService(carStorage)
-	engineStorage := engineStore.New(db)
-	engineService := engineService.NewEngineService(engineStorage)
-	carHandler := carHandler.NewCarHandler(carService)
-	engineHandler := engineHandler.NewEngineHandler(engineService)
+	carService := carService.NewCarService(carStorage, rates, cfg.Batch.MaxOperations, logger)
+	engineService := engineService.NewEngineService(engineStorage, logger)
+	catalogService := catalogService.NewCatalogService(catalogStorage, logger)
+	userService := userService.NewUserService(userStorage, tokenManager, logger)
+	apiKeyService := apiKeyService.NewAPIKeyService(apiKeyStorage, logger)
+	auditService := auditService.NewAuditService(auditStorage, logger)
+	importService := importService.NewImportService(carStorage, engineStorage, logger)
+	carHandler := carHandler.NewCarHandler(carService, cfg.Batch.MaxOperations, logger)
+	engineHandler := engineHandler.NewEngineHandler(engineService, logger)
+	catalogHandler := catalogHandler.NewCatalogHandler(catalogService, logger)
+	authHandler := authHandler.NewAuthHandler(userService, logger)
+	apiKeyHandler := apiKeyHandler.NewAPIKeyHandler(apiKeyService, logger)
+	auditHandler := auditHandler.NewAuditHandler(auditService, logger)
+	importHandler := importHandler.NewImportHandler(importService, logger)
 
-	// Execute schema
-	schemaFile := "store/schema.sql"
-	if err := executeSchemaFile(db, schemaFile); err != nil {
-		log.Fatalf("Error while executing the schema file: %v", err)
+	// Permanently remove what has been in the trash for too long. The job
+	// stops, and is waited for, before the database is closed.
+	if cfg.Trash.PurgeInterval > 0 {
+		purger := trash.NewPurger(carStorage, engineStorage, cfg.Trash.Retention, logger)
+		jobCtx, stopJobs := context.WithCancel(ctx)
+		var jobs sync.WaitGroup
+		jobs.Add(1)
+		go func() {
+			defer jobs.Done()
+			purger.Run(jobCtx, cfg.Trash.PurgeInterval)
+		}()
+		defer func() {
+			stopJobs()
+			jobs.Wait()
+		}()
+	}
+
+	// Bootstrap the first admin account
+	if cfg.Auth.AdminUsername != "" {
+		if err := userService.EnsureUser(ctx, cfg.Auth.AdminUsername, cfg.Auth.AdminPassword, models.RoleAdmin); err != nil {
+			return fmt.Errorf("creating the admin user: %w", err)
+		}
 	}
 
 	// Set up routes
 	router := mux.NewRouter()
+	var keys service.APIKeyServiceInterface
+	if cfg.Features.APIKeys {
+		keys = apiKeyService
+	}
+	router.Use(middleware.Authenticate(logger, tokenManager, keys))
+
+	router.HandleFunc("/auth/token", authHandler.Token).Methods("POST")
+	if cfg.Features.Metrics {
+		router.Handle("/metrics", appMetrics.Handler()).Methods("GET")
+	}
+	router.HandleFunc("/healthz", healthHandler.Liveness).Methods("GET")
+	router.HandleFunc("/readyz", healthHandler.Readiness).Methods("GET")
+
+	scope := func(scope string, h http.HandlerFunc) http.HandlerFunc { return middleware.RequireScope(scope, h) }
+	admin := func(h http.HandlerFunc) http.HandlerFunc { return middleware.RequireRole(models.RoleAdmin, h) }
+
+	if cfg.Features.Search {
+		router.HandleFunc("/cars/search", scope(models.ScopeCarsRead, carHandler.SearchCars)).Methods("GET")
+	}
+	router.HandleFunc("/cars/trash", scope(models.ScopeCarsRead, carHandler.ListDeletedCars)).Methods("GET")
+	router.HandleFunc("/cars/price-stats", scope(models.ScopeCarsRead, carHandler.GetPriceStats)).Methods("GET")
+	router.HandleFunc("/cars/{id}", scope(models.ScopeCarsRead, carHandler.GetCarById)).Methods("GET")
+	router.HandleFunc("/cars/vin/{vin}", scope(models.ScopeCarsRead, carHandler.GetCarByVIN)).Methods("GET")
+	router.HandleFunc("/cars/{id}/price-history", scope(models.ScopeCarsRead, carHandler.GetPriceHistory)).Methods("GET")
+	router.HandleFunc("/cars", scope(models.ScopeCarsRead, carHandler.ListCars)).Methods("GET")
+	router.HandleFunc("/export/cars", scope(models.ScopeCarsRead, carHandler.ExportCars)).Methods("GET")
+	router.HandleFunc("/cars", scope(models.ScopeCarsWrite, carHandler.CreateCar)).Methods("POST")
+	router.HandleFunc("/cars/batch", scope(models.ScopeCarsWrite, carHandler.BatchCars)).Methods("POST")
+	router.HandleFunc("/cars/{id}", scope(models.ScopeCarsWrite, carHandler.UpdateCar)).Methods("PUT")
+	router.HandleFunc("/cars/{id}", scope(models.ScopeCarsWrite, carHandler.PatchCar)).Methods("PATCH")
+	router.HandleFunc("/cars/{id}", scope(models.ScopeCarsWrite, carHandler.DeleteCar)).Methods("DELETE")
+	router.HandleFunc("/cars/{id}/restore", scope(models.ScopeCarsWrite, carHandler.RestoreCar)).Methods("POST")
+
+	router.HandleFunc("/engine/trash", scope(models.ScopeEnginesRead, engineHandler.ListDeletedEngines)).Methods("GET")
+	router.HandleFunc("/engine/{id}", scope(models.ScopeEnginesRead, engineHandler.GetEngineById)).Methods("GET")
+	router.HandleFunc("/engine", scope(models.ScopeEnginesWrite, engineHandler.CreateEngine)).Methods("POST")
+	router.HandleFunc("/engine/{id}", scope(models.ScopeEnginesWrite, engineHandler.UpdateEngine)).Methods("PUT")
+	router.HandleFunc("/engine/{id}", scope(models.ScopeEnginesWrite, engineHandler.PatchEngine)).Methods("PATCH")
+	router.HandleFunc("/engine/{id}", scope(models.ScopeEnginesWrite, engineHandler.DeleteEngine)).Methods("DELETE")
+	router.HandleFunc("/engine/{id}/restore", scope(models.ScopeEnginesWrite, engineHandler.RestoreEngine)).Methods("POST")
+
+	// Cars refer to the brands and models of the catalog, which car writers
+	// maintain.
+	router.HandleFunc("/brands", scope(models.ScopeCarsRead, catalogHandler.ListBrands)).Methods("GET")
+	router.HandleFunc("/brands/{id}", scope(models.ScopeCarsRead, catalogHandler.GetBrand)).Methods("GET")
+	router.HandleFunc("/brands", scope(models.ScopeCarsWrite, catalogHandler.CreateBrand)).Methods("POST")
+	router.HandleFunc("/brands/{id}", scope(models.ScopeCarsWrite, catalogHandler.UpdateBrand)).Methods("PUT")
+	router.HandleFunc("/brands/{id}", scope(models.ScopeCarsWrite, catalogHandler.DeleteBrand)).Methods("DELETE")
+	router.HandleFunc("/brands/{id}/models", scope(models.ScopeCarsRead, catalogHandler.ListCarModels)).Methods("GET")
+	router.HandleFunc("/brands/{id}/models/{model}", scope(models.ScopeCarsRead, catalogHandler.GetCarModel)).Methods("GET")
+	router.HandleFunc("/brands/{id}/models", scope(models.ScopeCarsWrite, catalogHandler.CreateCarModel)).Methods("POST")
+	router.HandleFunc("/brands/{id}/models/{model}", scope(models.ScopeCarsWrite, catalogHandler.UpdateCarModel)).Methods("PUT")
+	router.HandleFunc("/brands/{id}/models/{model}", scope(models.ScopeCarsWrite, catalogHandler.DeleteCarModel)).Methods("DELETE")
+
+	// Imported cars can bring their own engines, so they need both scopes
+	router.HandleFunc("/import/cars", scope(models.ScopeCarsWrite, scope(models.ScopeEnginesWrite, importHandler.ImportCars))).Methods("POST")
+	router.HandleFunc("/import/engines", scope(models.ScopeEnginesWrite, importHandler.ImportEngines)).Methods("POST")
+
+	router.HandleFunc("/audit", admin(auditHandler.ListAuditEntries)).Methods("GET")
+
+	if cfg.Features.APIKeys {
+		router.HandleFunc("/admin/api-keys", admin(apiKeyHandler.CreateAPIKey)).Methods("POST")
+		router.HandleFunc("/admin/api-keys", admin(apiKeyHandler.ListAPIKeys)).Methods("GET")
+		router.HandleFunc("/admin/api-keys/{id}/rotate", admin(apiKeyHandler.RotateAPIKey)).Methods("POST")
+		router.HandleFunc("/admin/api-keys/{id}", admin(apiKeyHandler.RevokeAPIKey)).Methods("DELETE")
+	}
+
 	// Debugging: Print registered routes
 	router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
 		path, _ := route.GetPathTemplate()
 		methods, _ := route.GetMethods()
-		log.Printf("Registered route: %s %v", path, methods)
+		logger.Debug("registered route", "path", path, "methods", methods)
 		return nil
 	})
 
-	router.HandleFunc("/cars/{id}", carHandler.GetCarById).Methods("GET")
-	router.HandleFunc("/cars", carHandler.GetCarByBrand).Methods("GET")
-	router.HandleFunc("/cars", carHandler.CreateCar).Methods("POST") 
-	router.HandleFunc("/cars/{id}", carHandler.UpdateCar).Methods("PUT")
-	router.HandleFunc("/cars/{id}", carHandler.DeleteCar).Methods("DELETE")
-
-	router.HandleFunc("/engine/{id}", engineHandler.GetEngineById).Methods("GET")
-	router.HandleFunc("/engine", engineHandler.CreateEngine).Methods("POST")
-	router.HandleFunc("/engine/{id}", engineHandler.UpdateEngine).Methods("PUT")
-	router.HandleFunc("/engine/{id}", engineHandler.DeleteEngine).Methods("DELETE")
+	// Every request gets an id, a trace, an access log line and is counted
+	var h http.Handler = router
+	if cfg.Features.Metrics {
+		h = middleware.Metrics(appMetrics, router)(h)
+	}
+	h = middleware.AccessLog(logger, router)(h)
+	h = middleware.Tracing(router)(h)
+	h = middleware.RequestID(h)
 
 	// Start server
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8081"
+	server := &http.Server{
+		Addr:              cfg.Server.Addr(),
+		Handler:           h,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
 	}
-	addr := fmt.Sprintf(":%s", port)
-	log.Printf("Server listening on %s", addr)
-	log.Fatal(http.ListenAndServe(addr, router))
-}
 
-// Execute schema file for database migrations
-func executeSchemaFile(db *sql.DB, fileName string) error {
-	sqlFile, err := os.ReadFile(fileName)
-	if err != nil {
-		return err
+	serveErr := make(chan error, 1)
+	go func() {
+		logger.Info("server listening", "addr", server.Addr)
+		serveErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("serving HTTP: %w", err)
+	case <-ctx.Done():
 	}
 
-	_, err = db.Exec(string(sqlFile))
-	if err != nil {
-		return err
+	// Fail readiness and let in-flight requests finish before the deferred
+	// cleanup closes the database
+	logger.Info("shutting down", "timeout", cfg.Server.ShutdownTimeout)
+	healthHandler.Drain()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		server.Close()
+		return fmt.Errorf("draining connections: %w", err)
 	}
+	logger.Info("server stopped")
 	return nil
 }
+
+// fatal logs msg at error level and exits.
+func fatal(logger *slog.Logger, msg string, args ...any) {
+	logger.Error(msg, args...)
+	os.Exit(1)
+}
+
+// newTokenManager configures JWT signing. HS256 signs with JWT_SECRET, RS256
+// with the PEM RSA key in JWT_PRIVATE_KEY_FILE.
+func newTokenManager(cfg config.AuthConfig) (*auth.TokenManager, error) {
+	switch cfg.JWTAlgorithm {
+	case "RS256":
+		keyPEM, err := os.ReadFile(cfg.JWTPrivateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading JWT_PRIVATE_KEY_FILE: %w", err)
+		}
+		return auth.NewRS256(keyPEM, cfg.JWTIssuer, cfg.JWTTTL)
+	default:
+		return auth.NewHS256([]byte(cfg.JWTSecret), cfg.JWTIssuer, cfg.JWTTTL)
+	}
+}
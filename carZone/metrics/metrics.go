@@ -0,0 +1,99 @@
+// Package metrics defines the Prometheus collectors exported on /metrics and
+// the store decorators that feed them.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/ayushi-khandal09/carZone/apperrors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "carzone"
+
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+
+	storeDuration *prometheus.HistogramVec
+	storeErrors   *prometheus.CounterVec
+}
+
+// New creates the collectors on a fresh registry together with the Go runtime
+// and process collectors.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests handled, by method, route template and status code.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency, by method, route template and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		storeDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "store_query_duration_seconds",
+			Help:      "Duration of store calls, by store and method.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"store", "method"}),
+		storeErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "store_errors_total",
+			Help:      "Store calls that returned an error, by store, method and error code.",
+		}, []string{"store", "method", "code"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.storeDuration,
+		m.storeErrors,
+	)
+	return m
+}
+
+// RegisterDB exports the connection pool statistics of db as the
+// go_sql_open_connections, go_sql_in_use_connections, go_sql_idle_connections
+// and go_sql_wait_count_total families, among others.
+func (m *Metrics) RegisterDB(db *sql.DB, name string) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// ObserveRequest records one HTTP request.
+func (m *Metrics) ObserveRequest(method, route, status string, duration time.Duration) {
+	m.httpRequests.WithLabelValues(method, route, status).Inc()
+	m.httpDuration.WithLabelValues(method, route, status).Observe(duration.Seconds())
+}
+
+// observeStore records the duration of one store call and, when it failed,
+// the apperrors code it failed with.
+func (m *Metrics) observeStore(store, method string, start time.Time, err error) {
+	m.storeDuration.WithLabelValues(store, method).Observe(time.Since(start).Seconds())
+	if err != nil {
+		m.storeErrors.WithLabelValues(store, method, apperrors.As(err).Code()).Inc()
+	}
+}
+
+func observe[T any](m *Metrics, store, method string, fn func() (T, error)) (T, error) {
+	start := time.Now()
+	v, err := fn()
+	m.observeStore(store, method, start, err)
+	return v, err
+}
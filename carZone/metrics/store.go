@@ -0,0 +1,259 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/ayushi-khandal09/carZone/models"
+	"github.com/ayushi-khandal09/carZone/store"
+)
+
+type carStore struct {
+	next    store.CarStoreInterface
+	metrics *Metrics
+}
+
+// InstrumentCarStore records the duration and errors of every call to next
+// under the "car" store label.
+func InstrumentCarStore(next store.CarStoreInterface, m *Metrics) store.CarStoreInterface {
+	return carStore{next: next, metrics: m}
+}
+
+func (s carStore) GetCarById(ctx context.Context, id string) (models.Car, error) {
+	return observe(s.metrics, "car", "GetCarById", func() (models.Car, error) {
+		return s.next.GetCarById(ctx, id)
+	})
+}
+
+func (s carStore) GetCarByVIN(ctx context.Context, vin string) (models.Car, error) {
+	return observe(s.metrics, "car", "GetCarByVIN", func() (models.Car, error) {
+		return s.next.GetCarByVIN(ctx, vin)
+	})
+}
+
+func (s carStore) GetCarByBrand(ctx context.Context, brand string, isEngine bool) ([]models.Car, error) {
+	return observe(s.metrics, "car", "GetCarByBrand", func() ([]models.Car, error) {
+		return s.next.GetCarByBrand(ctx, brand, isEngine)
+	})
+}
+
+func (s carStore) ListCars(ctx context.Context, filter models.CarFilter) (models.CarPage, error) {
+	return observe(s.metrics, "car", "ListCars", func() (models.CarPage, error) {
+		return s.next.ListCars(ctx, filter)
+	})
+}
+
+func (s carStore) SearchCars(ctx context.Context, query string, limit int) ([]models.CarSearchResult, error) {
+	return observe(s.metrics, "car", "SearchCars", func() ([]models.CarSearchResult, error) {
+		return s.next.SearchCars(ctx, query, limit)
+	})
+}
+
+func (s carStore) CreateCar(ctx context.Context, carReq *models.CarRequest) (models.Car, error) {
+	return observe(s.metrics, "car", "CreateCar", func() (models.Car, error) {
+		return s.next.CreateCar(ctx, carReq)
+	})
+}
+
+func (s carStore) UpdateCar(ctx context.Context, id string, carReq *models.CarRequest, version int64) (models.Car, error) {
+	return observe(s.metrics, "car", "UpdateCar", func() (models.Car, error) {
+		return s.next.UpdateCar(ctx, id, carReq, version)
+	})
+}
+
+func (s carStore) DeleteCar(ctx context.Context, id string, version int64) (models.Car, error) {
+	return observe(s.metrics, "car", "DeleteCar", func() (models.Car, error) {
+		return s.next.DeleteCar(ctx, id, version)
+	})
+}
+
+func (s carStore) PatchCar(ctx context.Context, id string, patch models.CarPatch, version int64) (models.Car, error) {
+	return observe(s.metrics, "car", "PatchCar", func() (models.Car, error) {
+		return s.next.PatchCar(ctx, id, patch, version)
+	})
+}
+
+func (s carStore) DeletedCars(ctx context.Context) ([]models.Car, error) {
+	return observe(s.metrics, "car", "DeletedCars", func() ([]models.Car, error) {
+		return s.next.DeletedCars(ctx)
+	})
+}
+
+func (s carStore) RestoreCar(ctx context.Context, id string) (models.Car, error) {
+	return observe(s.metrics, "car", "RestoreCar", func() (models.Car, error) {
+		return s.next.RestoreCar(ctx, id)
+	})
+}
+
+func (s carStore) PurgeCars(ctx context.Context, before time.Time) (int64, error) {
+	return observe(s.metrics, "car", "PurgeCars", func() (int64, error) {
+		return s.next.PurgeCars(ctx, before)
+	})
+}
+
+func (s carStore) PriceHistory(ctx context.Context, id string) (models.PriceHistory, error) {
+	return observe(s.metrics, "car", "PriceHistory", func() (models.PriceHistory, error) {
+		return s.next.PriceHistory(ctx, id)
+	})
+}
+
+func (s carStore) PriceStats(ctx context.Context, filter models.PriceStatsFilter) ([]models.PriceStats, error) {
+	return observe(s.metrics, "car", "PriceStats", func() ([]models.PriceStats, error) {
+		return s.next.PriceStats(ctx, filter)
+	})
+}
+
+func (s carStore) ExportCars(ctx context.Context, filter models.CarFilter, fn func(models.Car) error) error {
+	_, err := observe(s.metrics, "car", "ExportCars", func() (struct{}, error) {
+		return struct{}{}, s.next.ExportCars(ctx, filter, fn)
+	})
+	return err
+}
+
+func (s carStore) ImportCars(ctx context.Context, rows []models.CarImportRow, options models.ImportOptions) ([]models.CarImportRow, error) {
+	return observe(s.metrics, "car", "ImportCars", func() ([]models.CarImportRow, error) {
+		return s.next.ImportCars(ctx, rows, options)
+	})
+}
+
+func (s carStore) BatchCars(ctx context.Context, items []models.CarBatchItem, atomic bool) ([]models.CarBatchItem, error) {
+	return observe(s.metrics, "car", "BatchCars", func() ([]models.CarBatchItem, error) {
+		return s.next.BatchCars(ctx, items, atomic)
+	})
+}
+
+type engineStore struct {
+	next    store.EngineStoreInterface
+	metrics *Metrics
+}
+
+// InstrumentEngineStore records the duration and errors of every call to
+// next under the "engine" store label.
+func InstrumentEngineStore(next store.EngineStoreInterface, m *Metrics) store.EngineStoreInterface {
+	return engineStore{next: next, metrics: m}
+}
+
+func (s engineStore) EngineById(ctx context.Context, id string) (models.Engine, error) {
+	return observe(s.metrics, "engine", "EngineById", func() (models.Engine, error) {
+		return s.next.EngineById(ctx, id)
+	})
+}
+
+func (s engineStore) EngineCreate(ctx context.Context, engineReq *models.EngineRequest) (models.Engine, error) {
+	return observe(s.metrics, "engine", "EngineCreate", func() (models.Engine, error) {
+		return s.next.EngineCreate(ctx, engineReq)
+	})
+}
+
+func (s engineStore) EngineUpdate(ctx context.Context, id string, engineReq *models.EngineRequest, version int64) (models.Engine, error) {
+	return observe(s.metrics, "engine", "EngineUpdate", func() (models.Engine, error) {
+		return s.next.EngineUpdate(ctx, id, engineReq, version)
+	})
+}
+
+func (s engineStore) EngineDelete(ctx context.Context, id string, version int64) (models.Engine, error) {
+	return observe(s.metrics, "engine", "EngineDelete", func() (models.Engine, error) {
+		return s.next.EngineDelete(ctx, id, version)
+	})
+}
+
+func (s engineStore) EnginePatch(ctx context.Context, id string, patch models.EnginePatch, version int64) (models.Engine, error) {
+	return observe(s.metrics, "engine", "EnginePatch", func() (models.Engine, error) {
+		return s.next.EnginePatch(ctx, id, patch, version)
+	})
+}
+
+func (s engineStore) DeletedEngines(ctx context.Context) ([]models.Engine, error) {
+	return observe(s.metrics, "engine", "DeletedEngines", func() ([]models.Engine, error) {
+		return s.next.DeletedEngines(ctx)
+	})
+}
+
+func (s engineStore) EngineRestore(ctx context.Context, id string) (models.Engine, error) {
+	return observe(s.metrics, "engine", "EngineRestore", func() (models.Engine, error) {
+		return s.next.EngineRestore(ctx, id)
+	})
+}
+
+func (s engineStore) EnginePurge(ctx context.Context, before time.Time) (int64, error) {
+	return observe(s.metrics, "engine", "EnginePurge", func() (int64, error) {
+		return s.next.EnginePurge(ctx, before)
+	})
+}
+
+func (s engineStore) EngineImport(ctx context.Context, rows []models.EngineImportRow, options models.ImportOptions) ([]models.EngineImportRow, error) {
+	return observe(s.metrics, "engine", "EngineImport", func() ([]models.EngineImportRow, error) {
+		return s.next.EngineImport(ctx, rows, options)
+	})
+}
+
+type catalogStore struct {
+	next    store.CatalogStoreInterface
+	metrics *Metrics
+}
+
+// InstrumentCatalogStore records the duration and errors of every call to
+// next under the "catalog" store label.
+func InstrumentCatalogStore(next store.CatalogStoreInterface, m *Metrics) store.CatalogStoreInterface {
+	return catalogStore{next: next, metrics: m}
+}
+
+func (s catalogStore) ListBrands(ctx context.Context) ([]models.Brand, error) {
+	return observe(s.metrics, "catalog", "ListBrands", func() ([]models.Brand, error) {
+		return s.next.ListBrands(ctx)
+	})
+}
+
+func (s catalogStore) GetBrand(ctx context.Context, ref string) (models.Brand, error) {
+	return observe(s.metrics, "catalog", "GetBrand", func() (models.Brand, error) {
+		return s.next.GetBrand(ctx, ref)
+	})
+}
+
+func (s catalogStore) CreateBrand(ctx context.Context, brandReq *models.BrandRequest) (models.Brand, error) {
+	return observe(s.metrics, "catalog", "CreateBrand", func() (models.Brand, error) {
+		return s.next.CreateBrand(ctx, brandReq)
+	})
+}
+
+func (s catalogStore) UpdateBrand(ctx context.Context, ref string, brandReq *models.BrandRequest) (models.Brand, error) {
+	return observe(s.metrics, "catalog", "UpdateBrand", func() (models.Brand, error) {
+		return s.next.UpdateBrand(ctx, ref, brandReq)
+	})
+}
+
+func (s catalogStore) DeleteBrand(ctx context.Context, ref string) (models.Brand, error) {
+	return observe(s.metrics, "catalog", "DeleteBrand", func() (models.Brand, error) {
+		return s.next.DeleteBrand(ctx, ref)
+	})
+}
+
+func (s catalogStore) ListCarModels(ctx context.Context, brandRef string) ([]models.CarModel, error) {
+	return observe(s.metrics, "catalog", "ListCarModels", func() ([]models.CarModel, error) {
+		return s.next.ListCarModels(ctx, brandRef)
+	})
+}
+
+func (s catalogStore) GetCarModel(ctx context.Context, brandRef, ref string) (models.CarModel, error) {
+	return observe(s.metrics, "catalog", "GetCarModel", func() (models.CarModel, error) {
+		return s.next.GetCarModel(ctx, brandRef, ref)
+	})
+}
+
+func (s catalogStore) CreateCarModel(ctx context.Context, brandRef string, modelReq *models.CarModelRequest) (models.CarModel, error) {
+	return observe(s.metrics, "catalog", "CreateCarModel", func() (models.CarModel, error) {
+		return s.next.CreateCarModel(ctx, brandRef, modelReq)
+	})
+}
+
+func (s catalogStore) UpdateCarModel(ctx context.Context, brandRef, ref string, modelReq *models.CarModelRequest) (models.CarModel, error) {
+	return observe(s.metrics, "catalog", "UpdateCarModel", func() (models.CarModel, error) {
+		return s.next.UpdateCarModel(ctx, brandRef, ref, modelReq)
+	})
+}
+
+func (s catalogStore) DeleteCarModel(ctx context.Context, brandRef, ref string) (models.CarModel, error) {
+	return observe(s.metrics, "catalog", "DeleteCarModel", func() (models.CarModel, error) {
+		return s.next.DeleteCarModel(ctx, brandRef, ref)
+	})
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// responseRecorder remembers the status code and body size written by the
+// wrapped handler.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// RouteTemplate returns the path template of the route of router that
+// matches r, such as /cars/{id}, or "unmatched".
+func RouteTemplate(router *mux.Router, r *http.Request) string {
+	var match mux.RouteMatch
+	if router.Match(r, &match) && match.Route != nil {
+		if template, err := match.Route.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return "unmatched"
+}
+
+// AccessLog writes one log line per request with the method, route template,
+// status, latency and response size. It wraps the router rather than being
+// registered with router.Use so that unmatched requests are logged too.
+func AccessLog(logger *slog.Logger, router *mux.Router) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			recorder := &responseRecorder{ResponseWriter: w}
+
+			next.ServeHTTP(recorder, r)
+
+			status := recorder.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			level := slog.LevelInfo
+			if status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+			logger.LogAttrs(r.Context(), level, "request completed",
+				slog.String("method", r.Method),
+				slog.String("route", RouteTemplate(router, r)),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", status),
+				slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+				slog.Int("bytes", recorder.bytes),
+				slog.String("remote_addr", r.RemoteAddr),
+			)
+		})
+	}
+}
@@ -0,0 +1,112 @@
+// Package middleware holds the HTTP middleware wrapped around the router.
+package middleware
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/ayushi-khandal09/carZone/apperrors"
+	"github.com/ayushi-khandal09/carZone/auth"
+	"github.com/ayushi-khandal09/carZone/handler"
+	"github.com/ayushi-khandal09/carZone/models"
+	"github.com/ayushi-khandal09/carZone/service"
+	"github.com/gorilla/mux"
+)
+
+// Authenticate verifies bearer tokens or X-API-Key headers and stores the
+// principal in the request context. Requests without credentials continue
+// anonymously and are turned away by RequireRole or RequireScope on protected
+// routes. A nil keys service rejects X-API-Key headers.
+func Authenticate(logger *slog.Logger, tokens *auth.TokenManager, keys service.APIKeyServiceInterface) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if secret := r.Header.Get("X-API-Key"); secret != "" {
+				if keys == nil {
+					unauthorized(w, r, "API keys are disabled")
+					return
+				}
+				key, err := keys.Authenticate(r.Context(), secret)
+				if err != nil {
+					if errors.Is(err, apperrors.ErrUnauthorized) {
+						unauthorized(w, r, apperrors.As(err).Message)
+						return
+					}
+					handler.WriteError(r.Context(), logger, w, err)
+					return
+				}
+				ctx := auth.WithPrincipal(r.Context(), auth.Principal{
+					Subject:  "apikey:" + key.ID.String(),
+					Username: key.Name,
+					Scopes:   key.Scopes,
+				})
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			header := r.Header.Get("Authorization")
+			if header == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			scheme, token, ok := strings.Cut(header, " ")
+			if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+				unauthorized(w, r, "authorization header must be a bearer token")
+				return
+			}
+
+			claims, err := tokens.Verify(strings.TrimSpace(token))
+			if err != nil {
+				unauthorized(w, r, "invalid or expired token")
+				return
+			}
+
+			ctx := auth.WithPrincipal(r.Context(), auth.Principal{
+				Subject:  claims.Subject,
+				Username: claims.Username,
+				Role:     claims.Role,
+			})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequireRole only lets callers with at least role through to next.
+func RequireRole(role models.Role, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := auth.PrincipalFrom(r.Context())
+		if !ok {
+			unauthorized(w, r, "authentication required")
+			return
+		}
+		if !principal.Role.AtLeast(role) {
+			handler.WriteError(r.Context(), nil, w, apperrors.Forbidden("requires the "+string(role)+" role"))
+			return
+		}
+		next(w, r)
+	}
+}
+
+// RequireScope only lets callers granted scope through to next, either
+// directly on their API key or through their role.
+func RequireScope(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := auth.PrincipalFrom(r.Context())
+		if !ok {
+			unauthorized(w, r, "authentication required")
+			return
+		}
+		if !principal.HasScope(scope) {
+			handler.WriteError(r.Context(), nil, w, apperrors.Forbidden("requires the "+scope+" scope"))
+			return
+		}
+		next(w, r)
+	}
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request, message string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="carzone"`)
+	handler.WriteError(r.Context(), nil, w, apperrors.Unauthorized(message))
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ayushi-khandal09/carZone/metrics"
+	"github.com/gorilla/mux"
+)
+
+// Metrics counts requests and records their latency by route template and
+// status. Like AccessLog it wraps the router so unmatched requests are
+// counted under the "unmatched" route.
+func Metrics(m *metrics.Metrics, router *mux.Router) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			recorder := &responseRecorder{ResponseWriter: w}
+
+			next.ServeHTTP(recorder, r)
+
+			status := recorder.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			m.ObserveRequest(r.Method, RouteTemplate(router, r), strconv.Itoa(status), time.Since(start))
+		})
+	}
+}
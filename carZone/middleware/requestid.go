@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"net/http"
+	"regexp"
+
+	"github.com/ayushi-khandal09/carZone/logging"
+	"github.com/google/uuid"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// validRequestID keeps ids coming from clients short and printable so they
+// are safe to log.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID propagates the X-Request-ID header of the request, or generates
+// one, into the request context and the response headers.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = uuid.NewString()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(logging.WithRequestID(r.Context(), id)))
+	})
+}
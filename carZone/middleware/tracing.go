@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/ayushi-khandal09/carZone/middleware")
+
+// Tracing starts a server span for every request, continuing the trace of an
+// incoming W3C traceparent header, and stores it in the request context for
+// the service and store spans below it. The trace context is also written to
+// the response so clients can look the trace up.
+func Tracing(router *mux.Router) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			propagator := otel.GetTextMapPropagator()
+			ctx := propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+			route := RouteTemplate(router, r)
+			ctx, span := tracer.Start(ctx, r.Method+" "+route,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPRequestMethodKey.String(r.Method),
+					semconv.HTTPRoute(route),
+					semconv.URLPath(r.URL.Path),
+				),
+			)
+			defer span.End()
+
+			propagator.Inject(ctx, propagation.HeaderCarrier(w.Header()))
+			recorder := &responseRecorder{ResponseWriter: w}
+
+			next.ServeHTTP(recorder, r.WithContext(ctx))
+
+			status := recorder.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package middleware_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ayushi-khandal09/carZone/middleware"
+	carService "github.com/ayushi-khandal09/carZone/service/car"
+	"github.com/ayushi-khandal09/carZone/store/memory"
+	"github.com/ayushi-khandal09/carZone/tracing"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracingPropagatesTraceparent(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := tracing.NewProvider(exporter, "carzone-test")
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+
+	cars := carService.NewCarService(memory.NewCarStore(memory.NewDB()), nil, 100, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	router := mux.NewRouter()
+	router.HandleFunc("/cars/{id}", func(w http.ResponseWriter, r *http.Request) {
+		cars.GetCarById(r.Context(), mux.Vars(r)["id"])
+		w.WriteHeader(http.StatusNotFound)
+	})
+	handler := middleware.Tracing(router)(router)
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/cars/00000000-0000-0000-0000-000000000001", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if err := provider.ForceFlush(context.Background()); err != nil {
+		t.Fatalf("ForceFlush: %v", err)
+	}
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want the server and service spans", len(spans))
+	}
+	service, server := spans[0], spans[1]
+	if server.Name != "GET /cars/{id}" {
+		t.Errorf("server span name = %q, want GET /cars/{id}", server.Name)
+	}
+	if service.Name != "CarService.GetCarById" {
+		t.Errorf("service span name = %q, want CarService.GetCarById", service.Name)
+	}
+	if got := server.SpanContext.TraceID().String(); got != traceID {
+		t.Errorf("server span trace id = %s, want the incoming %s", got, traceID)
+	}
+	if service.Parent.SpanID() != server.SpanContext.SpanID() {
+		t.Errorf("service span is not a child of the server span")
+	}
+	if rec.Header().Get("traceparent") == "" {
+		t.Errorf("response has no traceparent header")
+	}
+}
@@ -0,0 +1,339 @@
+// Package migrate applies the versioned SQL migrations in store/migrations and
+// records them in the schema_migrations table.
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// lockID is the key of the Postgres advisory lock held while migrating, so
+// that several replicas starting at once do not race each other.
+const lockID int64 = 7_245_301_818
+
+var fileNamePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+type Status struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+	Modified  bool       `json:"modified"`
+}
+
+type appliedMigration struct {
+	checksum  string
+	appliedAt time.Time
+}
+
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// New reads the migration files from fsys. Every version must have both an up
+// and a down file.
+func New(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+func load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: invalid version: %w", entry.Name(), err)
+		}
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(content)
+			sum := sha256.Sum256(content)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s must have both an up and a down file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Migrations returns the known migrations ordered by version.
+func (m *Migrator) Migrations() []Migration {
+	return m.migrations
+}
+
+// Up applies every pending migration and returns the ones it applied.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := m.verify(applied); err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			if err := m.apply(ctx, conn, migration); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down rolls back the last steps applied migrations and returns them.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			if err := m.revert(ctx, conn, migration); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Redo rolls back the latest applied migration and applies it again.
+func (m *Migrator) Redo(ctx context.Context) (*Migration, error) {
+	var redone *Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			if err := m.revert(ctx, conn, migration); err != nil {
+				return err
+			}
+			if err := m.apply(ctx, conn, migration); err != nil {
+				return err
+			}
+			redone = &migration
+			return nil
+		}
+		return errors.New("no applied migration to redo")
+	})
+	return redone, err
+}
+
+// Status reports every known migration and whether it has been applied.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	applied, err := m.applied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if record, ok := applied[migration.Version]; ok {
+			appliedAt := record.appliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+			status.Modified = record.checksum != migration.Checksum
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// CheckVersion reports an error when the database is behind the newest
+// migration. It only reads, without creating the schema_migrations table or
+// comparing checksums, so it suits a probe that runs every few seconds.
+func (m *Migrator) CheckVersion(ctx context.Context) error {
+	if len(m.migrations) == 0 {
+		return nil
+	}
+	latest := m.migrations[len(m.migrations)-1]
+
+	var current int64
+	err := m.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&current)
+	if err != nil {
+		return err
+	}
+	if current < latest.Version {
+		return fmt.Errorf("database is at migration %d, want %d_%s", current, latest.Version, latest.Name)
+	}
+	return nil
+}
+
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockID); err != nil {
+		return fmt.Errorf("acquiring migration lock: %w", err)
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockID); err != nil {
+			slog.ErrorContext(ctx, "releasing migration lock", "error", err)
+		}
+	}()
+
+	return fn(conn)
+}
+
+func ensureTable(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		checksum VARCHAR(64) NOT NULL,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	return err
+}
+
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[int64]appliedMigration, error) {
+	if err := ensureTable(ctx, conn); err != nil {
+		return nil, err
+	}
+
+	rows, err := conn.QueryContext(ctx, "SELECT version, checksum, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int64]appliedMigration{}
+	for rows.Next() {
+		var version int64
+		var record appliedMigration
+		if err := rows.Scan(&version, &record.checksum, &record.appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = record
+	}
+	return applied, rows.Err()
+}
+
+func (m *Migrator) verify(applied map[int64]appliedMigration) error {
+	for _, migration := range m.migrations {
+		record, ok := applied[migration.Version]
+		if ok && record.checksum != migration.Checksum {
+			return fmt.Errorf("migration %d_%s was modified after it was applied", migration.Version, migration.Name)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	return inTx(ctx, conn, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+			return fmt.Errorf("applying migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)",
+			migration.Version, migration.Name, migration.Checksum)
+		return err
+	})
+}
+
+func (m *Migrator) revert(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	return inTx(ctx, conn, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, migration.Down); err != nil {
+			return fmt.Errorf("reverting migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		_, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = $1", migration.Version)
+		return err
+	})
+}
+
+func inTx(ctx context.Context, conn *sql.Conn, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			slog.ErrorContext(ctx, "rolling back migration transaction", "error", rbErr)
+		}
+		return err
+	}
+	return tx.Commit()
+}
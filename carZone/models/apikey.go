@@ -0,0 +1,82 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/ayushi-khandal09/carZone/apperrors"
+	"github.com/google/uuid"
+)
+
+const (
+	ScopeCarsRead     = "cars:read"
+	ScopeCarsWrite    = "cars:write"
+	ScopeEnginesRead  = "engines:read"
+	ScopeEnginesWrite = "engines:write"
+)
+
+// Scopes lists every scope an API key can be granted.
+var Scopes = []string{ScopeCarsRead, ScopeCarsWrite, ScopeEnginesRead, ScopeEnginesWrite}
+
+// roleScopes are the scopes implied by a user's role.
+var roleScopes = map[Role][]string{
+	RoleViewer: {ScopeCarsRead, ScopeEnginesRead},
+	RoleDealer: {ScopeCarsRead, ScopeCarsWrite, ScopeEnginesRead, ScopeEnginesWrite},
+	RoleAdmin:  Scopes,
+}
+
+func (r Role) Scopes() []string {
+	return roleScopes[r]
+}
+
+type APIKey struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+// APIKeyWithSecret is returned once when a key is created or rotated. The
+// plain key is never stored.
+type APIKeyWithSecret struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+type APIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+func (k APIKey) Revoked() bool {
+	return k.RevokedAt != nil
+}
+
+func ValidateAPIKeyRequest(keyReq APIKeyRequest) error {
+	if strings.TrimSpace(keyReq.Name) == "" {
+		return apperrors.Validation("name", "name is required")
+	}
+	if len(keyReq.Scopes) == 0 {
+		return apperrors.Validation("scopes", "at least one scope is required")
+	}
+	for _, scope := range keyReq.Scopes {
+		if !HasScope(Scopes, scope) {
+			return apperrors.Validation("scopes", "scope must be one of: "+strings.Join(Scopes, ", "))
+		}
+	}
+	return nil
+}
+
+func HasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
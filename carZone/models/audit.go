@@ -0,0 +1,159 @@
+package models
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ayushi-khandal09/carZone/apperrors"
+	"github.com/google/uuid"
+)
+
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditPatch   = "patch"
+	AuditDelete  = "delete"
+	AuditRestore = "restore"
+)
+
+// AuditEntities lists the entities whose mutations are audited.
+var AuditEntities = []string{"car", "engine", "brand", "car_model"}
+
+// AuditEntry records one mutation of a car, an engine or a catalog entry.
+// Before is empty for a create, After holds the row as it was written.
+type AuditEntry struct {
+	ID        uuid.UUID              `json:"id"`
+	Actor     string                 `json:"actor"`
+	ActorName string                 `json:"actor_name"`
+	Action    string                 `json:"action"`
+	Entity    string                 `json:"entity"`
+	EntityID  uuid.UUID              `json:"entity_id"`
+	Before    json.RawMessage        `json:"before,omitempty"`
+	After     json.RawMessage        `json:"after,omitempty"`
+	Changes   map[string]AuditChange `json:"changes,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// AuditChange is the old and new value of a field that an audited mutation
+// changed.
+type AuditChange struct {
+	From json.RawMessage `json:"from"`
+	To   json.RawMessage `json:"to"`
+}
+
+// AuditFilter describes a page of the audit log, newest entries first. Zero
+// values mean the filter is not applied.
+type AuditFilter struct {
+	Entity   string
+	EntityID uuid.UUID
+	Actor    string
+	From     time.Time
+	To       time.Time
+	Cursor   string
+	Limit    int
+}
+
+type AuditPage struct {
+	Entries    []AuditEntry `json:"entries"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
+
+// AuditCursor is the position of the last entry of a page.
+type AuditCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        uuid.UUID `json:"id"`
+}
+
+func EncodeAuditCursor(cursor AuditCursor) string {
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// DecodeAuditCursor parses the cursor of filter. It returns nil when the
+// filter asks for the first page.
+func DecodeAuditCursor(filter AuditFilter) (*AuditCursor, error) {
+	if filter.Cursor == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+	if err != nil {
+		return nil, apperrors.Validation("cursor", "cursor is malformed")
+	}
+	var cursor AuditCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return nil, apperrors.Validation("cursor", "cursor is malformed")
+	}
+	return &cursor, nil
+}
+
+// ValidateAuditFilter checks filter and fills in the default page size.
+func ValidateAuditFilter(filter *AuditFilter) error {
+	if filter.Entity != "" && !slices.Contains(AuditEntities, filter.Entity) {
+		return apperrors.Validation("entity", "entity must be one of "+strings.Join(AuditEntities, ", "))
+	}
+	if filter.EntityID != uuid.Nil && filter.Entity == "" {
+		return apperrors.Validation("entity", "entity is required when id is set")
+	}
+	if filter.Limit == 0 {
+		filter.Limit = DefaultPageSize
+	}
+	if filter.Limit < 0 || filter.Limit > MaxPageSize {
+		return apperrors.Validation("limit", "limit must be between 1 and 100")
+	}
+	if !filter.To.IsZero() && filter.From.After(filter.To) {
+		return apperrors.Validation("from", "from must not be after to")
+	}
+	_, err := DecodeAuditCursor(*filter)
+	return err
+}
+
+// FinishAuditPage trims a page fetched with one extra entry to filter.Limit,
+// sets NextCursor when more entries follow and fills in the changes of every
+// entry.
+func FinishAuditPage(page AuditPage, filter AuditFilter) AuditPage {
+	if len(page.Entries) > filter.Limit {
+		page.Entries = page.Entries[:filter.Limit]
+		last := page.Entries[len(page.Entries)-1]
+		page.NextCursor = EncodeAuditCursor(AuditCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	for i := range page.Entries {
+		page.Entries[i].Changes = AuditChanges(page.Entries[i].Before, page.Entries[i].After)
+	}
+	return page
+}
+
+// AuditChanges compares the top level fields of two JSON objects and returns
+// those that differ. A missing object counts as having no fields.
+func AuditChanges(before, after json.RawMessage) map[string]AuditChange {
+	var old, cur map[string]json.RawMessage
+	if len(before) > 0 {
+		_ = json.Unmarshal(before, &old)
+	}
+	if len(after) > 0 {
+		_ = json.Unmarshal(after, &cur)
+	}
+
+	changes := map[string]AuditChange{}
+	for field, from := range old {
+		to, ok := cur[field]
+		if !ok {
+			to = json.RawMessage("null")
+		}
+		if !bytes.Equal(from, to) {
+			changes[field] = AuditChange{From: from, To: to}
+		}
+	}
+	for field, to := range cur {
+		if _, ok := old[field]; !ok {
+			changes[field] = AuditChange{From: json.RawMessage("null"), To: to}
+		}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	return changes
+}
@@ -0,0 +1,132 @@
+package models
+
+import (
+	"strconv"
+
+	"github.com/ayushi-khandal09/carZone/apperrors"
+	"github.com/google/uuid"
+)
+
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+// CarBatchRequest is the body of POST /cars/batch. The operations run in
+// order in one transaction. Unless Atomic is set to false, a single failed
+// operation leaves every car as it was.
+type CarBatchRequest struct {
+	Atomic     *bool               `json:"atomic"`
+	Operations []CarBatchOperation `json:"operations"`
+}
+
+// CarBatchOperation creates a car from Car, replaces the car ID with Car, or
+// deletes the car ID. Version works like If-Match on PUT and DELETE.
+type CarBatchOperation struct {
+	Op      string      `json:"op"`
+	ID      string      `json:"id,omitempty"`
+	Version int64       `json:"version,omitempty"`
+	Car     *CarRequest `json:"car,omitempty"`
+}
+
+// CarBatchItem is an operation of a batch as the store applies it. ID is
+// assigned by the service for a create. Result is the car the operation left
+// behind, and Err the error that stopped it.
+type CarBatchItem struct {
+	Op      string
+	ID      uuid.UUID
+	Version int64
+	Car     CarRequest
+	Result  Car
+	Err     error
+}
+
+const (
+	BatchCreated    = "created"
+	BatchUpdated    = "updated"
+	BatchDeleted    = "deleted"
+	BatchFailed     = "failed"
+	BatchRolledBack = "rolled_back"
+)
+
+// batchStatuses is the status of an operation that was applied.
+var batchStatuses = map[string]string{
+	BatchCreate: BatchCreated,
+	BatchUpdate: BatchUpdated,
+	BatchDelete: BatchDeleted,
+}
+
+// BatchError is the error of a failed operation, in the form of an error
+// response.
+type BatchError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Field   string `json:"field,omitempty"`
+}
+
+// CarBatchResult is the outcome of the operation at Index. An operation that
+// worked but was undone by the failure of another in an atomic batch is
+// rolled_back and carries no car.
+type CarBatchResult struct {
+	Index  int         `json:"index"`
+	Op     string      `json:"op"`
+	Status string      `json:"status"`
+	ID     *uuid.UUID  `json:"id,omitempty"`
+	Car    *Car        `json:"car,omitempty"`
+	Error  *BatchError `json:"error,omitempty"`
+}
+
+type CarBatchReport struct {
+	Atomic    bool             `json:"atomic"`
+	Committed bool             `json:"committed"`
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+	Results   []CarBatchResult `json:"results"`
+}
+
+// NewCarBatchReport summarises a batch once the store has been through it.
+func NewCarBatchReport(atomic bool, items []CarBatchItem) CarBatchReport {
+	report := CarBatchReport{Atomic: atomic, Results: make([]CarBatchResult, len(items))}
+	for _, item := range items {
+		if item.Err != nil {
+			report.Failed++
+		}
+	}
+	report.Committed = !atomic || report.Failed == 0
+	for i, item := range items {
+		result := CarBatchResult{Index: i, Op: item.Op}
+		switch {
+		case item.Err != nil:
+			appErr := apperrors.As(item.Err)
+			result.Status = BatchFailed
+			result.Error = &BatchError{Code: appErr.Code(), Message: appErr.Message, Field: appErr.Field}
+		case !report.Committed:
+			result.Status = BatchRolledBack
+		default:
+			report.Succeeded++
+			car := item.Result
+			result.Car = &car
+			result.Status = batchStatuses[item.Op]
+		}
+		// The id of a create only means something once the car exists.
+		if item.ID != uuid.Nil && (item.Op != BatchCreate || result.Status == BatchCreated) {
+			id := item.ID
+			result.ID = &id
+		}
+		report.Results[i] = result
+	}
+	return report
+}
+
+// ValidateCarBatch checks the size of a batch. maxOperations bounds the
+// operations of a single batch.
+func ValidateCarBatch(req CarBatchRequest, maxOperations int) error {
+	if len(req.Operations) == 0 {
+		return apperrors.Validation("operations", "batch has no operations")
+	}
+	if len(req.Operations) > maxOperations {
+		return apperrors.Validation("operations", "a batch has at most "+strconv.Itoa(maxOperations)+" operations")
+	}
+	return nil
+}
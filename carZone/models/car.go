@@ -1,51 +1,66 @@
 package models
 
 import (
-	"errors"
 	"strconv"
 	"time"
 
+	"github.com/ayushi-khandal09/carZone/apperrors"
 	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
 )
 
 type Car struct {
-	ID        uuid.UUID `json:"id"`
-	Name      string    `json:"name"`
-	Year      string    `json:"year"`
-	Brand     string    `json:"brand"`
-	FuelType  string    `json:"fuel_type"`
-	Engine    Engine    `json:"engine"`
-	Price     float64   `json:"price"`
-	CreatedAt time.Time `json"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        uuid.UUID       `json:"id"`
+	VIN       string          `json:"vin,omitempty"`
+	Name      string          `json:"name"`
+	Year      string          `json:"year"`
+	Brand     string          `json:"brand"`
+	BrandID   uuid.UUID       `json:"brand_id"`
+	ModelID   uuid.UUID       `json:"model_id"`
+	FuelType  string          `json:"fuel_type"`
+	Engine    Engine          `json:"engine"`
+	Price     decimal.Decimal `json:"price"`
+	Currency  string          `json:"currency"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	Version   int64           `json:"version"`
+	DeletedAt *time.Time      `json:"deleted_at,omitempty"`
 }
 
+// CarRequest refers to the brand of the car, and to its model in Name, by
+// catalog id or by name. Names are matched by slug, so "toyota" and
+// "TOYOTA " both find Toyota. The car is stored with the catalog names.
 type CarRequest struct {
-	Name     string  `json:"name"`
-	Year     string  `json:"year"`
-	Brand    string  `json:"brand"`
-	FuelType string  `json:"fuel_type"`
-	Engine   Engine  `json:"engine"`
-	Price    float64 `json:"price"`
+	VIN      string          `json:"vin,omitempty"`
+	Name     string          `json:"name"`
+	Year     string          `json:"year"`
+	Brand    string          `json:"brand"`
+	FuelType string          `json:"fuel_type"`
+	Engine   Engine          `json:"engine"`
+	Price    decimal.Decimal `json:"price"`
+	Currency string          `json:"currency"`
 }
 
 func ValidateRequest(carReq CarRequest) error {
-	if err := validateName(carReq.Name); err != nil{
+	if err := validateVIN(carReq.VIN); err != nil {
 		return err
 	}
-	if err := validateYear(carReq.Year); err != nil{
+	if err := validateName(carReq.Name); err != nil {
 		return err
 	}
-	if err := validateBrand(carReq.Brand); err != nil{
+	if err := validateYear(carReq.Year); err != nil {
 		return err
 	}
-	if err := validateFuelType(carReq.FuelType); err != nil{
+	if err := validateBrand(carReq.Brand); err != nil {
+		return err
+	}
+	if err := validateFuelType(carReq.FuelType); err != nil {
 		return err
 	}
 	if err := validateEngine(carReq.Engine); err != nil {
 		return err
 	}
-	if err := validatePrice(carReq.Price); err!=nil {
+	if err := validatePrice(carReq.Price, carReq.Currency); err != nil {
 		return err
 	}
 	return nil
@@ -53,63 +68,56 @@ func ValidateRequest(carReq CarRequest) error {
 
 func validateName(name string) error {
 	if name == "" {
-		return errors.New("Name is required")
+		return apperrors.Validation("name", "Name is required")
 	}
 	return nil
 }
 
 func validateYear(year string) error {
 	if year == "" {
-		return errors.New("Year is required")
+		return apperrors.Validation("year", "Year is required")
 	}
-    _, err := strconv.Atoi(year)
+	_, err := strconv.Atoi(year)
 	if err != nil {
-		return errors.New("year must be a vaid number")
+		return apperrors.Validation("year", "year must be a vaid number")
 	}
 	currentYear := time.Now().Year()
-	yearInt,_ := strconv.Atoi(year)
+	yearInt, _ := strconv.Atoi(year)
 	if yearInt < 1886 || yearInt > currentYear {
-		return errors.New("Year must be between 188 and current year")
+		return apperrors.Validation("year", "Year must be between 188 and current year")
 	}
 	return nil
 }
 
 func validateBrand(brand string) error {
 	if brand == "" {
-		return errors.New("Brand is required")
+		return apperrors.Validation("brand", "Brand is required")
 	}
 	return nil
 }
 
-func validateFuelType(fuelType string) error{
+func validateFuelType(fuelType string) error {
 	validateFuelType := []string{"Petrol", "Diesel", "Electric", "Hybrid"}
 	for _, validType := range validateFuelType {
 		if fuelType == validType {
 			return nil
 		}
 	}
-	return errors.New("Fuel Type must be one of: Petrol, Diesel, Electric, Hybrid")
+	return apperrors.Validation("fuel_type", "Fuel Type must be one of: Petrol, Diesel, Electric, Hybrid")
 }
 
-func validateEngine(engine Engine) error{
+func validateEngine(engine Engine) error {
 	if engine.EngineID == uuid.Nil {
-		return errors.New("EngineID is required")
+		return apperrors.Validation("engine.engine_id", "EngineID is required")
 	}
 	if engine.Displacement <= 0 {
-		return errors.New("displacement must be greater than zero")
+		return apperrors.Validation("engine.displacement", "displacement must be greater than zero")
 	}
 	if engine.NoOfCyclinders <= 0 {
-		return errors.New("noOfCyclinders must be greater than zero")
+		return apperrors.Validation("engine.noOfCyclinders", "noOfCyclinders must be greater than zero")
 	}
 	if engine.CarRange <= 0 {
-		return errors.New("carRange must be greater than zero")
+		return apperrors.Validation("engine.carRange", "carRange must be greater than zero")
 	}
 	return nil
 }
-
-func validatePrice(price float64) error{
-	if price <= 0 {
-		return errors.New("Price must be greater than zero")
-	}
-	return nil
-}
\ No newline at end of file
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/ayushi-khandal09/carZone/apperrors"
+	"github.com/google/uuid"
+)
+
+// Brand is an entry of the brand catalog. Cars refer to their brand, and to
+// its models, by id or by slug.
+type Brand struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Slug      string    `json:"slug"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type BrandRequest struct {
+	Name string `json:"name"`
+}
+
+// CarModel is a model of a brand. Its slug is unique within the brand.
+// Slugs are made from names with Slug.
+type CarModel struct {
+	ID        uuid.UUID `json:"id"`
+	BrandID   uuid.UUID `json:"brand_id"`
+	Name      string    `json:"name"`
+	Slug      string    `json:"slug"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type CarModelRequest struct {
+	Name string `json:"name"`
+}
+
+// Slug returns the canonical form of a brand or model name: lower case, with
+// every run of other characters than letters and digits turned into a single
+// dash. "Toyota", "toyota" and "TOYOTA " all become "toyota".
+func Slug(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return b.String()
+}
+
+// ValidateBrandRequest trims the name of a brand, which needs a letter or
+// digit to make a slug of.
+func ValidateBrandRequest(req *BrandRequest) error {
+	return validateCatalogName(&req.Name)
+}
+
+// ValidateCarModelRequest checks req like ValidateBrandRequest.
+func ValidateCarModelRequest(req *CarModelRequest) error {
+	return validateCatalogName(&req.Name)
+}
+
+func validateCatalogName(name *string) error {
+	*name = strings.TrimSpace(*name)
+	if *name == "" {
+		return apperrors.Validation("name", "name is required")
+	}
+	if Slug(*name) == "" {
+		return apperrors.Validation("name", "name must contain a letter or digit")
+	}
+	return nil
+}
@@ -1,22 +1,25 @@
 package models
 
 import (
-	"errors"
+	"time"
 
+	"github.com/ayushi-khandal09/carZone/apperrors"
 	"github.com/google/uuid"
 )
 
 type Engine struct {
-	EngineID       uuid.UUID `json:"engine_id"`
-	Displacement   int64     `json:"displacement"`
-	NoOfCyclinders int64     `json:"noOfCyclinders"`
-	CarRange       int64     `json:"carRange"`
+	EngineID       uuid.UUID  `json:"engine_id"`
+	Displacement   int64      `json:"displacement"`
+	NoOfCyclinders int64      `json:"noOfCyclinders"`
+	CarRange       int64      `json:"carRange"`
+	Version        int64      `json:"version,omitempty"`
+	DeletedAt      *time.Time `json:"deleted_at,omitempty"`
 }
 
 type EngineRequest struct {
-	Displacement int64 `json:"displacement"`
-	NoOfCyclinders int64     `json:"noOfCyclinders"`
-	CarRange       int64     `json:"carRange"`
+	Displacement   int64 `json:"displacement"`
+	NoOfCyclinders int64 `json:"noOfCyclinders"`
+	CarRange       int64 `json:"carRange"`
 }
 
 func ValidateEngineRequest(EngineReq EngineRequest) error {
@@ -34,21 +37,21 @@ func ValidateEngineRequest(EngineReq EngineRequest) error {
 
 func validateDisplacement(displacement int64) error {
 	if displacement <= 0 {
-		return errors.New("displacement must be greater than zero")
+		return apperrors.Validation("displacement", "displacement must be greater than zero")
 	}
 	return nil
 }
 
 func validateNoOfCylinders(noOfCyclinders int64) error {
 	if noOfCyclinders <= 0 {
-		return errors.New("noOfCylinders must be greater than zero")
+		return apperrors.Validation("noOfCyclinders", "noOfCylinders must be greater than zero")
 	}
 	return nil
 }
 
 func validateCarRange(carRange int64) error {
 	if carRange <= 0 {
-		return errors.New("carRange must be greater than zero")
+		return apperrors.Validation("carRange", "carRange must be greater than zero")
 	}
 	return nil
 }
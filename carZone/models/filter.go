@@ -0,0 +1,194 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/ayushi-khandal09/carZone/apperrors"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// CarSortFields lists the columns a car listing can be sorted on.
+var CarSortFields = []string{
+	"name", "year", "brand", "fuel_type", "price", "created_at", "updated_at",
+	"displacement", "no_of_cylinders", "car_range",
+}
+
+// CarFilter describes a page of the car listing. Zero values mean the filter
+// is not applied.
+type CarFilter struct {
+	Brand           string
+	FuelType        string
+	YearFrom        int
+	YearTo          int
+	PriceMin        decimal.Decimal
+	PriceMax        decimal.Decimal
+	DisplacementMin int64
+	DisplacementMax int64
+	Cylinders       int64
+	SortBy          string
+	SortDesc        bool
+	Cursor          string
+	Limit           int
+	IsEngine        bool
+	// Currency converts the prices of the page into the given currency. It
+	// cannot be combined with PriceMin, PriceMax or sorting by price, which
+	// apply to the prices as they are stored.
+	Currency string
+}
+
+type CarPage struct {
+	Cars       []Car  `json:"cars"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Total      int64  `json:"total"`
+}
+
+// CarCursor is the position of the last car of a page in keyset pagination.
+// It remembers the sort it was produced for so it cannot be reused with a
+// different ordering.
+type CarCursor struct {
+	SortBy   string    `json:"s"`
+	SortDesc bool      `json:"d"`
+	Value    string    `json:"v"`
+	ID       uuid.UUID `json:"id"`
+}
+
+func EncodeCarCursor(cursor CarCursor) string {
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// DecodeCarCursor parses the cursor of filter. It returns nil when the filter
+// asks for the first page.
+func DecodeCarCursor(filter CarFilter) (*CarCursor, error) {
+	if filter.Cursor == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+	if err != nil {
+		return nil, apperrors.Validation("cursor", "cursor is malformed")
+	}
+	var cursor CarCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return nil, apperrors.Validation("cursor", "cursor is malformed")
+	}
+	if cursor.SortBy != filter.SortBy || cursor.SortDesc != filter.SortDesc {
+		return nil, apperrors.Validation("cursor", "cursor does not match the requested sort")
+	}
+	return &cursor, nil
+}
+
+// ValidateCarFilter checks filter and fills in the default sort and page size.
+func ValidateCarFilter(filter *CarFilter) error {
+	if filter.SortBy == "" {
+		filter.SortBy = "created_at"
+	}
+	if !isCarSortField(filter.SortBy) {
+		return apperrors.Validation("sort", "sort must be one of the car columns")
+	}
+	if filter.Limit == 0 {
+		filter.Limit = DefaultPageSize
+	}
+	if filter.Limit < 0 || filter.Limit > MaxPageSize {
+		return apperrors.Validation("limit", "limit must be between 1 and 100")
+	}
+	if filter.YearFrom < 0 || filter.YearTo < 0 {
+		return apperrors.Validation("year", "year must not be negative")
+	}
+	if filter.YearTo != 0 && filter.YearFrom > filter.YearTo {
+		return apperrors.Validation("year_from", "year_from must not be after year_to")
+	}
+	if filter.PriceMin.IsNegative() || filter.PriceMax.IsNegative() {
+		return apperrors.Validation("price", "price must not be negative")
+	}
+	if !filter.PriceMax.IsZero() && filter.PriceMin.GreaterThan(filter.PriceMax) {
+		return apperrors.Validation("price_min", "price_min must not be greater than price_max")
+	}
+	if filter.DisplacementMin < 0 || filter.DisplacementMax < 0 {
+		return apperrors.Validation("displacement", "displacement must not be negative")
+	}
+	if filter.DisplacementMax != 0 && filter.DisplacementMin > filter.DisplacementMax {
+		return apperrors.Validation("displacement_min", "displacement_min must not be greater than displacement_max")
+	}
+	if filter.Cylinders < 0 {
+		return apperrors.Validation("cylinders", "cylinders must not be negative")
+	}
+	if filter.Currency != "" {
+		if err := validateCurrency(filter.Currency); err != nil {
+			return err
+		}
+		// Prices are converted after the page is read, so the store would
+		// order and bound them in the currencies they are stored in.
+		if filter.SortBy == "price" || !filter.PriceMin.IsZero() || !filter.PriceMax.IsZero() {
+			return apperrors.Validation("currency", "currency cannot be combined with sorting or filtering by price")
+		}
+	}
+	_, err := DecodeCarCursor(*filter)
+	return err
+}
+
+func isCarSortField(field string) bool {
+	for _, f := range CarSortFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// CarSortValue returns the value of the sort field of car in the textual form
+// stored in a cursor.
+func CarSortValue(car Car, field string) string {
+	switch field {
+	case "name":
+		return car.Name
+	case "year":
+		return car.Year
+	case "brand":
+		return car.Brand
+	case "fuel_type":
+		return car.FuelType
+	case "price":
+		return car.Price.String()
+	case "updated_at":
+		return car.UpdatedAt.UTC().Format(time.RFC3339Nano)
+	case "displacement":
+		return strconv.FormatInt(car.Engine.Displacement, 10)
+	case "no_of_cylinders":
+		return strconv.FormatInt(car.Engine.NoOfCyclinders, 10)
+	case "car_range":
+		return strconv.FormatInt(car.Engine.CarRange, 10)
+	default:
+		return car.CreatedAt.UTC().Format(time.RFC3339Nano)
+	}
+}
+
+// FinishCarPage trims a page fetched with one extra row to filter.Limit and
+// sets NextCursor when more rows follow. Engine details are dropped unless the
+// filter asked for them.
+func FinishCarPage(page CarPage, filter CarFilter) CarPage {
+	if len(page.Cars) > filter.Limit {
+		page.Cars = page.Cars[:filter.Limit]
+		last := page.Cars[len(page.Cars)-1]
+		page.NextCursor = EncodeCarCursor(CarCursor{
+			SortBy:   filter.SortBy,
+			SortDesc: filter.SortDesc,
+			Value:    CarSortValue(last, filter.SortBy),
+			ID:       last.ID,
+		})
+	}
+	if !filter.IsEngine {
+		for i := range page.Cars {
+			page.Cars[i].Engine = Engine{EngineID: page.Cars[i].Engine.EngineID}
+		}
+	}
+	return page
+}
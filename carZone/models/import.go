@@ -0,0 +1,327 @@
+package models
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ayushi-khandal09/carZone/apperrors"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	ImportAllOrNothing = "all_or_nothing"
+	ImportBestEffort   = "best_effort"
+
+	ImportCSV    = "csv"
+	ImportNDJSON = "ndjson"
+
+	// MaxImportRows bounds the rows of a single import.
+	MaxImportRows = 5000
+)
+
+// ImportOptions controls how the rows of an import are written. In
+// all_or_nothing mode a single failed row leaves everything unchanged, in
+// best_effort mode the valid rows are kept. A dry run reports what would
+// happen and writes nothing.
+type ImportOptions struct {
+	DryRun bool
+	Mode   string
+}
+
+// ValidateImportOptions checks options and fills in the default mode.
+func ValidateImportOptions(options *ImportOptions) error {
+	if options.Mode == "" {
+		options.Mode = ImportAllOrNothing
+	}
+	if options.Mode != ImportAllOrNothing && options.Mode != ImportBestEffort {
+		return apperrors.Validation("mode", "mode must be all_or_nothing or best_effort")
+	}
+	return nil
+}
+
+// ImportRow is what every row of an import has: the line it was read from,
+// the id it is created with, assigned before the row reaches the store, and
+// the error that stopped it, if any.
+type ImportRow struct {
+	Line int
+	ID   uuid.UUID
+	Err  error
+}
+
+func (r ImportRow) importRow() ImportRow {
+	return r
+}
+
+// CarImportRow is a car of an import. NewEngine means the row described its
+// own engine, which is created with Car.Engine.EngineID.
+type CarImportRow struct {
+	ImportRow
+	Car       CarRequest
+	NewEngine bool
+}
+
+type EngineImportRow struct {
+	ImportRow
+	Engine EngineRequest
+}
+
+type ImportError struct {
+	Line    int    `json:"line"`
+	Code    string `json:"code"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+type ImportedRow struct {
+	Line int       `json:"line"`
+	ID   uuid.UUID `json:"id"`
+}
+
+// ImportReport is the outcome of an import. Created lists the ids of the
+// rows that were written and is left out of dry runs.
+type ImportReport struct {
+	DryRun   bool          `json:"dry_run"`
+	Mode     string        `json:"mode"`
+	Rows     int           `json:"rows"`
+	Imported int           `json:"imported"`
+	Failed   int           `json:"failed"`
+	Created  []ImportedRow `json:"created,omitempty"`
+	Errors   []ImportError `json:"errors"`
+}
+
+// NewImportReport summarises the rows of an import once the store has been
+// through them. In all_or_nothing mode nothing counts as imported once a row
+// failed.
+func NewImportReport[T interface{ importRow() ImportRow }](options ImportOptions, rows []T) ImportReport {
+	report := ImportReport{DryRun: options.DryRun, Mode: options.Mode, Rows: len(rows), Errors: []ImportError{}}
+	for _, row := range rows {
+		if row := row.importRow(); row.Err != nil {
+			appErr := apperrors.As(row.Err)
+			report.Errors = append(report.Errors, ImportError{
+				Line:    row.Line,
+				Code:    appErr.Code(),
+				Field:   appErr.Field,
+				Message: appErr.Message,
+			})
+		}
+	}
+	report.Failed = len(report.Errors)
+	if report.Failed > 0 && options.Mode == ImportAllOrNothing {
+		return report
+	}
+	report.Imported = report.Rows - report.Failed
+	if options.DryRun {
+		return report
+	}
+	for _, row := range rows {
+		if row := row.importRow(); row.Err == nil {
+			report.Created = append(report.Created, ImportedRow{Line: row.Line, ID: row.ID})
+		}
+	}
+	return report
+}
+
+// carImportColumns maps the CSV header of a car import to the fields they
+// set. The engine is either referenced by engine_id or described by the
+// three engine columns.
+var carImportColumns = map[string]func(row *CarImportRow, value string) error{
+	"vin":       func(row *CarImportRow, value string) error { row.Car.VIN = value; return nil },
+	"name":      func(row *CarImportRow, value string) error { row.Car.Name = value; return nil },
+	"year":      func(row *CarImportRow, value string) error { row.Car.Year = value; return nil },
+	"brand":     func(row *CarImportRow, value string) error { row.Car.Brand = value; return nil },
+	"fuel_type": func(row *CarImportRow, value string) error { row.Car.FuelType = value; return nil },
+	"currency":  func(row *CarImportRow, value string) error { row.Car.Currency = value; return nil },
+	"price": func(row *CarImportRow, value string) error {
+		price, err := decimal.NewFromString(value)
+		if err != nil {
+			return apperrors.Validation("price", "price must be a valid number")
+		}
+		row.Car.Price = price
+		return nil
+	},
+	"engine_id": func(row *CarImportRow, value string) error {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return apperrors.Validation("engine.engine_id", "engine_id must be a valid UUID")
+		}
+		row.Car.Engine.EngineID = id
+		return nil
+	},
+	"displacement": func(row *CarImportRow, value string) error {
+		return parseImportInt("engine.displacement", value, &row.Car.Engine.Displacement)
+	},
+	"noOfCyclinders": func(row *CarImportRow, value string) error {
+		return parseImportInt("engine.noOfCyclinders", value, &row.Car.Engine.NoOfCyclinders)
+	},
+	"carRange": func(row *CarImportRow, value string) error {
+		return parseImportInt("engine.carRange", value, &row.Car.Engine.CarRange)
+	},
+}
+
+var engineImportColumns = map[string]func(row *EngineImportRow, value string) error{
+	"displacement": func(row *EngineImportRow, value string) error {
+		return parseImportInt("displacement", value, &row.Engine.Displacement)
+	},
+	"noOfCyclinders": func(row *EngineImportRow, value string) error {
+		return parseImportInt("noOfCyclinders", value, &row.Engine.NoOfCyclinders)
+	},
+	"carRange": func(row *EngineImportRow, value string) error {
+		return parseImportInt("carRange", value, &row.Engine.CarRange)
+	},
+}
+
+func parseImportInt(field, value string, dst *int64) error {
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return apperrors.Validation(field, field+" must be a valid number")
+	}
+	*dst = n
+	return nil
+}
+
+// DecodeCarImport reads the cars of an import in the given format. Rows
+// that cannot be read carry their error and the others are returned as
+// they are, to be validated by the caller. An error is only returned when
+// the input as a whole is unusable.
+func DecodeCarImport(r io.Reader, format string) ([]CarImportRow, error) {
+	switch format {
+	case ImportCSV:
+		return decodeCSV(r, carImportColumns, func(line int) CarImportRow { return CarImportRow{ImportRow: ImportRow{Line: line}} },
+			func(row *CarImportRow, err error) { row.Err = err })
+	case ImportNDJSON:
+		return decodeNDJSON(r, func(line int, raw []byte) CarImportRow {
+			row := CarImportRow{ImportRow: ImportRow{Line: line}}
+			if err := decodeImportObject(raw, &row.Car); err != nil {
+				row.Err = err
+			}
+			return row
+		})
+	default:
+		return nil, apperrors.UnsupportedMediaType("imports are read from CSV or NDJSON")
+	}
+}
+
+// DecodeEngineImport reads the engines of an import, like DecodeCarImport.
+func DecodeEngineImport(r io.Reader, format string) ([]EngineImportRow, error) {
+	switch format {
+	case ImportCSV:
+		return decodeCSV(r, engineImportColumns, func(line int) EngineImportRow { return EngineImportRow{ImportRow: ImportRow{Line: line}} },
+			func(row *EngineImportRow, err error) { row.Err = err })
+	case ImportNDJSON:
+		return decodeNDJSON(r, func(line int, raw []byte) EngineImportRow {
+			row := EngineImportRow{ImportRow: ImportRow{Line: line}}
+			if err := decodeImportObject(raw, &row.Engine); err != nil {
+				row.Err = err
+			}
+			return row
+		})
+	default:
+		return nil, apperrors.UnsupportedMediaType("imports are read from CSV or NDJSON")
+	}
+}
+
+// decodeCSV reads a CSV file whose first line names the columns. Empty cells
+// leave their field unset.
+func decodeCSV[T any](r io.Reader, columns map[string]func(*T, string) error, newRow func(line int) T, fail func(*T, error)) ([]T, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, apperrors.BadRequest("import is empty", nil)
+	}
+	if err != nil {
+		return nil, apperrors.BadRequest("malformed CSV header", err)
+	}
+	setters := make([]func(*T, string) error, len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		setter, ok := columns[name]
+		if !ok {
+			return nil, apperrors.Validation(name, "unknown column "+strconv.Quote(name))
+		}
+		setters[i] = setter
+	}
+
+	var rows []T
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			// A broken quote loses track of where the rows are, so nothing
+			// after it can be trusted.
+			return nil, apperrors.BadRequest("malformed CSV: "+err.Error(), err)
+		}
+		line, _ := reader.FieldPos(0)
+		if len(rows) == MaxImportRows {
+			return nil, apperrors.Validation("rows", "an import has at most "+strconv.Itoa(MaxImportRows)+" rows")
+		}
+		row := newRow(line)
+		if len(record) != len(header) {
+			fail(&row, apperrors.Validation("", "row has "+strconv.Itoa(len(record))+" columns, the header has "+strconv.Itoa(len(header))))
+			rows = append(rows, row)
+			continue
+		}
+		for i, value := range record {
+			if value == "" {
+				continue
+			}
+			if err := setters[i](&row, value); err != nil {
+				fail(&row, err)
+				break
+			}
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 {
+		return nil, apperrors.BadRequest("import has no rows", nil)
+	}
+	return rows, nil
+}
+
+// decodeNDJSON reads one JSON object per line. Blank lines are skipped but
+// still counted, so line numbers match the file.
+func decodeNDJSON[T any](r io.Reader, decode func(line int, raw []byte) T) ([]T, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var rows []T
+	line := 0
+	for scanner.Scan() {
+		line++
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		if len(rows) == MaxImportRows {
+			return nil, apperrors.Validation("rows", "an import has at most "+strconv.Itoa(MaxImportRows)+" rows")
+		}
+		rows = append(rows, decode(line, raw))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, apperrors.BadRequest("unable to read import on line "+strconv.Itoa(line+1), err)
+	}
+	if len(rows) == 0 {
+		return nil, apperrors.BadRequest("import has no rows", nil)
+	}
+	return rows, nil
+}
+
+func decodeImportObject(raw []byte, dst any) error {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(dst); err != nil {
+		return apperrors.Validation("", "row is not a valid JSON object: "+err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"strconv"
+
+	"github.com/ayushi-khandal09/carZone/apperrors"
+	"github.com/shopspring/decimal"
+)
+
+// DefaultCurrency is used for prices given without a currency and for the
+// prices stored before currencies were introduced.
+const DefaultCurrency = "USD"
+
+// maxPrice is the first amount that no longer fits the NUMERIC(13, 3) price
+// columns.
+var maxPrice = decimal.New(1, 10)
+
+func init() {
+	// Keep prices JSON numbers, as they were when they were float64. The
+	// decimal is written out exactly, so no precision is lost.
+	decimal.MarshalJSONWithoutQuotes = true
+}
+
+// currencies maps the supported ISO 4217 currency codes to the number of
+// digits of their minor unit.
+var currencies = map[string]int32{
+	"AED": 2, "AUD": 2, "BHD": 3, "BRL": 2, "CAD": 2, "CHF": 2, "CLP": 0,
+	"CNY": 2, "CZK": 2, "DKK": 2, "EGP": 2, "EUR": 2, "GBP": 2, "HKD": 2,
+	"HUF": 2, "IDR": 2, "ILS": 2, "INR": 2, "ISK": 0, "JOD": 3, "JPY": 0,
+	"KES": 2, "KRW": 0, "KWD": 3, "MXN": 2, "MYR": 2, "NGN": 2, "NOK": 2,
+	"NZD": 2, "OMR": 3, "PHP": 2, "PKR": 2, "PLN": 2, "SAR": 2, "SEK": 2,
+	"SGD": 2, "THB": 2, "TND": 3, "TRY": 2, "USD": 2, "VND": 0, "ZAR": 2,
+}
+
+// MinorUnits returns the number of decimal places of currency and whether
+// the currency is supported.
+func MinorUnits(currency string) (int32, bool) {
+	digits, ok := currencies[currency]
+	return digits, ok
+}
+
+// RoundPrice rounds amount to the minor unit of currency, half away from
+// zero.
+func RoundPrice(amount decimal.Decimal, currency string) decimal.Decimal {
+	digits, ok := currencies[currency]
+	if !ok {
+		digits = 2
+	}
+	return amount.Round(digits)
+}
+
+func validateCurrency(currency string) error {
+	if _, ok := currencies[currency]; !ok {
+		return apperrors.Validation("currency", "currency must be a supported ISO 4217 code such as USD or EUR")
+	}
+	return nil
+}
+
+// validatePrice checks that price is a positive amount of currency with no
+// more decimal places than the currency has.
+func validatePrice(price decimal.Decimal, currency string) error {
+	if err := validateCurrency(currency); err != nil {
+		return err
+	}
+	if !price.IsPositive() {
+		return apperrors.Validation("price", "Price must be greater than zero")
+	}
+	if price.GreaterThanOrEqual(maxPrice) {
+		return apperrors.Validation("price", "Price must be less than "+maxPrice.String())
+	}
+	digits := currencies[currency]
+	if !price.Equal(price.Truncate(digits)) {
+		return apperrors.Validation("price", currency+" prices have at most "+strconv.Itoa(int(digits))+" decimal places")
+	}
+	return nil
+}
@@ -0,0 +1,250 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"maps"
+	"slices"
+
+	"github.com/ayushi-khandal09/carZone/apperrors"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// CarPatch is a partial update of a car. Nil fields are left unchanged.
+type CarPatch struct {
+	VIN      *string
+	Name     *string
+	Year     *string
+	Brand    *string
+	FuelType *string
+	EngineID *uuid.UUID
+	Price    *decimal.Decimal
+	Currency *string
+}
+
+func (p CarPatch) Empty() bool {
+	return p == CarPatch{}
+}
+
+// EnginePatch is a partial update of an engine. Nil fields are left
+// unchanged.
+type EnginePatch struct {
+	Displacement   *int64
+	NoOfCyclinders *int64
+	CarRange       *int64
+}
+
+func (p EnginePatch) Empty() bool {
+	return p == EnginePatch{}
+}
+
+// DecodeCarPatch parses an RFC 7396 merge patch for a car. Members set to
+// null are rejected, as none of the fields of a car can be removed, and so
+// are the fields the server maintains. brand_id and model_id refer to the
+// catalog like brand and name do, so a patch sets one of each pair at most.
+func DecodeCarPatch(data []byte) (CarPatch, error) {
+	fields, err := decodePatchObject("", data)
+	if err != nil {
+		return CarPatch{}, err
+	}
+
+	var patch CarPatch
+	for _, name := range slices.Sorted(maps.Keys(fields)) {
+		raw := fields[name]
+		switch name {
+		case "vin":
+			patch.VIN, err = decodePatchField[string](name, raw)
+		case "name":
+			if patch.Name != nil {
+				err = apperrors.Validation(name, "name and model_id cannot both be set")
+				break
+			}
+			patch.Name, err = decodePatchField[string](name, raw)
+		case "year":
+			patch.Year, err = decodePatchField[string](name, raw)
+		case "brand":
+			patch.Brand, err = decodePatchField[string](name, raw)
+		case "brand_id":
+			if patch.Brand != nil {
+				err = apperrors.Validation(name, "brand and brand_id cannot both be set")
+				break
+			}
+			patch.Brand, err = decodeCatalogPatch(name, raw)
+		case "model_id":
+			patch.Name, err = decodeCatalogPatch(name, raw)
+		case "fuel_type":
+			patch.FuelType, err = decodePatchField[string](name, raw)
+		case "price":
+			patch.Price, err = decodePatchField[decimal.Decimal](name, raw)
+		case "currency":
+			patch.Currency, err = decodePatchField[string](name, raw)
+		case "engine":
+			patch.EngineID, err = decodeCarEnginePatch(raw)
+		case "id", "created_at", "updated_at", "version":
+			err = apperrors.Validation(name, name+" is read-only")
+		default:
+			err = apperrors.Validation(name, "unknown field")
+		}
+		if err != nil {
+			return CarPatch{}, err
+		}
+	}
+	return patch, nil
+}
+
+// decodeCarEnginePatch reads the engine member of a car patch. Only the
+// reference to the engine can be changed through a car.
+func decodeCarEnginePatch(raw json.RawMessage) (*uuid.UUID, error) {
+	fields, err := decodePatchObject("engine", raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var engineID *uuid.UUID
+	for _, name := range slices.Sorted(maps.Keys(fields)) {
+		if name != "engine_id" {
+			return nil, apperrors.Validation("engine."+name, "only engine.engine_id can be changed on a car")
+		}
+		engineID, err = decodePatchField[uuid.UUID]("engine.engine_id", fields[name])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return engineID, nil
+}
+
+// decodeCatalogPatch reads the id of a catalog entry as the reference to it
+// that a car patch carries.
+func decodeCatalogPatch(field string, raw json.RawMessage) (*string, error) {
+	id, err := decodePatchField[uuid.UUID](field, raw)
+	if err != nil {
+		return nil, err
+	}
+	ref := id.String()
+	return &ref, nil
+}
+
+// DecodeEnginePatch parses an RFC 7396 merge patch for an engine.
+func DecodeEnginePatch(data []byte) (EnginePatch, error) {
+	fields, err := decodePatchObject("", data)
+	if err != nil {
+		return EnginePatch{}, err
+	}
+
+	var patch EnginePatch
+	for _, name := range slices.Sorted(maps.Keys(fields)) {
+		raw := fields[name]
+		switch name {
+		case "displacement":
+			patch.Displacement, err = decodePatchField[int64](name, raw)
+		case "noOfCyclinders":
+			patch.NoOfCyclinders, err = decodePatchField[int64](name, raw)
+		case "carRange":
+			patch.CarRange, err = decodePatchField[int64](name, raw)
+		case "engine_id", "version":
+			err = apperrors.Validation(name, name+" is read-only")
+		default:
+			err = apperrors.Validation(name, "unknown field")
+		}
+		if err != nil {
+			return EnginePatch{}, err
+		}
+	}
+	return patch, nil
+}
+
+func decodePatchObject(field string, data []byte) (map[string]json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		if field == "" {
+			return nil, apperrors.BadRequest("merge patch must be a JSON object", nil)
+		}
+		return nil, apperrors.Validation(field, field+" cannot be removed")
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		if field == "" {
+			return nil, apperrors.BadRequest("merge patch must be a JSON object", err)
+		}
+		return nil, apperrors.Validation(field, field+" must be an object")
+	}
+	return fields, nil
+}
+
+func decodePatchField[T any](field string, raw json.RawMessage) (*T, error) {
+	if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		return nil, apperrors.Validation(field, field+" cannot be removed")
+	}
+	var value T
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, apperrors.Validation(field, field+" has an invalid value")
+	}
+	return &value, nil
+}
+
+// ValidateCarPatch validates the fields present in patch with the same rules
+// as ValidateRequest. A price is only checked together with a currency, so
+// callers fill in the current currency, or price, of the car when a patch
+// changes just one of them.
+func ValidateCarPatch(patch CarPatch) error {
+	if patch.VIN != nil {
+		if err := validateVIN(*patch.VIN); err != nil {
+			return err
+		}
+	}
+	if patch.Name != nil {
+		if err := validateName(*patch.Name); err != nil {
+			return err
+		}
+	}
+	if patch.Year != nil {
+		if err := validateYear(*patch.Year); err != nil {
+			return err
+		}
+	}
+	if patch.Brand != nil {
+		if err := validateBrand(*patch.Brand); err != nil {
+			return err
+		}
+	}
+	if patch.FuelType != nil {
+		if err := validateFuelType(*patch.FuelType); err != nil {
+			return err
+		}
+	}
+	if patch.EngineID != nil && *patch.EngineID == uuid.Nil {
+		return apperrors.Validation("engine.engine_id", "EngineID is required")
+	}
+	if patch.Currency != nil {
+		if err := validateCurrency(*patch.Currency); err != nil {
+			return err
+		}
+	}
+	if patch.Price != nil && patch.Currency != nil {
+		if err := validatePrice(*patch.Price, *patch.Currency); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateEnginePatch validates the fields present in patch with the same
+// rules as ValidateEngineRequest.
+func ValidateEnginePatch(patch EnginePatch) error {
+	if patch.Displacement != nil {
+		if err := validateDisplacement(*patch.Displacement); err != nil {
+			return err
+		}
+	}
+	if patch.NoOfCyclinders != nil {
+		if err := validateNoOfCylinders(*patch.NoOfCyclinders); err != nil {
+			return err
+		}
+	}
+	if patch.CarRange != nil {
+		if err := validateCarRange(*patch.CarRange); err != nil {
+			return err
+		}
+	}
+	return nil
+}
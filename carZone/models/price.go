@@ -0,0 +1,59 @@
+package models
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ayushi-khandal09/carZone/apperrors"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// PriceGroupings lists the columns car prices can be aggregated by.
+var PriceGroupings = []string{"brand", "fuel_type", "year"}
+
+// PriceChange is one change of the price, or the currency, of a car.
+type PriceChange struct {
+	OldPrice    decimal.Decimal `json:"old_price"`
+	OldCurrency string          `json:"old_currency"`
+	NewPrice    decimal.Decimal `json:"new_price"`
+	NewCurrency string          `json:"new_currency"`
+	ChangedAt   time.Time       `json:"changed_at"`
+}
+
+// PriceHistory is the current price of a car and how it got there, oldest
+// change first.
+type PriceHistory struct {
+	CarID    uuid.UUID       `json:"car_id"`
+	Price    decimal.Decimal `json:"price"`
+	Currency string          `json:"currency"`
+	Changes  []PriceChange   `json:"changes"`
+}
+
+// PriceStatsFilter selects the cars whose current prices are aggregated and
+// the column they are grouped by.
+type PriceStatsFilter struct {
+	GroupBy  string
+	Brand    string
+	FuelType string
+}
+
+// PriceStats aggregates the prices of the cars sharing a value of the
+// grouping column and a currency. Prices in different currencies are never
+// mixed.
+type PriceStats struct {
+	Group    string          `json:"group"`
+	Currency string          `json:"currency"`
+	Count    int64           `json:"count"`
+	Average  decimal.Decimal `json:"average"`
+	Min      decimal.Decimal `json:"min"`
+	Max      decimal.Decimal `json:"max"`
+}
+
+func ValidatePriceStatsFilter(filter PriceStatsFilter) error {
+	if !slices.Contains(PriceGroupings, filter.GroupBy) {
+		return apperrors.Validation("group_by", "group_by must be one of: "+strings.Join(PriceGroupings, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"html"
+	"strings"
+	"unicode"
+
+	"github.com/ayushi-khandal09/carZone/apperrors"
+)
+
+const (
+	// HighlightStart and HighlightStop wrap the matched words in highlights.
+	// The rest of a highlight is escaped, so it is safe to render as HTML.
+	HighlightStart = "<mark>"
+	HighlightStop  = "</mark>"
+
+	// MatchStart and MatchStop delimit the matched words in text a store
+	// highlights, before EscapeHighlight turns it into HTML. Stores take
+	// them out of the text first.
+	MatchStart = "\x02"
+	MatchStop  = "\x03"
+)
+
+var highlightReplacer = strings.NewReplacer(MatchStart, HighlightStart, MatchStop, HighlightStop)
+
+// EscapeHighlight escapes text as HTML and turns the matches delimited by
+// MatchStart and MatchStop into highlights.
+func EscapeHighlight(text string) string {
+	return highlightReplacer.Replace(html.EscapeString(text))
+}
+
+type CarSearchResult struct {
+	Car        Car               `json:"car"`
+	Rank       float64           `json:"rank"`
+	Highlights map[string]string `json:"highlights,omitempty"`
+}
+
+// SearchTerms splits a free text query into lower case words, dropping
+// anything that is not a letter or a digit.
+func SearchTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// ValidateSearch checks the search query and fills in the default limit.
+func ValidateSearch(query string, limit *int) error {
+	if len(SearchTerms(query)) == 0 {
+		return apperrors.Validation("q", "search query is required")
+	}
+	if *limit == 0 {
+		*limit = DefaultPageSize
+	}
+	if *limit < 0 || *limit > MaxPageSize {
+		return apperrors.Validation("limit", "limit must be between 1 and 100")
+	}
+	return nil
+}
+
+// CarHighlights keeps only the fields that contain a highlighted match.
+func CarHighlights(fields map[string]string) map[string]string {
+	highlights := map[string]string{}
+	for field, value := range fields {
+		if strings.Contains(value, HighlightStart) {
+			highlights[field] = value
+		}
+	}
+	return highlights
+}
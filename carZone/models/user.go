@@ -0,0 +1,64 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/ayushi-khandal09/carZone/apperrors"
+	"github.com/google/uuid"
+)
+
+type Role string
+
+const (
+	RoleViewer Role = "viewer"
+	RoleDealer Role = "dealer"
+	RoleAdmin  Role = "admin"
+)
+
+var roleRank = map[Role]int{
+	RoleViewer: 1,
+	RoleDealer: 2,
+	RoleAdmin:  3,
+}
+
+func (r Role) Valid() bool {
+	_, ok := roleRank[r]
+	return ok
+}
+
+// AtLeast reports whether r grants everything min grants. Roles are ordered
+// viewer < dealer < admin.
+func (r Role) AtLeast(min Role) bool {
+	return r.Valid() && roleRank[r] >= roleRank[min]
+}
+
+type User struct {
+	ID           uuid.UUID `json:"id"`
+	Username     string    `json:"username"`
+	PasswordHash string    `json:"-"`
+	Role         Role      `json:"role"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+func ValidateLoginRequest(loginReq LoginRequest) error {
+	if strings.TrimSpace(loginReq.Username) == "" {
+		return apperrors.Validation("username", "username is required")
+	}
+	if loginReq.Password == "" {
+		return apperrors.Validation("password", "password is required")
+	}
+	return nil
+}
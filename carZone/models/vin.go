@@ -0,0 +1,61 @@
+package models
+
+import (
+	"strings"
+
+	"github.com/ayushi-khandal09/carZone/apperrors"
+)
+
+// VINLength is the length of a vehicle identification number.
+const VINLength = 17
+
+// vinValues transliterates the characters of a VIN into the values summed
+// for its check digit. I, O and Q are left out as they read like 1 and 0.
+var vinValues = map[rune]int{
+	'0': 0, '1': 1, '2': 2, '3': 3, '4': 4, '5': 5, '6': 6, '7': 7, '8': 8, '9': 9,
+	'A': 1, 'B': 2, 'C': 3, 'D': 4, 'E': 5, 'F': 6, 'G': 7, 'H': 8,
+	'J': 1, 'K': 2, 'L': 3, 'M': 4, 'N': 5, 'P': 7, 'R': 9,
+	'S': 2, 'T': 3, 'U': 4, 'V': 5, 'W': 6, 'X': 7, 'Y': 8, 'Z': 9,
+}
+
+// vinWeights weighs each position of a VIN. The check digit itself, at
+// position 9, weighs nothing.
+var vinWeights = [VINLength]int{8, 7, 6, 5, 4, 3, 2, 10, 0, 9, 8, 7, 6, 5, 4, 3, 2}
+
+// NormalizeVIN trims vin and turns it to upper case, the form VINs are
+// stored and looked up in.
+func NormalizeVIN(vin string) string {
+	return strings.ToUpper(strings.TrimSpace(vin))
+}
+
+// ValidateVIN checks the length, the characters and the check digit of vin,
+// which must already be normalized.
+func ValidateVIN(vin string) error {
+	if len(vin) != VINLength {
+		return apperrors.Validation("vin", "vin must be 17 characters long")
+	}
+	sum := 0
+	for i, c := range vin {
+		value, ok := vinValues[c]
+		if !ok {
+			return apperrors.Validation("vin", "vin may only contain digits and the letters A to Z other than I, O and Q")
+		}
+		sum += value * vinWeights[i]
+	}
+	check := byte('0' + sum%11)
+	if sum%11 == 10 {
+		check = 'X'
+	}
+	if vin[8] != check {
+		return apperrors.Validation("vin", "vin check digit does not match")
+	}
+	return nil
+}
+
+// validateVIN checks the VIN of a car, which may be left out.
+func validateVIN(vin string) error {
+	if vin == "" {
+		return nil
+	}
+	return ValidateVIN(vin)
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ayushi-khandal09/carZone/apperrors"
+)
+
+func TestValidateVIN(t *testing.T) {
+	tests := []struct {
+		vin  string
+		want string
+	}{
+		{"1HGCM82633A004352", ""},
+		{"5YJ3E1EA2KF317000", ""},
+		// A check digit of 10 is written as X.
+		{"1M8GDM9AXKP042788", ""},
+		{"11111111111111111", ""},
+		{"1HGCM82643A004352", "check digit does not match"},
+		{"1HGCM826X3A004352", "check digit does not match"},
+		{"1M8GDM9A0KP042788", "check digit does not match"},
+		{"1HGCM82633A00435I", "may only contain"},
+		{"1HGCM82633A00435O", "may only contain"},
+		{"1HGCM82633A00435Q", "may only contain"},
+		{"1hgcm82633a004352", "may only contain"},
+		{"1HGCM82633A00435-", "may only contain"},
+		{"", "17 characters long"},
+		{"1HGCM82633A00435", "17 characters long"},
+		{"1HGCM82633A0043521", "17 characters long"},
+	}
+	for _, tt := range tests {
+		err := ValidateVIN(tt.vin)
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("ValidateVIN(%q) = %v, want nil", tt.vin, err)
+			}
+			continue
+		}
+		if !errors.Is(err, apperrors.ErrValidation) || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ValidateVIN(%q) = %v, want a validation error with %q", tt.vin, err, tt.want)
+		}
+	}
+}
+
+func TestNormalizeVIN(t *testing.T) {
+	if got := NormalizeVIN(" 1hgcm82633a004352\n"); got != "1HGCM82633A004352" {
+		t.Errorf("NormalizeVIN = %q, want 1HGCM82633A004352", got)
+	}
+}
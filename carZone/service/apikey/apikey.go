@@ -0,0 +1,133 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/ayushi-khandal09/carZone/apperrors"
+	"github.com/ayushi-khandal09/carZone/models"
+	"github.com/ayushi-khandal09/carZone/store"
+)
+
+// keyPrefix marks CarZone API keys so they are easy to spot in leaked
+// secrets scanners.
+const keyPrefix = "cz_"
+
+// touchInterval limits how often last_used_at is written for a busy key.
+const touchInterval = time.Minute
+
+type APIKeyService struct {
+	logger *slog.Logger
+	store  store.APIKeyStoreInterface
+}
+
+func NewAPIKeyService(store store.APIKeyStoreInterface, logger *slog.Logger) *APIKeyService {
+	return &APIKeyService{
+		logger: logger,
+		store:  store,
+	}
+}
+
+func (s *APIKeyService) CreateAPIKey(ctx context.Context, keyReq *models.APIKeyRequest) (*models.APIKeyWithSecret, error) {
+	if err := models.ValidateAPIKeyRequest(*keyReq); err != nil {
+		return nil, err
+	}
+	secret, prefix, hash, err := generateKey()
+	if err != nil {
+		return nil, apperrors.Internal(err)
+	}
+	created, err := s.store.CreateAPIKey(ctx, &models.APIKey{
+		Name:    strings.TrimSpace(keyReq.Name),
+		Prefix:  prefix,
+		KeyHash: hash,
+		Scopes:  keyReq.Scopes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "api key created", "api_key_id", created.ID, "scopes", created.Scopes)
+	return &models.APIKeyWithSecret{APIKey: created, Key: secret}, nil
+}
+
+func (s *APIKeyService) ListAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	keys, err := s.store.ListAPIKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// RotateAPIKey issues a new secret for the key. The old secret stops working
+// immediately.
+func (s *APIKeyService) RotateAPIKey(ctx context.Context, id string) (*models.APIKeyWithSecret, error) {
+	secret, prefix, hash, err := generateKey()
+	if err != nil {
+		return nil, apperrors.Internal(err)
+	}
+	rotated, err := s.store.RotateAPIKey(ctx, id, prefix, hash)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "api key rotated", "api_key_id", rotated.ID)
+	return &models.APIKeyWithSecret{APIKey: rotated, Key: secret}, nil
+}
+
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, id string) (*models.APIKey, error) {
+	revoked, err := s.store.RevokeAPIKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "api key revoked", "api_key_id", revoked.ID)
+	return &revoked, nil
+}
+
+// Authenticate looks up an active key by its secret and records that it was
+// used.
+func (s *APIKeyService) Authenticate(ctx context.Context, secret string) (*models.APIKey, error) {
+	if !strings.HasPrefix(secret, keyPrefix) {
+		return nil, apperrors.Unauthorized("invalid API key")
+	}
+	key, err := s.store.GetAPIKeyByHash(ctx, hashKey(secret))
+	if err != nil {
+		if errors.Is(err, apperrors.ErrNotFound) {
+			return nil, apperrors.Unauthorized("invalid API key")
+		}
+		return nil, err
+	}
+	if key.Revoked() {
+		return nil, apperrors.Unauthorized("API key has been revoked")
+	}
+
+	now := time.Now()
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > touchInterval {
+		if err := s.store.TouchAPIKey(ctx, key.ID.String(), now); err != nil {
+			s.logger.WarnContext(ctx, "recording api key usage", "api_key_id", key.ID, "error", err)
+		}
+		key.LastUsedAt = &now
+	}
+	return &key, nil
+}
+
+// generateKey returns a new secret of the form cz_<prefix>_<random>, the
+// prefix shown to admins to tell keys apart and the hash that is stored.
+func generateKey() (secret, prefix, hash string, err error) {
+	random := make([]byte, 36)
+	if _, err := rand.Read(random); err != nil {
+		return "", "", "", err
+	}
+	prefix = hex.EncodeToString(random[:4])
+	secret = keyPrefix + prefix + "_" + base64.RawURLEncoding.EncodeToString(random[4:])
+	return secret, prefix, hashKey(secret), nil
+}
+
+func hashKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
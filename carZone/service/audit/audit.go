@@ -0,0 +1,39 @@
+package audit
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/ayushi-khandal09/carZone/models"
+	"github.com/ayushi-khandal09/carZone/store"
+	"github.com/ayushi-khandal09/carZone/tracing"
+	"go.opentelemetry.io/otel"
+)
+
+var tracer = otel.Tracer("github.com/ayushi-khandal09/carZone/service/audit")
+
+type AuditService struct {
+	logger *slog.Logger
+	store  store.AuditStoreInterface
+}
+
+func NewAuditService(store store.AuditStoreInterface, logger *slog.Logger) *AuditService {
+	return &AuditService{
+		logger: logger,
+		store:  store,
+	}
+}
+
+func (s *AuditService) ListAuditEntries(ctx context.Context, filter models.AuditFilter) (_ *models.AuditPage, err error) {
+	ctx, span := tracer.Start(ctx, "AuditService.ListAuditEntries")
+	defer func() { tracing.End(span, err) }()
+
+	if err := models.ValidateAuditFilter(&filter); err != nil {
+		return nil, err
+	}
+	page, err := s.store.ListAuditEntries(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
@@ -2,22 +2,45 @@ package car
 
 import (
 	"context"
+	"errors"
+	"log/slog"
+	"strconv"
 
+	"github.com/ayushi-khandal09/carZone/apperrors"
+	"github.com/ayushi-khandal09/carZone/exchange"
 	"github.com/ayushi-khandal09/carZone/models"
 	"github.com/ayushi-khandal09/carZone/store"
+	"github.com/ayushi-khandal09/carZone/tracing"
+	"github.com/ayushi-khandal09/carZone/vin"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
 )
 
+var tracer = otel.Tracer("github.com/ayushi-khandal09/carZone/service/car")
+
 type CarService struct {
-	store store.CarStoreInterface
+	logger   *slog.Logger
+	store    store.CarStoreInterface
+	rates    exchange.Provider
+	maxBatch int
 }
 
-func NewCarService(store store.CarStoreInterface) *CarService {
+// NewCarService returns a car service. rates converts listed prices into
+// another currency; it may be nil, in which case conversions are refused.
+// maxBatch bounds the operations of a batch.
+func NewCarService(store store.CarStoreInterface, rates exchange.Provider, maxBatch int, logger *slog.Logger) *CarService {
 	return &CarService{
-		store: store,
+		logger:   logger,
+		store:    store,
+		rates:    rates,
+		maxBatch: maxBatch,
 	}
 }
 
-func (s *CarService) GetCarById(ctx context.Context, id string) (*models.Car, error) {
+func (s *CarService) GetCarById(ctx context.Context, id string) (_ *models.Car, err error) {
+	ctx, span := tracer.Start(ctx, "CarService.GetCarById")
+	defer func() { tracing.End(span, err) }()
+
 	car, err := s.store.GetCarById(ctx, id)
 	if err != nil {
 		return nil, err
@@ -25,7 +48,26 @@ func (s *CarService) GetCarById(ctx context.Context, id string) (*models.Car, er
 	return &car, nil
 }
 
-func (s *CarService) GetCarsByBrand(ctx context.Context, brand string, isEngine bool) ([]models.Car, error) {
+// GetCarByVIN finds a car by its VIN, in any case.
+func (s *CarService) GetCarByVIN(ctx context.Context, vin string) (_ *models.Car, err error) {
+	ctx, span := tracer.Start(ctx, "CarService.GetCarByVIN")
+	defer func() { tracing.End(span, err) }()
+
+	vin = models.NormalizeVIN(vin)
+	if err := models.ValidateVIN(vin); err != nil {
+		return nil, err
+	}
+	car, err := s.store.GetCarByVIN(ctx, vin)
+	if err != nil {
+		return nil, err
+	}
+	return &car, nil
+}
+
+func (s *CarService) GetCarsByBrand(ctx context.Context, brand string, isEngine bool) (_ []models.Car, err error) {
+	ctx, span := tracer.Start(ctx, "CarService.GetCarsByBrand")
+	defer func() { tracing.End(span, err) }()
+
 	cars, err := s.store.GetCarByBrand(ctx, brand, isEngine)
 	if err != nil {
 		return nil, err
@@ -33,7 +75,82 @@ func (s *CarService) GetCarsByBrand(ctx context.Context, brand string, isEngine
 	return cars, nil
 }
 
-func (s *CarService) CreateCar(ctx context.Context, car *models.CarRequest) (*models.Car, error) {
+func (s *CarService) ListCars(ctx context.Context, filter models.CarFilter) (_ *models.CarPage, err error) {
+	ctx, span := tracer.Start(ctx, "CarService.ListCars")
+	defer func() { tracing.End(span, err) }()
+
+	if err := models.ValidateCarFilter(&filter); err != nil {
+		return nil, err
+	}
+	if filter.Currency != "" && s.rates == nil {
+		return nil, apperrors.Validation("currency", "currency conversion is not configured")
+	}
+	page, err := s.store.ListCars(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	for i := range page.Cars {
+		if err := s.convert(ctx, &page.Cars[i], filter.Currency); err != nil {
+			return nil, err
+		}
+	}
+	return &page, nil
+}
+
+// ExportCars streams every car matching filter to fn. The filter is checked
+// like a listing's, but paging does not apply.
+func (s *CarService) ExportCars(ctx context.Context, filter models.CarFilter, fn func(models.Car) error) (err error) {
+	ctx, span := tracer.Start(ctx, "CarService.ExportCars")
+	defer func() { tracing.End(span, err) }()
+
+	if err := models.ValidateCarFilter(&filter); err != nil {
+		return err
+	}
+	if filter.Currency != "" && s.rates == nil {
+		return apperrors.Validation("currency", "currency conversion is not configured")
+	}
+	return s.store.ExportCars(ctx, filter, func(car models.Car) error {
+		if err := s.convert(ctx, &car, filter.Currency); err != nil {
+			return err
+		}
+		return fn(car)
+	})
+}
+
+// convert shows the price of car in currency. An empty currency leaves the
+// car as it is.
+func (s *CarService) convert(ctx context.Context, car *models.Car, currency string) error {
+	if currency == "" {
+		return nil
+	}
+	price, err := exchange.Convert(ctx, s.rates, car.Price, car.Currency, currency)
+	if err != nil {
+		return err
+	}
+	car.Price = price
+	car.Currency = currency
+	return nil
+}
+
+func (s *CarService) SearchCars(ctx context.Context, query string, limit int) (_ []models.CarSearchResult, err error) {
+	ctx, span := tracer.Start(ctx, "CarService.SearchCars")
+	defer func() { tracing.End(span, err) }()
+
+	if err := models.ValidateSearch(query, &limit); err != nil {
+		return nil, err
+	}
+	results, err := s.store.SearchCars(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (s *CarService) CreateCar(ctx context.Context, car *models.CarRequest) (_ *models.Car, err error) {
+	ctx, span := tracer.Start(ctx, "CarService.CreateCar")
+	defer func() { tracing.End(span, err) }()
+
+	withDefaults(car, true)
 	if err := models.ValidateRequest(*car); err != nil {
 		return nil, err
 	}
@@ -41,25 +158,215 @@ func (s *CarService) CreateCar(ctx context.Context, car *models.CarRequest) (*mo
 	if err != nil {
 		return nil, err
 	}
+	s.logger.InfoContext(ctx, "car created", "car_id", createdCar.ID)
 	return &createdCar, nil
 }
 
-func (s *CarService) UpdateCar(ctx context.Context, id string, carReq *models.CarRequest) (*models.Car, error) {
+func (s *CarService) UpdateCar(ctx context.Context, id string, carReq *models.CarRequest, version int64) (_ *models.Car, err error) {
+	ctx, span := tracer.Start(ctx, "CarService.UpdateCar")
+	defer func() { tracing.End(span, err) }()
+
+	withDefaults(carReq, false)
 	if err := models.ValidateRequest(*carReq); err != nil {
 		return nil, err
 	}
 
-	updatedCar, err := s.store.UpdateCar(ctx, id, *&carReq)
+	updatedCar, err := s.store.UpdateCar(ctx, id, *&carReq, version)
 	if err != nil {
 		return nil, err
 	}
+	s.logger.InfoContext(ctx, "car updated", "car_id", updatedCar.ID, "version", updatedCar.Version)
 	return &updatedCar, nil
 }
 
-func (s *CarService) DeleteCar(ctx context.Context, id string) (*models.Car, error) {
-	deleteCar, err := s.store.DeleteCar(ctx, id)
+func (s *CarService) DeleteCar(ctx context.Context, id string, version int64) (_ *models.Car, err error) {
+	ctx, span := tracer.Start(ctx, "CarService.DeleteCar")
+	defer func() { tracing.End(span, err) }()
+
+	deleteCar, err := s.store.DeleteCar(ctx, id, version)
 	if err != nil {
 		return nil, err
 	}
+	s.logger.InfoContext(ctx, "car deleted", "car_id", deleteCar.ID)
 	return &deleteCar, nil
-}
\ No newline at end of file
+}
+
+// PatchCar applies a partial update. An empty patch changes nothing and
+// returns the car as it is.
+func (s *CarService) PatchCar(ctx context.Context, id string, patch models.CarPatch, version int64) (_ *models.Car, err error) {
+	ctx, span := tracer.Start(ctx, "CarService.PatchCar")
+	defer func() { tracing.End(span, err) }()
+
+	if patch.VIN != nil {
+		*patch.VIN = models.NormalizeVIN(*patch.VIN)
+	}
+	if err := models.ValidateCarPatch(patch); err != nil {
+		return nil, err
+	}
+	// A new price has to fit the currency it ends up in, and a new currency
+	// the price the car keeps. An unconditional patch is held to the version
+	// that check read, so the other value cannot change in between.
+	if (patch.Price == nil) != (patch.Currency == nil) {
+		read, err := s.validatePriceChange(ctx, id, patch)
+		if err != nil {
+			return nil, err
+		}
+		if version == 0 {
+			version = read
+		}
+	}
+
+	if patch.Empty() {
+		car, err := s.store.GetCarById(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if version != 0 && car.Version != version {
+			return nil, apperrors.PreconditionFailed("car has been modified, it is now at version " + strconv.FormatInt(car.Version, 10))
+		}
+		return &car, nil
+	}
+
+	patchedCar, err := s.store.PatchCar(ctx, id, patch, version)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "car patched", "car_id", patchedCar.ID, "version", patchedCar.Version)
+	return &patchedCar, nil
+}
+
+func (s *CarService) ListDeletedCars(ctx context.Context) (_ []models.Car, err error) {
+	ctx, span := tracer.Start(ctx, "CarService.ListDeletedCars")
+	defer func() { tracing.End(span, err) }()
+
+	return s.store.DeletedCars(ctx)
+}
+
+func (s *CarService) RestoreCar(ctx context.Context, id string) (_ *models.Car, err error) {
+	ctx, span := tracer.Start(ctx, "CarService.RestoreCar")
+	defer func() { tracing.End(span, err) }()
+
+	restoredCar, err := s.store.RestoreCar(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "car restored", "car_id", restoredCar.ID)
+	return &restoredCar, nil
+}
+
+func (s *CarService) GetPriceHistory(ctx context.Context, id string) (_ *models.PriceHistory, err error) {
+	ctx, span := tracer.Start(ctx, "CarService.GetPriceHistory")
+	defer func() { tracing.End(span, err) }()
+
+	history, err := s.store.PriceHistory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &history, nil
+}
+
+func (s *CarService) GetPriceStats(ctx context.Context, filter models.PriceStatsFilter) (_ []models.PriceStats, err error) {
+	ctx, span := tracer.Start(ctx, "CarService.GetPriceStats")
+	defer func() { tracing.End(span, err) }()
+
+	if err := models.ValidatePriceStatsFilter(filter); err != nil {
+		return nil, err
+	}
+	return s.store.PriceStats(ctx, filter)
+}
+
+// BatchCars checks every operation of a batch like CreateCar, UpdateCar and
+// DeleteCar would and hands them to the store together. An operation that
+// fails its checks is reported without being tried.
+func (s *CarService) BatchCars(ctx context.Context, req models.CarBatchRequest) (_ *models.CarBatchReport, err error) {
+	ctx, span := tracer.Start(ctx, "CarService.BatchCars")
+	defer func() { tracing.End(span, err) }()
+
+	if err := models.ValidateCarBatch(req, s.maxBatch); err != nil {
+		return nil, err
+	}
+	atomic := req.Atomic == nil || *req.Atomic
+
+	items := make([]models.CarBatchItem, len(req.Operations))
+	for i, op := range req.Operations {
+		items[i] = models.CarBatchItem{Op: op.Op, Version: op.Version}
+		items[i].ID, items[i].Err = batchItem(op, &items[i].Car)
+	}
+
+	items, err = s.store.BatchCars(ctx, items, atomic)
+	if err != nil {
+		return nil, err
+	}
+	for i, item := range items {
+		if item.Err != nil && errors.Is(apperrors.As(item.Err), apperrors.ErrInternal) {
+			s.logger.ErrorContext(ctx, "applying car batch", "index", i, "op", item.Op, "error", item.Err)
+		}
+	}
+	report := models.NewCarBatchReport(atomic, items)
+	s.logger.InfoContext(ctx, "car batch applied", "operations", len(items), "succeeded", report.Succeeded,
+		"failed", report.Failed, "committed", report.Committed)
+	return &report, nil
+}
+
+// batchItem checks an operation of a batch and fills in the car it writes.
+// It returns the id of the car, a new one for a create.
+func batchItem(op models.CarBatchOperation, car *models.CarRequest) (uuid.UUID, error) {
+	var id uuid.UUID
+	switch op.Op {
+	case models.BatchCreate:
+		if op.ID != "" {
+			return uuid.Nil, apperrors.Validation("id", "id is assigned to the car a create makes")
+		}
+		id = uuid.New()
+	case models.BatchUpdate, models.BatchDelete:
+		parsed, err := uuid.Parse(op.ID)
+		if err != nil {
+			return uuid.Nil, apperrors.Validation("id", "id must be a valid UUID")
+		}
+		id = parsed
+	default:
+		return uuid.Nil, apperrors.Validation("op", "op must be create, update or delete")
+	}
+
+	if op.Op == models.BatchDelete {
+		if op.Car != nil {
+			return id, apperrors.Validation("car", "a delete takes no car")
+		}
+		return id, nil
+	}
+	if op.Car == nil {
+		return id, apperrors.Validation("car", "car is required")
+	}
+	*car = *op.Car
+	withDefaults(car, op.Op == models.BatchCreate)
+	return id, models.ValidateRequest(*car)
+}
+
+// withDefaults fills in what a car request may leave out: the currency and,
+// for a new car, the brand and year its VIN tells.
+func withDefaults(car *models.CarRequest, create bool) {
+	car.VIN = models.NormalizeVIN(car.VIN)
+	if create {
+		vin.Prefill(car)
+	}
+	if car.Currency == "" {
+		car.Currency = models.DefaultCurrency
+	}
+}
+
+// validatePriceChange checks a patch that changes only one of the price and
+// the currency against the current value of the other, and returns the
+// version of the car it checked against.
+func (s *CarService) validatePriceChange(ctx context.Context, id string, patch models.CarPatch) (int64, error) {
+	car, err := s.store.GetCarById(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	if patch.Price == nil {
+		patch.Price = &car.Price
+	}
+	if patch.Currency == nil {
+		patch.Currency = &car.Currency
+	}
+	return car.Version, models.ValidateCarPatch(patch)
+}
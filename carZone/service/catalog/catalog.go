@@ -0,0 +1,145 @@
+package catalog
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/ayushi-khandal09/carZone/models"
+	"github.com/ayushi-khandal09/carZone/store"
+	"github.com/ayushi-khandal09/carZone/tracing"
+	"go.opentelemetry.io/otel"
+)
+
+var tracer = otel.Tracer("github.com/ayushi-khandal09/carZone/service/catalog")
+
+type CatalogService struct {
+	logger *slog.Logger
+	store  store.CatalogStoreInterface
+}
+
+func NewCatalogService(store store.CatalogStoreInterface, logger *slog.Logger) *CatalogService {
+	return &CatalogService{
+		logger: logger,
+		store:  store,
+	}
+}
+
+func (s *CatalogService) ListBrands(ctx context.Context) (_ []models.Brand, err error) {
+	ctx, span := tracer.Start(ctx, "CatalogService.ListBrands")
+	defer func() { tracing.End(span, err) }()
+
+	return s.store.ListBrands(ctx)
+}
+
+func (s *CatalogService) GetBrand(ctx context.Context, ref string) (_ *models.Brand, err error) {
+	ctx, span := tracer.Start(ctx, "CatalogService.GetBrand")
+	defer func() { tracing.End(span, err) }()
+
+	brand, err := s.store.GetBrand(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return &brand, nil
+}
+
+func (s *CatalogService) CreateBrand(ctx context.Context, brandReq *models.BrandRequest) (_ *models.Brand, err error) {
+	ctx, span := tracer.Start(ctx, "CatalogService.CreateBrand")
+	defer func() { tracing.End(span, err) }()
+
+	if err := models.ValidateBrandRequest(brandReq); err != nil {
+		return nil, err
+	}
+	brand, err := s.store.CreateBrand(ctx, brandReq)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "brand created", "brand_id", brand.ID, "slug", brand.Slug)
+	return &brand, nil
+}
+
+func (s *CatalogService) UpdateBrand(ctx context.Context, ref string, brandReq *models.BrandRequest) (_ *models.Brand, err error) {
+	ctx, span := tracer.Start(ctx, "CatalogService.UpdateBrand")
+	defer func() { tracing.End(span, err) }()
+
+	if err := models.ValidateBrandRequest(brandReq); err != nil {
+		return nil, err
+	}
+	brand, err := s.store.UpdateBrand(ctx, ref, brandReq)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "brand updated", "brand_id", brand.ID, "slug", brand.Slug)
+	return &brand, nil
+}
+
+func (s *CatalogService) DeleteBrand(ctx context.Context, ref string) (_ *models.Brand, err error) {
+	ctx, span := tracer.Start(ctx, "CatalogService.DeleteBrand")
+	defer func() { tracing.End(span, err) }()
+
+	brand, err := s.store.DeleteBrand(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "brand deleted", "brand_id", brand.ID)
+	return &brand, nil
+}
+
+func (s *CatalogService) ListCarModels(ctx context.Context, brandRef string) (_ []models.CarModel, err error) {
+	ctx, span := tracer.Start(ctx, "CatalogService.ListCarModels")
+	defer func() { tracing.End(span, err) }()
+
+	return s.store.ListCarModels(ctx, brandRef)
+}
+
+func (s *CatalogService) GetCarModel(ctx context.Context, brandRef, ref string) (_ *models.CarModel, err error) {
+	ctx, span := tracer.Start(ctx, "CatalogService.GetCarModel")
+	defer func() { tracing.End(span, err) }()
+
+	model, err := s.store.GetCarModel(ctx, brandRef, ref)
+	if err != nil {
+		return nil, err
+	}
+	return &model, nil
+}
+
+func (s *CatalogService) CreateCarModel(ctx context.Context, brandRef string, modelReq *models.CarModelRequest) (_ *models.CarModel, err error) {
+	ctx, span := tracer.Start(ctx, "CatalogService.CreateCarModel")
+	defer func() { tracing.End(span, err) }()
+
+	if err := models.ValidateCarModelRequest(modelReq); err != nil {
+		return nil, err
+	}
+	model, err := s.store.CreateCarModel(ctx, brandRef, modelReq)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "model created", "brand_id", model.BrandID, "model_id", model.ID, "slug", model.Slug)
+	return &model, nil
+}
+
+func (s *CatalogService) UpdateCarModel(ctx context.Context, brandRef, ref string, modelReq *models.CarModelRequest) (_ *models.CarModel, err error) {
+	ctx, span := tracer.Start(ctx, "CatalogService.UpdateCarModel")
+	defer func() { tracing.End(span, err) }()
+
+	if err := models.ValidateCarModelRequest(modelReq); err != nil {
+		return nil, err
+	}
+	model, err := s.store.UpdateCarModel(ctx, brandRef, ref, modelReq)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "model updated", "brand_id", model.BrandID, "model_id", model.ID, "slug", model.Slug)
+	return &model, nil
+}
+
+func (s *CatalogService) DeleteCarModel(ctx context.Context, brandRef, ref string) (_ *models.CarModel, err error) {
+	ctx, span := tracer.Start(ctx, "CatalogService.DeleteCarModel")
+	defer func() { tracing.End(span, err) }()
+
+	model, err := s.store.DeleteCarModel(ctx, brandRef, ref)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "model deleted", "brand_id", model.BrandID, "model_id", model.ID)
+	return &model, nil
+}
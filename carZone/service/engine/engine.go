@@ -2,22 +2,34 @@ package engine
 
 import (
 	"context"
+	"log/slog"
+	"strconv"
 
+	"github.com/ayushi-khandal09/carZone/apperrors"
 	"github.com/ayushi-khandal09/carZone/models"
 	"github.com/ayushi-khandal09/carZone/store"
+	"github.com/ayushi-khandal09/carZone/tracing"
+	"go.opentelemetry.io/otel"
 )
 
+var tracer = otel.Tracer("github.com/ayushi-khandal09/carZone/service/engine")
+
 type EngineService struct {
-	store store.EngineStoreInterface
+	logger *slog.Logger
+	store  store.EngineStoreInterface
 }
 
-func NewEngineService(store store.EngineStoreInterface) *EngineService {
+func NewEngineService(store store.EngineStoreInterface, logger *slog.Logger) *EngineService {
 	return &EngineService{
-		store: store,
+		logger: logger,
+		store:  store,
 	}
 }
 
-func(s *EngineService) GetEngineById(ctx context.Context, id string) (*models.Engine, error) {
+func (s *EngineService) GetEngineById(ctx context.Context, id string) (_ *models.Engine, err error) {
+	ctx, span := tracer.Start(ctx, "EngineService.GetEngineById")
+	defer func() { tracing.End(span, err) }()
+
 	engine, err := s.store.EngineById(ctx, id)
 	if err != nil {
 		return nil, err
@@ -25,7 +37,10 @@ func(s *EngineService) GetEngineById(ctx context.Context, id string) (*models.En
 	return &engine, nil
 }
 
-func (s *EngineService) CreateEngine(ctx context.Context, engineReq *models.EngineRequest) (*models.Engine, error) {
+func (s *EngineService) CreateEngine(ctx context.Context, engineReq *models.EngineRequest) (_ *models.Engine, err error) {
+	ctx, span := tracer.Start(ctx, "EngineService.CreateEngine")
+	defer func() { tracing.End(span, err) }()
+
 	if err := models.ValidateEngineRequest(*engineReq); err != nil {
 		return nil, err
 	}
@@ -33,24 +48,81 @@ func (s *EngineService) CreateEngine(ctx context.Context, engineReq *models.Engi
 	if err != nil {
 		return nil, err
 	}
+	s.logger.InfoContext(ctx, "engine created", "engine_id", createEngine.EngineID)
 	return &createEngine, err
 }
 
-func (s *EngineService) UpdateEngine(ctx context.Context, id string, engineReq *models.EngineRequest) (*models.Engine, error) {
+func (s *EngineService) UpdateEngine(ctx context.Context, id string, engineReq *models.EngineRequest, version int64) (_ *models.Engine, err error) {
+	ctx, span := tracer.Start(ctx, "EngineService.UpdateEngine")
+	defer func() { tracing.End(span, err) }()
+
 	if err := models.ValidateEngineRequest(*engineReq); err != nil {
 		return nil, err
 	}
-	updateEngine, err := s.store.EngineUpdate(ctx, id, engineReq)
+	updateEngine, err := s.store.EngineUpdate(ctx, id, engineReq, version)
 	if err != nil {
 		return nil, err
 	}
+	s.logger.InfoContext(ctx, "engine updated", "engine_id", updateEngine.EngineID, "version", updateEngine.Version)
 	return &updateEngine, err
 }
 
-func (s *EngineService) DeleteEngine(ctx context.Context, id string) (*models.Engine, error) {
-	deleteEngine, err := s.store.EngineDelete(ctx, id)
+func (s *EngineService) DeleteEngine(ctx context.Context, id string, version int64) (_ *models.Engine, err error) {
+	ctx, span := tracer.Start(ctx, "EngineService.DeleteEngine")
+	defer func() { tracing.End(span, err) }()
+
+	deleteEngine, err := s.store.EngineDelete(ctx, id, version)
 	if err != nil {
 		return nil, err
 	}
+	s.logger.InfoContext(ctx, "engine deleted", "engine_id", deleteEngine.EngineID)
 	return &deleteEngine, err
-}
\ No newline at end of file
+}
+
+// PatchEngine applies a partial update. An empty patch changes nothing and
+// returns the engine as it is.
+func (s *EngineService) PatchEngine(ctx context.Context, id string, patch models.EnginePatch, version int64) (_ *models.Engine, err error) {
+	ctx, span := tracer.Start(ctx, "EngineService.PatchEngine")
+	defer func() { tracing.End(span, err) }()
+
+	if err := models.ValidateEnginePatch(patch); err != nil {
+		return nil, err
+	}
+
+	if patch.Empty() {
+		engine, err := s.store.EngineById(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if version != 0 && engine.Version != version {
+			return nil, apperrors.PreconditionFailed("engine has been modified, it is now at version " + strconv.FormatInt(engine.Version, 10))
+		}
+		return &engine, nil
+	}
+
+	patchedEngine, err := s.store.EnginePatch(ctx, id, patch, version)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "engine patched", "engine_id", patchedEngine.EngineID, "version", patchedEngine.Version)
+	return &patchedEngine, nil
+}
+
+func (s *EngineService) ListDeletedEngines(ctx context.Context) (_ []models.Engine, err error) {
+	ctx, span := tracer.Start(ctx, "EngineService.ListDeletedEngines")
+	defer func() { tracing.End(span, err) }()
+
+	return s.store.DeletedEngines(ctx)
+}
+
+func (s *EngineService) RestoreEngine(ctx context.Context, id string) (_ *models.Engine, err error) {
+	ctx, span := tracer.Start(ctx, "EngineService.RestoreEngine")
+	defer func() { tracing.End(span, err) }()
+
+	restoredEngine, err := s.store.EngineRestore(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "engine restored", "engine_id", restoredEngine.EngineID)
+	return &restoredEngine, nil
+}
@@ -0,0 +1,124 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/ayushi-khandal09/carZone/apperrors"
+	"github.com/ayushi-khandal09/carZone/models"
+	"github.com/ayushi-khandal09/carZone/store"
+	"github.com/ayushi-khandal09/carZone/tracing"
+	"github.com/ayushi-khandal09/carZone/vin"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+var tracer = otel.Tracer("github.com/ayushi-khandal09/carZone/service/importer")
+
+type ImportService struct {
+	logger  *slog.Logger
+	cars    store.CarStoreInterface
+	engines store.EngineStoreInterface
+}
+
+func NewImportService(cars store.CarStoreInterface, engines store.EngineStoreInterface, logger *slog.Logger) *ImportService {
+	return &ImportService{
+		logger:  logger,
+		cars:    cars,
+		engines: engines,
+	}
+}
+
+// ImportCars validates every row with models.ValidateRequest and hands them
+// to the store together. Brands and years left out are taken from the VIN.
+// A row without an engine id brings its own engine, a row with one takes
+// the details of that engine.
+func (s *ImportService) ImportCars(ctx context.Context, rows []models.CarImportRow, options models.ImportOptions) (_ *models.ImportReport, err error) {
+	ctx, span := tracer.Start(ctx, "ImportService.ImportCars")
+	defer func() { tracing.End(span, err) }()
+
+	if err := models.ValidateImportOptions(&options); err != nil {
+		return nil, err
+	}
+
+	engines := map[uuid.UUID]models.Engine{}
+	for i := range rows {
+		row := &rows[i]
+		if row.Err != nil {
+			continue
+		}
+		row.ID = uuid.New()
+		row.Car.VIN = models.NormalizeVIN(row.Car.VIN)
+		vin.Prefill(&row.Car)
+		if row.Car.Currency == "" {
+			row.Car.Currency = models.DefaultCurrency
+		}
+		if row.Car.Engine.EngineID == uuid.Nil {
+			row.NewEngine = true
+			row.Car.Engine.EngineID = uuid.New()
+		} else {
+			engine, ok := engines[row.Car.Engine.EngineID]
+			if !ok {
+				engine, err = s.engines.EngineById(ctx, row.Car.Engine.EngineID.String())
+				if errors.Is(err, apperrors.ErrNotFound) {
+					row.Err = apperrors.ForeignKey("engine.engine_id", "engine_id does not exists in the engine table")
+					continue
+				}
+				if err != nil {
+					return nil, err
+				}
+				engines[engine.EngineID] = engine
+			}
+			row.Car.Engine = engine
+		}
+		row.Err = models.ValidateRequest(row.Car)
+	}
+
+	rows, err = s.cars.ImportCars(ctx, rows, options)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		s.logFailure(ctx, "importing car", row.ImportRow)
+	}
+	report := models.NewImportReport(options, rows)
+	s.logger.InfoContext(ctx, "cars imported", "rows", report.Rows, "imported", report.Imported, "dry_run", report.DryRun)
+	return &report, nil
+}
+
+// ImportEngines validates every row with models.ValidateEngineRequest and
+// hands them to the store together.
+func (s *ImportService) ImportEngines(ctx context.Context, rows []models.EngineImportRow, options models.ImportOptions) (_ *models.ImportReport, err error) {
+	ctx, span := tracer.Start(ctx, "ImportService.ImportEngines")
+	defer func() { tracing.End(span, err) }()
+
+	if err := models.ValidateImportOptions(&options); err != nil {
+		return nil, err
+	}
+	for i := range rows {
+		if rows[i].Err == nil {
+			rows[i].ID = uuid.New()
+			rows[i].Err = models.ValidateEngineRequest(rows[i].Engine)
+		}
+	}
+
+	rows, err = s.engines.EngineImport(ctx, rows, options)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		s.logFailure(ctx, "importing engine", row.ImportRow)
+	}
+	report := models.NewImportReport(options, rows)
+	s.logger.InfoContext(ctx, "engines imported", "rows", report.Rows, "imported", report.Imported, "dry_run", report.DryRun)
+	return &report, nil
+}
+
+// logFailure logs a row that failed for a reason the report hides from the
+// client.
+func (s *ImportService) logFailure(ctx context.Context, msg string, row models.ImportRow) {
+	if row.Err != nil && errors.Is(apperrors.As(row.Err), apperrors.ErrInternal) {
+		s.logger.ErrorContext(ctx, msg, "line", row.Line, "error", row.Err)
+	}
+}
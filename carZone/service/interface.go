@@ -8,15 +8,74 @@ import (
 
 type CarServiceInterface interface {
 	GetCarById(ctx context.Context, id string) (*models.Car, error)
+	GetCarByVIN(ctx context.Context, vin string) (*models.Car, error)
 	GetCarsByBrand(ctx context.Context, brand string, isEngine bool) ([]models.Car, error)
+	ListCars(ctx context.Context, filter models.CarFilter) (*models.CarPage, error)
+	SearchCars(ctx context.Context, query string, limit int) ([]models.CarSearchResult, error)
+	// ExportCars calls fn with every car matching filter, converted like
+	// ListCars converts them.
+	ExportCars(ctx context.Context, filter models.CarFilter, fn func(models.Car) error) error
 	CreateCar(ctx context.Context, car *models.CarRequest) (*models.Car, error)
-	UpdateCar(ctx context.Context, id string, carReq *models.CarRequest) (*models.Car, error)
-	DeleteCar(ctx context.Context, id string) (*models.Car, error)
+	// UpdateCar and DeleteCar only apply when version is 0 or the current
+	// version of the car.
+	UpdateCar(ctx context.Context, id string, carReq *models.CarRequest, version int64) (*models.Car, error)
+	DeleteCar(ctx context.Context, id string, version int64) (*models.Car, error)
+	PatchCar(ctx context.Context, id string, patch models.CarPatch, version int64) (*models.Car, error)
+	ListDeletedCars(ctx context.Context) ([]models.Car, error)
+	RestoreCar(ctx context.Context, id string) (*models.Car, error)
+	GetPriceHistory(ctx context.Context, id string) (*models.PriceHistory, error)
+	GetPriceStats(ctx context.Context, filter models.PriceStatsFilter) ([]models.PriceStats, error)
+	// BatchCars creates, updates and deletes cars in one transaction and
+	// reports the outcome of every operation.
+	BatchCars(ctx context.Context, req models.CarBatchRequest) (*models.CarBatchReport, error)
 }
 
 type EngineServiceInterface interface {
 	GetEngineById(ctx context.Context, id string) (*models.Engine, error)
 	CreateEngine(ctx context.Context, engineReq *models.EngineRequest) (*models.Engine, error)
-	UpdateEngine(ctx context.Context, id string, engineReq *models.EngineRequest) (*models.Engine, error)
-	DeleteEngine(ctx context.Context, id string) (*models.Engine, error)
-}
\ No newline at end of file
+	// UpdateEngine and DeleteEngine only apply when version is 0 or the
+	// current version of the engine.
+	UpdateEngine(ctx context.Context, id string, engineReq *models.EngineRequest, version int64) (*models.Engine, error)
+	DeleteEngine(ctx context.Context, id string, version int64) (*models.Engine, error)
+	PatchEngine(ctx context.Context, id string, patch models.EnginePatch, version int64) (*models.Engine, error)
+	ListDeletedEngines(ctx context.Context) ([]models.Engine, error)
+	RestoreEngine(ctx context.Context, id string) (*models.Engine, error)
+}
+
+// CatalogServiceInterface manages the brands and models cars refer to. ref
+// and brandRef are an id or a name, which is matched by its slug.
+type CatalogServiceInterface interface {
+	ListBrands(ctx context.Context) ([]models.Brand, error)
+	GetBrand(ctx context.Context, ref string) (*models.Brand, error)
+	CreateBrand(ctx context.Context, brandReq *models.BrandRequest) (*models.Brand, error)
+	UpdateBrand(ctx context.Context, ref string, brandReq *models.BrandRequest) (*models.Brand, error)
+	DeleteBrand(ctx context.Context, ref string) (*models.Brand, error)
+	ListCarModels(ctx context.Context, brandRef string) ([]models.CarModel, error)
+	GetCarModel(ctx context.Context, brandRef, ref string) (*models.CarModel, error)
+	CreateCarModel(ctx context.Context, brandRef string, modelReq *models.CarModelRequest) (*models.CarModel, error)
+	UpdateCarModel(ctx context.Context, brandRef, ref string, modelReq *models.CarModelRequest) (*models.CarModel, error)
+	DeleteCarModel(ctx context.Context, brandRef, ref string) (*models.CarModel, error)
+}
+
+// ImportServiceInterface creates cars and engines in bulk from decoded import
+// rows and reports the outcome of every row.
+type ImportServiceInterface interface {
+	ImportCars(ctx context.Context, rows []models.CarImportRow, options models.ImportOptions) (*models.ImportReport, error)
+	ImportEngines(ctx context.Context, rows []models.EngineImportRow, options models.ImportOptions) (*models.ImportReport, error)
+}
+
+type AuditServiceInterface interface {
+	ListAuditEntries(ctx context.Context, filter models.AuditFilter) (*models.AuditPage, error)
+}
+
+type UserServiceInterface interface {
+	Login(ctx context.Context, loginReq *models.LoginRequest) (*models.TokenResponse, error)
+}
+
+type APIKeyServiceInterface interface {
+	CreateAPIKey(ctx context.Context, keyReq *models.APIKeyRequest) (*models.APIKeyWithSecret, error)
+	ListAPIKeys(ctx context.Context) ([]models.APIKey, error)
+	RotateAPIKey(ctx context.Context, id string) (*models.APIKeyWithSecret, error)
+	RevokeAPIKey(ctx context.Context, id string) (*models.APIKey, error)
+	Authenticate(ctx context.Context, secret string) (*models.APIKey, error)
+}
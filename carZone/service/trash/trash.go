@@ -0,0 +1,70 @@
+// Package trash permanently removes the cars and engines that have been in
+// the trash for longer than the retention window.
+package trash
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/ayushi-khandal09/carZone/store"
+	"github.com/ayushi-khandal09/carZone/tracing"
+	"go.opentelemetry.io/otel"
+)
+
+var tracer = otel.Tracer("github.com/ayushi-khandal09/carZone/service/trash")
+
+type Purger struct {
+	logger    *slog.Logger
+	cars      store.CarStoreInterface
+	engines   store.EngineStoreInterface
+	retention time.Duration
+}
+
+func NewPurger(cars store.CarStoreInterface, engines store.EngineStoreInterface, retention time.Duration, logger *slog.Logger) *Purger {
+	return &Purger{
+		logger:    logger,
+		cars:      cars,
+		engines:   engines,
+		retention: retention,
+	}
+}
+
+// Purge removes everything deleted more than the retention window ago. Cars
+// go first so that the count of engines does not include their cars.
+func (p *Purger) Purge(ctx context.Context) (err error) {
+	ctx, span := tracer.Start(ctx, "Purger.Purge")
+	defer func() { tracing.End(span, err) }()
+
+	before := time.Now().Add(-p.retention)
+	cars, err := p.cars.PurgeCars(ctx, before)
+	if err != nil {
+		return err
+	}
+	engines, err := p.engines.EnginePurge(ctx, before)
+	if err != nil {
+		return err
+	}
+	if cars > 0 || engines > 0 {
+		p.logger.InfoContext(ctx, "purged trash", "cars", cars, "engines", engines, "deleted_before", before)
+	}
+	return nil
+}
+
+// Run purges once straight away and then every interval until ctx is done.
+// Failures are logged and retried on the next tick.
+func (p *Purger) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.Purge(ctx); err != nil && ctx.Err() == nil {
+			p.logger.ErrorContext(ctx, "purging trash", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/ayushi-khandal09/carZone/apperrors"
+	"github.com/ayushi-khandal09/carZone/auth"
+	"github.com/ayushi-khandal09/carZone/models"
+	"github.com/ayushi-khandal09/carZone/store"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// dummyHash is compared against when the user does not exist so that unknown
+// usernames take as long to reject as wrong passwords.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("carzone-dummy-password"), bcrypt.DefaultCost)
+
+type UserService struct {
+	logger *slog.Logger
+	store  store.UserStoreInterface
+	tokens *auth.TokenManager
+}
+
+func NewUserService(store store.UserStoreInterface, tokens *auth.TokenManager, logger *slog.Logger) *UserService {
+	return &UserService{
+		logger: logger,
+		store:  store,
+		tokens: tokens,
+	}
+}
+
+func (s *UserService) Login(ctx context.Context, loginReq *models.LoginRequest) (*models.TokenResponse, error) {
+	if err := models.ValidateLoginRequest(*loginReq); err != nil {
+		return nil, err
+	}
+
+	user, err := s.store.GetUserByUsername(ctx, loginReq.Username)
+	if err != nil {
+		if !errors.Is(err, apperrors.ErrNotFound) {
+			return nil, err
+		}
+		_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(loginReq.Password))
+		s.logger.WarnContext(ctx, "login failed", "username", loginReq.Username, "reason", "unknown user")
+		return nil, apperrors.Unauthorized("invalid username or password")
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(loginReq.Password)); err != nil {
+		s.logger.WarnContext(ctx, "login failed", "username", loginReq.Username, "reason", "wrong password")
+		return nil, apperrors.Unauthorized("invalid username or password")
+	}
+
+	token, err := s.tokens.Issue(user)
+	if err != nil {
+		return nil, apperrors.Internal(err)
+	}
+	s.logger.InfoContext(ctx, "token issued", "username", user.Username, "role", user.Role)
+	return &models.TokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(s.tokens.TTL().Seconds()),
+	}, nil
+}
+
+// EnsureUser creates the user with a bcrypt hash of password unless a user
+// with that name already exists. It is used to bootstrap the first admin.
+func (s *UserService) EnsureUser(ctx context.Context, username, password string, role models.Role) error {
+	if err := models.ValidateLoginRequest(models.LoginRequest{Username: username, Password: password}); err != nil {
+		return err
+	}
+	if !role.Valid() {
+		return apperrors.Validation("role", "role must be one of: viewer, dealer, admin")
+	}
+
+	_, err := s.store.GetUserByUsername(ctx, username)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, apperrors.ErrNotFound) {
+		return err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return apperrors.Internal(err)
+	}
+	_, err = s.store.CreateUser(ctx, &models.User{Username: username, PasswordHash: string(hash), Role: role})
+	if err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "user created", "username", username, "role", role)
+	return nil
+}
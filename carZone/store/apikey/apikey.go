@@ -0,0 +1,123 @@
+package apikey
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/ayushi-khandal09/carZone/apperrors"
+	"github.com/ayushi-khandal09/carZone/models"
+	"github.com/ayushi-khandal09/carZone/store"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const columns = "id, name, prefix, key_hash, scopes, created_at, updated_at, last_used_at, revoked_at"
+
+type APIKeyStore struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) *APIKeyStore {
+	return &APIKeyStore{db: db}
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanKey(row scanner) (models.APIKey, error) {
+	var key models.APIKey
+	err := row.Scan(
+		&key.ID,
+		&key.Name,
+		&key.Prefix,
+		&key.KeyHash,
+		pq.Array(&key.Scopes),
+		&key.CreatedAt,
+		&key.UpdatedAt,
+		&key.LastUsedAt,
+		&key.RevokedAt,
+	)
+	return key, err
+}
+
+func (a APIKeyStore) CreateAPIKey(ctx context.Context, key *models.APIKey) (models.APIKey, error) {
+	createdAt := time.Now()
+	created, err := scanKey(a.db.QueryRowContext(ctx,
+		`INSERT INTO api_keys (id, name, prefix, key_hash, scopes, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING `+columns,
+		uuid.New(), key.Name, key.Prefix, key.KeyHash, pq.Array(key.Scopes), createdAt, createdAt,
+	))
+	if err != nil {
+		return created, store.MapError(err, "api key")
+	}
+	return created, nil
+}
+
+func (a APIKeyStore) ListAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	rows, err := a.db.QueryContext(ctx, "SELECT "+columns+" FROM api_keys ORDER BY created_at, id")
+	if err != nil {
+		return nil, store.MapError(err, "api key")
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		key, err := scanKey(rows)
+		if err != nil {
+			return nil, store.MapError(err, "api key")
+		}
+		keys = append(keys, key)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, store.MapError(err, "api key")
+	}
+	return keys, nil
+}
+
+func (a APIKeyStore) GetAPIKeyByHash(ctx context.Context, hash string) (models.APIKey, error) {
+	key, err := scanKey(a.db.QueryRowContext(ctx, "SELECT "+columns+" FROM api_keys WHERE key_hash = $1", hash))
+	if err != nil {
+		return key, store.MapError(err, "api key")
+	}
+	return key, nil
+}
+
+// RotateAPIKey replaces the secret of an active key. Revoked keys cannot be
+// rotated.
+func (a APIKeyStore) RotateAPIKey(ctx context.Context, id string, prefix string, hash string) (models.APIKey, error) {
+	key, err := scanKey(a.db.QueryRowContext(ctx,
+		`UPDATE api_keys SET prefix = $2, key_hash = $3, updated_at = $4
+		WHERE id = $1 AND revoked_at IS NULL
+		RETURNING `+columns,
+		id, prefix, hash, time.Now(),
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return key, apperrors.NotFound("active api key not found")
+		}
+		return key, store.MapError(err, "api key")
+	}
+	return key, nil
+}
+
+func (a APIKeyStore) RevokeAPIKey(ctx context.Context, id string) (models.APIKey, error) {
+	now := time.Now()
+	key, err := scanKey(a.db.QueryRowContext(ctx,
+		`UPDATE api_keys SET revoked_at = COALESCE(revoked_at, $2), updated_at = $2
+		WHERE id = $1
+		RETURNING `+columns,
+		id, now,
+	))
+	if err != nil {
+		return key, store.MapError(err, "api key")
+	}
+	return key, nil
+}
+
+func (a APIKeyStore) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	_, err := a.db.ExecContext(ctx, "UPDATE api_keys SET last_used_at = $2 WHERE id = $1", id, usedAt)
+	return store.MapError(err, "api key")
+}
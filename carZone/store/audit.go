@@ -0,0 +1,70 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/ayushi-khandal09/carZone/auth"
+	"github.com/ayushi-khandal09/carZone/models"
+	"github.com/google/uuid"
+)
+
+// SystemActor is recorded as the actor of mutations made without an
+// authenticated caller.
+const SystemActor = "system"
+
+// NewAuditEntry describes a mutation of entity by the principal in ctx. before
+// and after are stored as JSON, nil leaves them empty.
+func NewAuditEntry(ctx context.Context, action, entity string, id uuid.UUID, before, after interface{}) (models.AuditEntry, error) {
+	entry := models.AuditEntry{
+		ID:        uuid.New(),
+		Actor:     SystemActor,
+		ActorName: SystemActor,
+		Action:    action,
+		Entity:    entity,
+		EntityID:  id,
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+	}
+	if principal, ok := auth.PrincipalFrom(ctx); ok {
+		entry.Actor = principal.Subject
+		entry.ActorName = principal.Username
+	}
+
+	var err error
+	if before != nil {
+		if entry.Before, err = json.Marshal(before); err != nil {
+			return entry, err
+		}
+	}
+	if after != nil {
+		if entry.After, err = json.Marshal(after); err != nil {
+			return entry, err
+		}
+	}
+	return entry, nil
+}
+
+// Audit writes an entry for a mutation of entity inside tx, so the entry is
+// only kept if the mutation commits.
+func Audit(ctx context.Context, tx *sql.Tx, action, entity string, id uuid.UUID, before, after interface{}) error {
+	entry, err := NewAuditEntry(ctx, action, entity, id, before, after)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO audit_log (id, actor, actor_name, action, entity, entity_id, before, after, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		entry.ID, entry.Actor, entry.ActorName, entry.Action, entry.Entity, entry.EntityID,
+		nullJSON(entry.Before), nullJSON(entry.After), entry.CreatedAt,
+	)
+	return MapError(err, "audit entry")
+}
+
+func nullJSON(raw json.RawMessage) interface{} {
+	if raw == nil {
+		return nil
+	}
+	return []byte(raw)
+}
@@ -0,0 +1,96 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/ayushi-khandal09/carZone/models"
+	"github.com/ayushi-khandal09/carZone/store"
+	"github.com/google/uuid"
+)
+
+// AuditStore reads the audit log. Entries are written by the car and engine
+// stores, in the transaction of the mutation they record.
+type AuditStore struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) *AuditStore {
+	return &AuditStore{db: db}
+}
+
+func (a AuditStore) ListAuditEntries(ctx context.Context, filter models.AuditFilter) (models.AuditPage, error) {
+	page := models.AuditPage{Entries: []models.AuditEntry{}}
+
+	cursor, err := models.DecodeAuditCursor(filter)
+	if err != nil {
+		return page, err
+	}
+
+	var conditions []string
+	var args []interface{}
+	addCondition := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.Entity != "" {
+		addCondition("entity = $%d", filter.Entity)
+	}
+	if filter.EntityID != uuid.Nil {
+		addCondition("entity_id = $%d", filter.EntityID)
+	}
+	if filter.Actor != "" {
+		addCondition("actor = $%d", filter.Actor)
+	}
+	if !filter.From.IsZero() {
+		addCondition("created_at >= $%d", filter.From.UTC())
+	}
+	if !filter.To.IsZero() {
+		addCondition("created_at < $%d", filter.To.UTC())
+	}
+	if cursor != nil {
+		args = append(args, cursor.CreatedAt, cursor.ID)
+		conditions = append(conditions, fmt.Sprintf("(created_at, id) < ($%d, $%d)", len(args)-1, len(args)))
+	}
+
+	query := "SELECT id, actor, actor_name, action, entity, entity_id, before, after, created_at FROM audit_log"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit+1)
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d", len(args))
+
+	rows, err := a.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return page, store.MapError(err, "audit entry")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry models.AuditEntry
+		var before, after []byte
+		err := rows.Scan(
+			&entry.ID,
+			&entry.Actor,
+			&entry.ActorName,
+			&entry.Action,
+			&entry.Entity,
+			&entry.EntityID,
+			&before,
+			&after,
+			&entry.CreatedAt,
+		)
+		if err != nil {
+			return page, store.MapError(err, "audit entry")
+		}
+		entry.Before, entry.After = before, after
+		page.Entries = append(page.Entries, entry)
+	}
+	if err = rows.Err(); err != nil {
+		return page, store.MapError(err, "audit entry")
+	}
+
+	return models.FinishAuditPage(page, filter), nil
+}
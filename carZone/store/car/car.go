@@ -4,265 +4,1099 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/ayushi-khandal09/carZone/apperrors"
 	"github.com/ayushi-khandal09/carZone/models"
+	"github.com/ayushi-khandal09/carZone/store"
 	"github.com/google/uuid"
 )
 
 type Store struct {
-	db *sql.DB
+	db     *sql.DB
+	logger *slog.Logger
 }
 
-func New(db *sql.DB) Store {
-	return Store{db: db}
+func New(db *sql.DB, logger *slog.Logger) Store {
+	return Store{db: db, logger: logger}
 }
 
 func (s Store) GetCarById(ctx context.Context, id string) (models.Car, error) {
+	return s.getCar(ctx, "c.id", id)
+}
+
+// GetCarByVIN returns the car with the given VIN unless it is in the trash.
+func (s Store) GetCarByVIN(ctx context.Context, vin string) (models.Car, error) {
+	return s.getCar(ctx, "c.vin", vin)
+}
+
+// getCar reads the live car whose column matches value, joined with its
+// engine.
+func (s Store) getCar(ctx context.Context, column string, value string) (models.Car, error) {
 	var car models.Car
 
-	query := `SELECT c.id, c.name, c.year, c.brand, c.fuel_type, c.engine_id, c.price, c.created_at, c.updated_at,
-	e.id, e.displacement, e.no_of_cylinders, e.car_range FROM car c LEFT JOIN
-	engine e ON c.engine_id = e.id WHERE c.id=$1 `
+	query := `SELECT c.id, c.vin, c.name, c.year, c.brand, c.brand_id, c.model_id, c.fuel_type, c.engine_id, c.price, c.currency, c.created_at, c.updated_at, c.version,
+	e.id, e.displacement, e.no_of_cylinders, e.car_range, e.version FROM car c LEFT JOIN
+	engine e ON c.engine_id = e.id WHERE ` + column + `=$1 AND c.deleted_at IS NULL`
 
-	rows := s.db.QueryRowContext(ctx, query, id)
+	rows := s.db.QueryRowContext(ctx, query, value)
 	err := rows.Scan(
 		&car.ID,
+		&car.VIN,
 		&car.Name,
 		&car.Year,
 		&car.Brand,
+		&car.BrandID,
+		&car.ModelID,
 		&car.FuelType,
 		&car.Engine.EngineID,
 		&car.Price,
+		&car.Currency,
 		&car.CreatedAt,
 		&car.UpdatedAt,
+		&car.Version,
 		&car.Engine.EngineID,
 		&car.Engine.Displacement,
 		&car.Engine.NoOfCyclinders,
 		&car.Engine.CarRange,
+		&car.Engine.Version,
 	)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return car, nil
-		}
-		return car, err
+		return car, store.MapError(err, "car")
 	}
 	return car, nil
 }
 
+// brandIDs selects the brand whose slug or id is the slug of the reference
+// in $1, so a brand is found by any spelling of its name.
+const brandIDs = "(SELECT id FROM brand WHERE slug = $1 OR id::text = $1)"
+
 func (s Store) GetCarByBrand(ctx context.Context, brand string, isEngine bool) ([]models.Car, error) {
 	var cars []models.Car
 	var query string
 	if isEngine {
-		query = `SELECT c.id, c.name, c.year, c.fuel_type, c.engine_id, c.price, c.created_at, c.updated_at,
-		e.id, e.displacement, e.no_of_cylinders, e.car_range FROM car c LEFT JOIN engine e ON c.engine_id=e.id WHERE c.brand=$1`
+		query = `SELECT c.id, c.vin, c.name, c.year, c.brand, c.brand_id, c.model_id, c.fuel_type, c.engine_id, c.price, c.currency, c.created_at, c.updated_at, c.version,
+		e.displacement, e.no_of_cylinders, e.car_range, e.version FROM car c LEFT JOIN engine e ON c.engine_id=e.id WHERE c.brand_id IN ` + brandIDs + ` AND c.deleted_at IS NULL
+		ORDER BY c.created_at, c.id`
 	} else {
-		query = `SELECT id, name, year, brand, fuel_type, engine_id, price, created_at, updated_at FROM car WHERE brand=$1`
+		query = `SELECT id, vin, name, year, brand, brand_id, model_id, fuel_type, engine_id, price, currency, created_at, updated_at, version FROM car WHERE brand_id IN ` + brandIDs + ` AND deleted_at IS NULL
+		ORDER BY created_at, id`
 	}
-	rows, err := s.db.QueryContext(ctx, query, brand)
+	rows, err := s.db.QueryContext(ctx, query, models.Slug(brand))
 	if err != nil {
-		return nil, err
+		return nil, store.MapError(err, "car")
 	}
 	defer rows.Close()
 	for rows.Next() {
 		var car models.Car
 		if isEngine {
-			var engine models.Engine
 			err := rows.Scan(
 				&car.ID,
+				&car.VIN,
 				&car.Name,
 				&car.Year,
 				&car.Brand,
+				&car.BrandID,
+				&car.ModelID,
 				&car.FuelType,
 				&car.Engine.EngineID,
 				&car.Price,
+				&car.Currency,
 				&car.CreatedAt,
 				&car.UpdatedAt,
-				&car.Engine.EngineID,
+				&car.Version,
 				&car.Engine.Displacement,
 				&car.Engine.NoOfCyclinders,
 				&car.Engine.CarRange,
+				&car.Engine.Version,
 			)
 			if err != nil {
-				return nil, err
+				return nil, store.MapError(err, "car")
 			}
-			car.Engine = engine
 		} else {
 			err := rows.Scan(
 				&car.ID,
+				&car.VIN,
 				&car.Name,
 				&car.Year,
 				&car.Brand,
+				&car.BrandID,
+				&car.ModelID,
 				&car.FuelType,
 				&car.Engine.EngineID,
 				&car.Price,
+				&car.Currency,
 				&car.CreatedAt,
 				&car.UpdatedAt,
+				&car.Version,
 			)
 			if err != nil {
-				return nil, err
+				return nil, store.MapError(err, "car")
 			}
 		}
 		cars = append(cars, car)
 	}
 	if err = rows.Err(); err != nil {
-		return nil, err
+		return nil, store.MapError(err, "car")
 	}
 	return cars, nil
 }
 
-func (s Store) CreateCar(ctx context.Context, carReq *models.CarRequest) (models.Car, error) {
+func (s Store) CreateCar(ctx context.Context, carReq *models.CarRequest) (_ models.Car, err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Car{}, store.MapError(err, "car")
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				s.logger.ErrorContext(ctx, "rolling back car transaction", "error", rbErr)
+			}
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	return s.createCar(ctx, tx, uuid.New(), carReq)
+}
+
+func (s Store) createCar(ctx context.Context, tx *sql.Tx, id uuid.UUID, carReq *models.CarRequest) (models.Car, error) {
 	var createCar models.Car
-	var engineId uuid.UUID
 
-	err := s.db.QueryRowContext(ctx, "SELECT id FROM engine WHERE id=$1", carReq.Engine.EngineID).Scan(&engineId)
+	if err := s.checkEngine(ctx, tx, carReq.Engine.EngineID); err != nil {
+		return createCar, err
+	}
+	brand, model, err := store.ResolveCarModel(ctx, tx, carReq.Brand, carReq.Name)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return createCar, errors.New("engine_id does not exists in the engine table")
-		}
 		return createCar, err
 	}
-	carID := uuid.New()
 	createdAt := time.Now()
 	updatedAt := createdAt
 
 	newCar := models.Car{
-		ID:        carID,
-		Name:      carReq.Name,
+		ID:        id,
+		VIN:       carReq.VIN,
+		Name:      model.Name,
 		Year:      carReq.Year,
-		Brand:     carReq.Brand,
+		Brand:     brand.Name,
+		BrandID:   brand.ID,
+		ModelID:   model.ID,
 		FuelType:  carReq.FuelType,
 		Engine:    carReq.Engine,
 		Price:     carReq.Price,
+		Currency:  carReq.Currency,
 		CreatedAt: createdAt,
 		UpdatedAt: updatedAt,
 	}
 
-	tx, err := s.db.BeginTx(ctx, nil)
-	if err != nil {
-		return createCar, err
-	}
-	defer func() {
-		if err != nil {
-			tx.Rollback()
-			return
-		}
-		err = tx.Commit()
-	}()
-
-	query := `INSERT INTO car (id, name, year, brand, fuel_type, engine_id, price, created_at, updated_at)
-	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-	RETURNING id, name, year, brand, fuel_type, engine_id, price, created_at, updated_at`
+	query := `INSERT INTO car (id, vin, name, year, brand, brand_id, model_id, fuel_type, engine_id, price, currency, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	RETURNING id, vin, name, year, brand, brand_id, model_id, fuel_type, engine_id, price, currency, created_at, updated_at, version`
 
 	err = tx.QueryRowContext(ctx, query,
 		newCar.ID,
+		newCar.VIN,
 		newCar.Name,
 		newCar.Year,
 		newCar.Brand,
+		newCar.BrandID,
+		newCar.ModelID,
 		newCar.FuelType,
 		newCar.Engine.EngineID,
 		newCar.Price,
+		newCar.Currency,
 		newCar.CreatedAt,
 		newCar.UpdatedAt,
 	).Scan(
 		&createCar.ID,
+		&createCar.VIN,
 		&createCar.Name,
 		&createCar.Year,
 		&createCar.Brand,
+		&createCar.BrandID,
+		&createCar.ModelID,
 		&createCar.FuelType,
 		&createCar.Engine.EngineID,
 		&createCar.Price,
+		&createCar.Currency,
 		&createCar.CreatedAt,
 		&createCar.UpdatedAt,
+		&createCar.Version,
 	)
 	if err != nil {
+		return createCar, store.MapError(err, "car")
+	}
+	if err = store.Audit(ctx, tx, models.AuditCreate, "car", createCar.ID, nil, createCar); err != nil {
 		return createCar, err
 	}
 	return createCar, nil
 }
 
-func (s Store) UpdateCar(ctx context.Context, id string, carReq *models.CarRequest) (models.Car, error) {
-	var updatedCar models.Car
-
+func (s Store) UpdateCar(ctx context.Context, id string, carReq *models.CarRequest, version int64) (_ models.Car, err error) {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return updatedCar, err
+		return models.Car{}, store.MapError(err, "car")
 	}
 	defer func() {
 		if err != nil {
-			tx.Rollback()
+			if rbErr := tx.Rollback(); rbErr != nil {
+				s.logger.ErrorContext(ctx, "rolling back car transaction", "error", rbErr)
+			}
 			return
 		}
 		err = tx.Commit()
 	}()
+
+	return s.updateCar(ctx, tx, id, carReq, version)
+}
+
+func (s Store) updateCar(ctx context.Context, tx *sql.Tx, id string, carReq *models.CarRequest, version int64) (models.Car, error) {
+	var updatedCar models.Car
+
+	before, err := s.lockCar(ctx, tx, id, false)
+	if err != nil {
+		return updatedCar, err
+	}
+	if version != 0 && before.Version != version {
+		return updatedCar, apperrors.PreconditionFailed("car has been modified, it is now at version " + strconv.FormatInt(before.Version, 10))
+	}
+	if err = s.checkEngine(ctx, tx, carReq.Engine.EngineID); err != nil {
+		return updatedCar, err
+	}
+	brand, model, err := store.ResolveCarModel(ctx, tx, carReq.Brand, carReq.Name)
+	if err != nil {
+		return updatedCar, err
+	}
 	query := `
-		UPDATED car
-		SET name = $2, year = $3, fuel_type = $5, engine_id = $6, price=$7, updated_at=$8 
-		WHERE id = $1
-		RETURN id, name, year, brand, fuel_type, engine_id, price, created_at, updated_at`
+		UPDATE car
+		SET name = $2, year = $3, brand = $4, fuel_type = $5, engine_id = $6, price = $7, updated_at = $8,
+		currency = $10, vin = $11, brand_id = $12, model_id = $13, version = version + 1
+		WHERE id = $1 AND deleted_at IS NULL AND ($9::bigint = 0 OR version = $9)
+		RETURNING id, vin, name, year, brand, brand_id, model_id, fuel_type, engine_id, price, currency, created_at, updated_at, version`
 
 	err = tx.QueryRowContext(ctx, query,
 		id,
-		carReq.Name,
+		model.Name,
 		carReq.Year,
-		carReq.Brand,
+		brand.Name,
 		carReq.FuelType,
 		carReq.Engine.EngineID,
 		carReq.Price,
 		time.Now(),
+		version,
+		carReq.Currency,
+		carReq.VIN,
+		brand.ID,
+		model.ID,
 	).Scan(
 		&updatedCar.ID,
+		&updatedCar.VIN,
 		&updatedCar.Name,
 		&updatedCar.Year,
 		&updatedCar.Brand,
+		&updatedCar.BrandID,
+		&updatedCar.ModelID,
 		&updatedCar.FuelType,
 		&updatedCar.Engine.EngineID,
 		&updatedCar.Price,
+		&updatedCar.Currency,
 		&updatedCar.CreatedAt,
 		&updatedCar.UpdatedAt,
+		&updatedCar.Version,
 	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return updatedCar, s.versionMismatch(ctx, tx, id)
+	}
 	if err != nil {
+		return updatedCar, store.MapError(err, "car")
+	}
+	if err = s.recordPrice(ctx, tx, before, updatedCar); err != nil {
+		return updatedCar, err
+	}
+	if err = store.Audit(ctx, tx, models.AuditUpdate, "car", updatedCar.ID, before, updatedCar); err != nil {
 		return updatedCar, err
 	}
 	return updatedCar, nil
 }
 
-func (s Store) DeleteCar(ctx context.Context, id string) (models.Car, error) {
-	var deletedCar models.Car
-
+func (s Store) DeleteCar(ctx context.Context, id string, version int64) (_ models.Car, err error) {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return deletedCar, err
+		return models.Car{}, store.MapError(err, "car")
 	}
 	defer func() {
 		if err != nil {
-			tx.Rollback()
+			if rbErr := tx.Rollback(); rbErr != nil {
+				s.logger.ErrorContext(ctx, "rolling back car transaction", "error", rbErr)
+			}
 			return
 		}
 		err = tx.Commit()
 	}()
 
-	tx.QueryRowContext(ctx, "SELECT id, name, year, brand, fuel_type, engine_id, price, created_at, updated_at FROM car WHERE id = $1", id).Scan(
-		&deletedCar.ID, &deletedCar.Name, &deletedCar.Year, &deletedCar.Brand, &deletedCar.Engine.EngineID,
-		&deletedCar.Price, &deletedCar.CreatedAt, &deletedCar.UpdatedAt,
-	)
+	return s.deleteCar(ctx, tx, id, version)
+}
+
+func (s Store) deleteCar(ctx context.Context, tx *sql.Tx, id string, version int64) (models.Car, error) {
+	deletedCar, err := s.lockCar(ctx, tx, id, false)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return models.Car{}, errors.New("Car not found")
-		}
 		return models.Car{}, err
 	}
-	result, err := tx.ExecContext(ctx, "DELETE FROM car WHERE id = $1", id)
+	if version != 0 && deletedCar.Version != version {
+		return models.Car{}, apperrors.PreconditionFailed("car has been modified, it is now at version " + strconv.FormatInt(deletedCar.Version, 10))
+	}
+	// The row is only marked as deleted, PurgeCars removes it for good once
+	// it has been in the trash for long enough.
+	deletedAt := time.Now()
+	result, err := tx.ExecContext(ctx, "UPDATE car SET deleted_at = $2, version = version + 1 WHERE id = $1", id, deletedAt)
 	if err != nil {
-		return models.Car{}, err
+		return models.Car{}, store.MapError(err, "car")
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		return models.Car{}, err
+		return models.Car{}, store.MapError(err, "car")
 	}
 	if rowsAffected == 0 {
-		return models.Car{}, errors.New("No rows were deleted")
+		return models.Car{}, apperrors.NotFound("car not found")
+	}
+	before := deletedCar
+	deletedCar.DeletedAt = &deletedAt
+	deletedCar.Version++
+	if err = store.Audit(ctx, tx, models.AuditDelete, "car", deletedCar.ID, before, deletedCar); err != nil {
+		return models.Car{}, err
 	}
 	return deletedCar, nil
 }
+
+// PatchCar builds an UPDATE that only sets the columns present in patch.
+func (s Store) PatchCar(ctx context.Context, id string, patch models.CarPatch, version int64) (_ models.Car, err error) {
+	var patchedCar models.Car
+
+	sets := []string{"updated_at = $3", "version = version + 1"}
+	args := []interface{}{id, version, time.Now()}
+	addSet := func(column string, value interface{}) {
+		args = append(args, value)
+		sets = append(sets, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+	if patch.VIN != nil {
+		addSet("vin", *patch.VIN)
+	}
+	if patch.Year != nil {
+		addSet("year", *patch.Year)
+	}
+	if patch.FuelType != nil {
+		addSet("fuel_type", *patch.FuelType)
+	}
+	if patch.EngineID != nil {
+		addSet("engine_id", *patch.EngineID)
+	}
+	if patch.Price != nil {
+		addSet("price", *patch.Price)
+	}
+	if patch.Currency != nil {
+		addSet("currency", *patch.Currency)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return patchedCar, store.MapError(err, "car")
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				s.logger.ErrorContext(ctx, "rolling back car transaction", "error", rbErr)
+			}
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	before, err := s.lockCar(ctx, tx, id, false)
+	if err != nil {
+		return patchedCar, err
+	}
+	if version != 0 && before.Version != version {
+		err = apperrors.PreconditionFailed("car has been modified, it is now at version " + strconv.FormatInt(before.Version, 10))
+		return patchedCar, err
+	}
+	if patch.EngineID != nil {
+		if err = s.checkEngine(ctx, tx, *patch.EngineID); err != nil {
+			return patchedCar, err
+		}
+	}
+	// A new brand or name is resolved against the catalog with the current
+	// value of the other one. The model keeps its name under a new brand.
+	if patch.Brand != nil || patch.Name != nil {
+		brandRef, modelRef := before.BrandID.String(), before.Name
+		if patch.Brand != nil {
+			brandRef = *patch.Brand
+		}
+		if patch.Name != nil {
+			modelRef = *patch.Name
+		}
+		var brand models.Brand
+		var model models.CarModel
+		brand, model, err = store.ResolveCarModel(ctx, tx, brandRef, modelRef)
+		if err != nil {
+			return patchedCar, err
+		}
+		addSet("brand", brand.Name)
+		addSet("brand_id", brand.ID)
+		addSet("name", model.Name)
+		addSet("model_id", model.ID)
+	}
+	query := `UPDATE car SET ` + strings.Join(sets, ", ") + `
+		WHERE id = $1 AND deleted_at IS NULL AND ($2::bigint = 0 OR version = $2)
+		RETURNING id, vin, name, year, brand, brand_id, model_id, fuel_type, engine_id, price, currency, created_at, updated_at, version`
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(
+		&patchedCar.ID,
+		&patchedCar.VIN,
+		&patchedCar.Name,
+		&patchedCar.Year,
+		&patchedCar.Brand,
+		&patchedCar.BrandID,
+		&patchedCar.ModelID,
+		&patchedCar.FuelType,
+		&patchedCar.Engine.EngineID,
+		&patchedCar.Price,
+		&patchedCar.Currency,
+		&patchedCar.CreatedAt,
+		&patchedCar.UpdatedAt,
+		&patchedCar.Version,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = s.versionMismatch(ctx, tx, id)
+		return patchedCar, err
+	}
+	if err != nil {
+		return patchedCar, store.MapError(err, "car")
+	}
+	if err = s.recordPrice(ctx, tx, before, patchedCar); err != nil {
+		return patchedCar, err
+	}
+	if err = store.Audit(ctx, tx, models.AuditPatch, "car", patchedCar.ID, before, patchedCar); err != nil {
+		return patchedCar, err
+	}
+	return patchedCar, nil
+}
+
+// lockCar reads the car with the given id, from the trash when inTrash is
+// set, and locks its row until tx ends.
+func (s Store) lockCar(ctx context.Context, tx *sql.Tx, id string, inTrash bool) (models.Car, error) {
+	var car models.Car
+
+	condition := "deleted_at IS NULL"
+	if inTrash {
+		condition = "deleted_at IS NOT NULL"
+	}
+	err := tx.QueryRowContext(ctx, `SELECT id, vin, name, year, brand, brand_id, model_id, fuel_type, engine_id, price, currency, created_at, updated_at, version, deleted_at
+	FROM car WHERE id = $1 AND `+condition+` FOR UPDATE`, id).Scan(
+		&car.ID, &car.VIN, &car.Name, &car.Year, &car.Brand, &car.BrandID, &car.ModelID, &car.FuelType, &car.Engine.EngineID,
+		&car.Price, &car.Currency, &car.CreatedAt, &car.UpdatedAt, &car.Version, &car.DeletedAt,
+	)
+	if err != nil {
+		return car, store.MapError(err, "car")
+	}
+	return car, nil
+}
+
+// recordPrice adds an entry to the price history of the car when an update
+// changed its price or currency.
+func (s Store) recordPrice(ctx context.Context, tx *sql.Tx, before, after models.Car) error {
+	if before.Price.Equal(after.Price) && before.Currency == after.Currency {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO car_price_history (id, car_id, old_price, old_currency, new_price, new_currency, changed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		uuid.New(), after.ID, before.Price, before.Currency, after.Price, after.Currency, after.UpdatedAt)
+	return store.MapError(err, "car")
+}
+
+// versionMismatch tells a missing car apart from one whose version moved on
+// after an update matched no rows.
+func (s Store) versionMismatch(ctx context.Context, tx *sql.Tx, id string) error {
+	var current int64
+	err := tx.QueryRowContext(ctx, "SELECT version FROM car WHERE id = $1 AND deleted_at IS NULL", id).Scan(&current)
+	if err != nil {
+		return store.MapError(err, "car")
+	}
+	return apperrors.PreconditionFailed("car has been modified, it is now at version " + strconv.FormatInt(current, 10))
+}
+
+// DeletedCars lists the cars in the trash, most recently deleted first.
+func (s Store) DeletedCars(ctx context.Context) ([]models.Car, error) {
+	cars := []models.Car{}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT id, vin, name, year, brand, brand_id, model_id, fuel_type, engine_id, price, currency, created_at, updated_at, version, deleted_at
+	FROM car WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC, id`)
+	if err != nil {
+		return nil, store.MapError(err, "car")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var car models.Car
+		err := rows.Scan(
+			&car.ID,
+			&car.VIN,
+			&car.Name,
+			&car.Year,
+			&car.Brand,
+			&car.BrandID,
+			&car.ModelID,
+			&car.FuelType,
+			&car.Engine.EngineID,
+			&car.Price,
+			&car.Currency,
+			&car.CreatedAt,
+			&car.UpdatedAt,
+			&car.Version,
+			&car.DeletedAt,
+		)
+		if err != nil {
+			return nil, store.MapError(err, "car")
+		}
+		cars = append(cars, car)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, store.MapError(err, "car")
+	}
+	return cars, nil
+}
+
+// RestoreCar takes a car out of the trash. Its engine has to be restored
+// first if it was deleted too.
+func (s Store) RestoreCar(ctx context.Context, id string) (_ models.Car, err error) {
+	var restoredCar models.Car
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return restoredCar, store.MapError(err, "car")
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				s.logger.ErrorContext(ctx, "rolling back car transaction", "error", rbErr)
+			}
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	before, err := s.lockCar(ctx, tx, id, true)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		err = apperrors.NotFound("car not found in trash")
+		return restoredCar, err
+	}
+	if err != nil {
+		return restoredCar, err
+	}
+	if err = s.checkEngine(ctx, tx, before.Engine.EngineID); err != nil {
+		if errors.Is(err, apperrors.ErrForeignKey) {
+			err = apperrors.ForeignKey("engine_id", "the engine of the car is deleted, restore it first")
+		}
+		return restoredCar, err
+	}
+
+	err = tx.QueryRowContext(ctx, `UPDATE car SET deleted_at = NULL, version = version + 1 WHERE id = $1
+	RETURNING id, vin, name, year, brand, brand_id, model_id, fuel_type, engine_id, price, currency, created_at, updated_at, version`, id).Scan(
+		&restoredCar.ID,
+		&restoredCar.VIN,
+		&restoredCar.Name,
+		&restoredCar.Year,
+		&restoredCar.Brand,
+		&restoredCar.BrandID,
+		&restoredCar.ModelID,
+		&restoredCar.FuelType,
+		&restoredCar.Engine.EngineID,
+		&restoredCar.Price,
+		&restoredCar.Currency,
+		&restoredCar.CreatedAt,
+		&restoredCar.UpdatedAt,
+		&restoredCar.Version,
+	)
+	if err != nil {
+		return restoredCar, store.MapError(err, "car")
+	}
+	if err = store.Audit(ctx, tx, models.AuditRestore, "car", restoredCar.ID, before, restoredCar); err != nil {
+		return restoredCar, err
+	}
+	return restoredCar, nil
+}
+
+// PurgeCars permanently removes the cars deleted before the given time.
+func (s Store) PurgeCars(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM car WHERE deleted_at < $1", before)
+	if err != nil {
+		return 0, store.MapError(err, "car")
+	}
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, store.MapError(err, "car")
+	}
+	return purged, nil
+}
+
+func (s Store) PriceHistory(ctx context.Context, id string) (models.PriceHistory, error) {
+	history := models.PriceHistory{Changes: []models.PriceChange{}}
+
+	err := s.db.QueryRowContext(ctx, "SELECT id, price, currency FROM car WHERE id = $1 AND deleted_at IS NULL", id).Scan(
+		&history.CarID, &history.Price, &history.Currency,
+	)
+	if err != nil {
+		return history, store.MapError(err, "car")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT old_price, old_currency, new_price, new_currency, changed_at FROM car_price_history
+	WHERE car_id = $1 ORDER BY changed_at, id`, id)
+	if err != nil {
+		return history, store.MapError(err, "car")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var change models.PriceChange
+		if err := rows.Scan(&change.OldPrice, &change.OldCurrency, &change.NewPrice, &change.NewCurrency, &change.ChangedAt); err != nil {
+			return history, store.MapError(err, "car")
+		}
+		history.Changes = append(history.Changes, change)
+	}
+	if err = rows.Err(); err != nil {
+		return history, store.MapError(err, "car")
+	}
+	return history, nil
+}
+
+// priceGroupColumns maps the price groupings to their SQL column.
+var priceGroupColumns = map[string]string{
+	"brand":     "brand",
+	"fuel_type": "fuel_type",
+	"year":      "year",
+}
+
+func (s Store) PriceStats(ctx context.Context, filter models.PriceStatsFilter) ([]models.PriceStats, error) {
+	stats := []models.PriceStats{}
+
+	column, ok := priceGroupColumns[filter.GroupBy]
+	if !ok {
+		return stats, models.ValidatePriceStatsFilter(filter)
+	}
+
+	conditions := []string{"deleted_at IS NULL"}
+	var args []interface{}
+	addCondition := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.Brand != "" {
+		addCondition("brand_id IN (SELECT id FROM brand WHERE slug = $%[1]d OR id::text = $%[1]d)", models.Slug(filter.Brand))
+	}
+	if filter.FuelType != "" {
+		addCondition("fuel_type = $%d", filter.FuelType)
+	}
+
+	query := fmt.Sprintf(`SELECT %[1]s, currency, COUNT(*), AVG(price), MIN(price), MAX(price) FROM car
+	WHERE %[2]s GROUP BY %[1]s, currency ORDER BY %[1]s, currency`, column, strings.Join(conditions, " AND "))
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, store.MapError(err, "car")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var stat models.PriceStats
+		if err := rows.Scan(&stat.Group, &stat.Currency, &stat.Count, &stat.Average, &stat.Min, &stat.Max); err != nil {
+			return nil, store.MapError(err, "car")
+		}
+		stat.Average = models.RoundPrice(stat.Average, stat.Currency)
+		stats = append(stats, stat)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, store.MapError(err, "car")
+	}
+	return stats, nil
+}
+
+// ImportCars writes every row in a savepoint of one transaction, so a failed
+// row is rolled back on its own and the rest of the import carries on.
+func (s Store) ImportCars(ctx context.Context, rows []models.CarImportRow, options models.ImportOptions) (_ []models.CarImportRow, err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, store.MapError(err, "car")
+	}
+	keep := false
+	defer func() {
+		if err != nil || !keep {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				s.logger.ErrorContext(ctx, "rolling back car transaction", "error", rbErr)
+			}
+			return
+		}
+		err = store.MapError(tx.Commit(), "car")
+	}()
+
+	failed := false
+	for i := range rows {
+		if rows[i].Err == nil {
+			if _, err = tx.ExecContext(ctx, "SAVEPOINT import_row"); err != nil {
+				return nil, store.MapError(err, "car")
+			}
+			end := "RELEASE SAVEPOINT import_row"
+			if rows[i].Err = s.importCar(ctx, tx, rows[i]); rows[i].Err != nil {
+				end = "ROLLBACK TO SAVEPOINT import_row"
+			}
+			if _, err = tx.ExecContext(ctx, end); err != nil {
+				return nil, store.MapError(err, "car")
+			}
+		}
+		failed = failed || rows[i].Err != nil
+	}
+	keep = !options.DryRun && !(failed && options.Mode == models.ImportAllOrNothing)
+	return rows, nil
+}
+
+// BatchCars applies every item in a savepoint of one transaction, the same
+// way ImportCars writes its rows.
+func (s Store) BatchCars(ctx context.Context, items []models.CarBatchItem, atomic bool) (_ []models.CarBatchItem, err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, store.MapError(err, "car")
+	}
+	keep := false
+	defer func() {
+		if err != nil || !keep {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				s.logger.ErrorContext(ctx, "rolling back car transaction", "error", rbErr)
+			}
+			return
+		}
+		err = store.MapError(tx.Commit(), "car")
+	}()
+
+	failed := false
+	for i := range items {
+		if items[i].Err == nil {
+			if _, err = tx.ExecContext(ctx, "SAVEPOINT batch_item"); err != nil {
+				return nil, store.MapError(err, "car")
+			}
+			end := "RELEASE SAVEPOINT batch_item"
+			if items[i].Result, items[i].Err = s.batchCar(ctx, tx, items[i]); items[i].Err != nil {
+				end = "ROLLBACK TO SAVEPOINT batch_item"
+			}
+			if _, err = tx.ExecContext(ctx, end); err != nil {
+				return nil, store.MapError(err, "car")
+			}
+		}
+		failed = failed || items[i].Err != nil
+	}
+	keep = !(failed && atomic)
+	return items, nil
+}
+
+func (s Store) batchCar(ctx context.Context, tx *sql.Tx, item models.CarBatchItem) (models.Car, error) {
+	switch item.Op {
+	case models.BatchCreate:
+		return s.createCar(ctx, tx, item.ID, &item.Car)
+	case models.BatchUpdate:
+		return s.updateCar(ctx, tx, item.ID.String(), &item.Car, item.Version)
+	case models.BatchDelete:
+		return s.deleteCar(ctx, tx, item.ID.String(), item.Version)
+	default:
+		return models.Car{}, apperrors.Validation("op", "op must be create, update or delete")
+	}
+}
+
+func (s Store) importCar(ctx context.Context, tx *sql.Tx, row models.CarImportRow) error {
+	if row.NewEngine {
+		engine := row.Car.Engine
+		engine.Version = 1
+		_, err := tx.ExecContext(ctx, "INSERT INTO engine (id, displacement, no_of_cylinders, car_range) VALUES ($1, $2, $3, $4)",
+			engine.EngineID, engine.Displacement, engine.NoOfCyclinders, engine.CarRange)
+		if err != nil {
+			return store.MapError(err, "engine")
+		}
+		if err := store.Audit(ctx, tx, models.AuditCreate, "engine", engine.EngineID, nil, engine); err != nil {
+			return err
+		}
+	}
+	_, err := s.createCar(ctx, tx, row.ID, &row.Car)
+	return err
+}
+
+// checkEngine reports a foreign key error when the engine does not exist or is
+// in the trash, which the foreign key constraint alone cannot tell.
+func (s Store) checkEngine(ctx context.Context, tx *sql.Tx, engineID uuid.UUID) error {
+	var exists bool
+	err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM engine WHERE id = $1 AND deleted_at IS NULL)", engineID).Scan(&exists)
+	if err != nil {
+		return store.MapError(err, "engine")
+	}
+	if !exists {
+		return apperrors.ForeignKey("engine_id", "engine_id does not exists in the engine table")
+	}
+	return nil
+}
+
+// sortColumns maps the public sort fields to their SQL expression and the type
+// the cursor value is cast to.
+var sortColumns = map[string][2]string{
+	"name":            {"c.name", "text"},
+	"year":            {"CAST(c.year AS INT)", "int"},
+	"brand":           {"c.brand", "text"},
+	"fuel_type":       {"c.fuel_type", "text"},
+	"price":           {"c.price", "numeric"},
+	"created_at":      {"c.created_at", "timestamp"},
+	"updated_at":      {"c.updated_at", "timestamp"},
+	"displacement":    {"COALESCE(e.displacement, 0)", "int"},
+	"no_of_cylinders": {"COALESCE(e.no_of_cylinders, 0)", "int"},
+	"car_range":       {"COALESCE(e.car_range, 0)", "int"},
+}
+
+func (s Store) ListCars(ctx context.Context, filter models.CarFilter) (models.CarPage, error) {
+	page := models.CarPage{Cars: []models.Car{}}
+
+	cursor, err := models.DecodeCarCursor(filter)
+	if err != nil {
+		return page, err
+	}
+	sortColumn, ok := sortColumns[filter.SortBy]
+	if !ok {
+		return page, apperrors.Validation("sort", "sort must be one of the car columns")
+	}
+
+	conditions, args := listConditions(filter)
+	from := " FROM car c LEFT JOIN engine e ON c.engine_id = e.id"
+	where := " WHERE " + strings.Join(conditions, " AND ")
+
+	err = s.db.QueryRowContext(ctx, "SELECT COUNT(*)"+from+where, args...).Scan(&page.Total)
+	if err != nil {
+		return page, store.MapError(err, "car")
+	}
+
+	direction, comparison := "ASC", ">"
+	if filter.SortDesc {
+		direction, comparison = "DESC", "<"
+	}
+	if cursor != nil {
+		args = append(args, cursor.Value, cursor.ID)
+		where += fmt.Sprintf(" AND (%s, c.id) %s ($%d::%s, $%d::uuid)",
+			sortColumn[0], comparison, len(args)-1, sortColumn[1], len(args))
+	}
+
+	args = append(args, filter.Limit+1)
+	query := listColumns + from + where +
+		fmt.Sprintf(" ORDER BY %s %s, c.id %s LIMIT $%d", sortColumn[0], direction, direction, len(args))
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return page, store.MapError(err, "car")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		car, err := scanListedCar(rows)
+		if err != nil {
+			return page, store.MapError(err, "car")
+		}
+		page.Cars = append(page.Cars, car)
+	}
+	if err = rows.Err(); err != nil {
+		return page, store.MapError(err, "car")
+	}
+
+	return models.FinishCarPage(page, filter), nil
+}
+
+// ExportCars streams the cars matching filter, in its order, straight from
+// the database cursor to fn, so the result is never held in memory.
+func (s Store) ExportCars(ctx context.Context, filter models.CarFilter, fn func(models.Car) error) error {
+	sortColumn, ok := sortColumns[filter.SortBy]
+	if !ok {
+		return apperrors.Validation("sort", "sort must be one of the car columns")
+	}
+
+	conditions, args := listConditions(filter)
+	direction := "ASC"
+	if filter.SortDesc {
+		direction = "DESC"
+	}
+	query := listColumns + " FROM car c LEFT JOIN engine e ON c.engine_id = e.id WHERE " + strings.Join(conditions, " AND ") +
+		fmt.Sprintf(" ORDER BY %s %s, c.id %s", sortColumn[0], direction, direction)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return store.MapError(err, "car")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		car, err := scanListedCar(rows)
+		if err != nil {
+			return store.MapError(err, "car")
+		}
+		if !filter.IsEngine {
+			car.Engine = models.Engine{EngineID: car.Engine.EngineID}
+		}
+		if err := fn(car); err != nil {
+			return err
+		}
+	}
+	return store.MapError(rows.Err(), "car")
+}
+
+// listColumns selects a car and its engine for ListCars and ExportCars, in
+// the order scanListedCar reads them.
+const listColumns = `SELECT c.id, c.vin, c.name, c.year, c.brand, c.brand_id, c.model_id, c.fuel_type, c.engine_id, c.price, c.currency, c.created_at, c.updated_at, c.version,
+	COALESCE(e.displacement, 0), COALESCE(e.no_of_cylinders, 0), COALESCE(e.car_range, 0), COALESCE(e.version, 0)`
+
+func scanListedCar(rows *sql.Rows) (models.Car, error) {
+	var car models.Car
+	err := rows.Scan(
+		&car.ID,
+		&car.VIN,
+		&car.Name,
+		&car.Year,
+		&car.Brand,
+		&car.BrandID,
+		&car.ModelID,
+		&car.FuelType,
+		&car.Engine.EngineID,
+		&car.Price,
+		&car.Currency,
+		&car.CreatedAt,
+		&car.UpdatedAt,
+		&car.Version,
+		&car.Engine.Displacement,
+		&car.Engine.NoOfCyclinders,
+		&car.Engine.CarRange,
+		&car.Engine.Version,
+	)
+	return car, err
+}
+
+// listConditions turns the filters of a listing into SQL conditions on the
+// car c joined with its engine e, and their arguments.
+func listConditions(filter models.CarFilter) ([]string, []interface{}) {
+	conditions := []string{"c.deleted_at IS NULL"}
+	var args []interface{}
+	addCondition := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.Brand != "" {
+		addCondition("c.brand_id IN (SELECT id FROM brand WHERE slug = $%[1]d OR id::text = $%[1]d)", models.Slug(filter.Brand))
+	}
+	if filter.FuelType != "" {
+		addCondition("c.fuel_type = $%d", filter.FuelType)
+	}
+	if filter.YearFrom != 0 {
+		addCondition("CAST(c.year AS INT) >= $%d", filter.YearFrom)
+	}
+	if filter.YearTo != 0 {
+		addCondition("CAST(c.year AS INT) <= $%d", filter.YearTo)
+	}
+	if !filter.PriceMin.IsZero() {
+		addCondition("c.price >= $%d", filter.PriceMin)
+	}
+	if !filter.PriceMax.IsZero() {
+		addCondition("c.price <= $%d", filter.PriceMax)
+	}
+	if filter.DisplacementMin != 0 {
+		addCondition("e.displacement >= $%d", filter.DisplacementMin)
+	}
+	if filter.DisplacementMax != 0 {
+		addCondition("e.displacement <= $%d", filter.DisplacementMax)
+	}
+	if filter.Cylinders != 0 {
+		addCondition("e.no_of_cylinders = $%d", filter.Cylinders)
+	}
+	return conditions, args
+}
+
+func (s Store) SearchCars(ctx context.Context, query string, limit int) ([]models.CarSearchResult, error) {
+	results := []models.CarSearchResult{}
+
+	terms := models.SearchTerms(query)
+	if len(terms) == 0 {
+		return results, nil
+	}
+	// Every term matches as a prefix and any matching term is enough, the
+	// rank decides which cars match best.
+	for i, term := range terms {
+		terms[i] = term + ":*"
+	}
+	tsQuery := strings.Join(terms, " | ")
+	text := strings.ToLower(query)
+
+	// The text is escaped in Go, so the matches are delimited with control
+	// characters, which are taken out of the columns first.
+	highlight := fmt.Sprintf(`'StartSel="%s", StopSel="%s", HighlightAll=true'`, models.MatchStart, models.MatchStop)
+	searchQuery := `SELECT c.id, c.vin, c.name, c.year, c.brand, c.brand_id, c.model_id, c.fuel_type, c.engine_id, c.price, c.currency, c.created_at, c.updated_at, c.version,
+	COALESCE(e.displacement, 0), COALESCE(e.no_of_cylinders, 0), COALESCE(e.car_range, 0), COALESCE(e.version, 0),
+	ts_rank(c.search_vector, q.query) + word_similarity($2, c.name || ' ' || c.brand || ' ' || c.fuel_type) AS rank,
+	ts_headline('simple', translate(c.name, $4, ''), q.query, ` + highlight + `),
+	ts_headline('simple', translate(c.brand, $4, ''), q.query, ` + highlight + `),
+	ts_headline('simple', translate(c.fuel_type, $4, ''), q.query, ` + highlight + `)
+	FROM car c LEFT JOIN engine e ON c.engine_id = e.id, to_tsquery('simple', $1) AS q(query)
+	WHERE c.deleted_at IS NULL
+	AND (c.search_vector @@ q.query OR $2 <% (c.name || ' ' || c.brand || ' ' || c.fuel_type))
+	ORDER BY rank DESC, c.id
+	LIMIT $3`
+
+	rows, err := s.db.QueryContext(ctx, searchQuery, tsQuery, text, limit, models.MatchStart+models.MatchStop)
+	if err != nil {
+		return nil, store.MapError(err, "car")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var result models.CarSearchResult
+		var name, brand, fuelType string
+		car := &result.Car
+		err := rows.Scan(
+			&car.ID,
+			&car.VIN,
+			&car.Name,
+			&car.Year,
+			&car.Brand,
+			&car.BrandID,
+			&car.ModelID,
+			&car.FuelType,
+			&car.Engine.EngineID,
+			&car.Price,
+			&car.Currency,
+			&car.CreatedAt,
+			&car.UpdatedAt,
+			&car.Version,
+			&car.Engine.Displacement,
+			&car.Engine.NoOfCyclinders,
+			&car.Engine.CarRange,
+			&car.Engine.Version,
+			&result.Rank,
+			&name,
+			&brand,
+			&fuelType,
+		)
+		if err != nil {
+			return nil, store.MapError(err, "car")
+		}
+		result.Highlights = models.CarHighlights(map[string]string{
+			"name":      models.EscapeHighlight(name),
+			"brand":     models.EscapeHighlight(brand),
+			"fuel_type": models.EscapeHighlight(fuelType),
+		})
+		results = append(results, result)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, store.MapError(err, "car")
+	}
+	return results, nil
+}
@@ -0,0 +1,68 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+
+	"github.com/ayushi-khandal09/carZone/apperrors"
+	"github.com/ayushi-khandal09/carZone/models"
+	"github.com/google/uuid"
+)
+
+// Querier runs a query on the database or inside a transaction.
+type Querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// A reference to a catalog entry is its id or its name, which matches the
+// entry with the same slug. The slug of an id is the id itself, so both are
+// looked up with one argument.
+
+// LookupBrand finds the brand ref refers to.
+func LookupBrand(ctx context.Context, q Querier, ref string) (models.Brand, error) {
+	var brand models.Brand
+	err := q.QueryRowContext(ctx, `SELECT id, name, slug, created_at, updated_at FROM brand
+	WHERE slug = $1 OR id::text = $1`, models.Slug(ref)).Scan(
+		&brand.ID, &brand.Name, &brand.Slug, &brand.CreatedAt, &brand.UpdatedAt,
+	)
+	return brand, MapError(err, "brand")
+}
+
+// LookupCarModel finds the model of the brand that ref refers to.
+func LookupCarModel(ctx context.Context, q Querier, brandID uuid.UUID, ref string) (models.CarModel, error) {
+	var model models.CarModel
+	err := q.QueryRowContext(ctx, `SELECT id, brand_id, name, slug, created_at, updated_at FROM car_model
+	WHERE brand_id = $1 AND (slug = $2 OR id::text = $2)`, brandID, models.Slug(ref)).Scan(
+		&model.ID, &model.BrandID, &model.Name, &model.Slug, &model.CreatedAt, &model.UpdatedAt,
+	)
+	return model, MapError(err, "model")
+}
+
+// ResolveCarModel finds the brand and model a car refers to, failing with a
+// foreign key error when either is not in the catalog.
+func ResolveCarModel(ctx context.Context, q Querier, brandRef, modelRef string) (models.Brand, models.CarModel, error) {
+	brand, err := LookupBrand(ctx, q, brandRef)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return brand, models.CarModel{}, UnknownBrand(brandRef)
+	}
+	if err != nil {
+		return brand, models.CarModel{}, err
+	}
+	model, err := LookupCarModel(ctx, q, brand.ID, modelRef)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return brand, model, UnknownCarModel(brand, modelRef)
+	}
+	return brand, model, err
+}
+
+// UnknownBrand and UnknownCarModel are the errors of a car that refers to
+// an entry missing from the catalog.
+func UnknownBrand(ref string) error {
+	return apperrors.ForeignKey("brand", "brand "+strconv.Quote(ref)+" is not in the catalog")
+}
+
+func UnknownCarModel(brand models.Brand, ref string) error {
+	return apperrors.ForeignKey("name", "model "+strconv.Quote(ref)+" of "+brand.Name+" is not in the catalog")
+}
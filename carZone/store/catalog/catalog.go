@@ -0,0 +1,371 @@
+package catalog
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/ayushi-khandal09/carZone/apperrors"
+	"github.com/ayushi-khandal09/carZone/models"
+	"github.com/ayushi-khandal09/carZone/store"
+	"github.com/google/uuid"
+)
+
+const (
+	brandColumns = "id, name, slug, created_at, updated_at"
+	modelColumns = "id, brand_id, name, slug, created_at, updated_at"
+)
+
+type Store struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func New(db *sql.DB, logger *slog.Logger) Store {
+	return Store{db: db, logger: logger}
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanBrand(row scanner) (models.Brand, error) {
+	var brand models.Brand
+	err := row.Scan(&brand.ID, &brand.Name, &brand.Slug, &brand.CreatedAt, &brand.UpdatedAt)
+	return brand, err
+}
+
+func scanModel(row scanner) (models.CarModel, error) {
+	var model models.CarModel
+	err := row.Scan(&model.ID, &model.BrandID, &model.Name, &model.Slug, &model.CreatedAt, &model.UpdatedAt)
+	return model, err
+}
+
+func (s Store) ListBrands(ctx context.Context) ([]models.Brand, error) {
+	brands := []models.Brand{}
+
+	rows, err := s.db.QueryContext(ctx, "SELECT "+brandColumns+" FROM brand ORDER BY slug")
+	if err != nil {
+		return nil, store.MapError(err, "brand")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		brand, err := scanBrand(rows)
+		if err != nil {
+			return nil, store.MapError(err, "brand")
+		}
+		brands = append(brands, brand)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, store.MapError(err, "brand")
+	}
+	return brands, nil
+}
+
+func (s Store) GetBrand(ctx context.Context, ref string) (models.Brand, error) {
+	return store.LookupBrand(ctx, s.db, ref)
+}
+
+func (s Store) CreateBrand(ctx context.Context, brandReq *models.BrandRequest) (_ models.Brand, err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Brand{}, store.MapError(err, "brand")
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				s.logger.ErrorContext(ctx, "rolling back catalog transaction", "error", rbErr)
+			}
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	createdAt := time.Now()
+	brand, err := scanBrand(tx.QueryRowContext(ctx,
+		`INSERT INTO brand (id, name, slug, created_at, updated_at) VALUES ($1, $2, $3, $4, $4)
+		RETURNING `+brandColumns,
+		uuid.New(), brandReq.Name, models.Slug(brandReq.Name), createdAt,
+	))
+	if err != nil {
+		return models.Brand{}, store.MapError(err, "brand")
+	}
+	if err = store.Audit(ctx, tx, models.AuditCreate, "brand", brand.ID, nil, brand); err != nil {
+		return models.Brand{}, err
+	}
+	return brand, nil
+}
+
+func (s Store) UpdateBrand(ctx context.Context, ref string, brandReq *models.BrandRequest) (_ models.Brand, err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Brand{}, store.MapError(err, "brand")
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				s.logger.ErrorContext(ctx, "rolling back catalog transaction", "error", rbErr)
+			}
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	before, err := store.LookupBrand(ctx, tx, ref)
+	if err != nil {
+		return models.Brand{}, err
+	}
+	brand, err := scanBrand(tx.QueryRowContext(ctx,
+		`UPDATE brand SET name = $2, slug = $3, updated_at = $4 WHERE id = $1
+		RETURNING `+brandColumns,
+		before.ID, brandReq.Name, models.Slug(brandReq.Name), time.Now(),
+	))
+	if err != nil {
+		return models.Brand{}, store.MapError(err, "brand")
+	}
+	if err = store.Audit(ctx, tx, models.AuditUpdate, "brand", brand.ID, before, brand); err != nil {
+		return models.Brand{}, err
+	}
+	if err = s.renameCars(ctx, tx, "brand", brand.ID, before.Name, brand.Name); err != nil {
+		return models.Brand{}, err
+	}
+	return brand, nil
+}
+
+func (s Store) DeleteBrand(ctx context.Context, ref string) (_ models.Brand, err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Brand{}, store.MapError(err, "brand")
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				s.logger.ErrorContext(ctx, "rolling back catalog transaction", "error", rbErr)
+			}
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	brand, err := store.LookupBrand(ctx, tx, ref)
+	if err != nil {
+		return models.Brand{}, err
+	}
+	var hasModels bool
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM car_model WHERE brand_id = $1)", brand.ID).Scan(&hasModels)
+	if err != nil {
+		return models.Brand{}, store.MapError(err, "brand")
+	}
+	if hasModels {
+		err = apperrors.Conflict("brand still has models, delete them first", nil)
+		return models.Brand{}, err
+	}
+	if _, err = tx.ExecContext(ctx, "DELETE FROM brand WHERE id = $1", brand.ID); err != nil {
+		return models.Brand{}, store.MapError(err, "brand")
+	}
+	if err = store.Audit(ctx, tx, models.AuditDelete, "brand", brand.ID, brand, nil); err != nil {
+		return models.Brand{}, err
+	}
+	return brand, nil
+}
+
+func (s Store) ListCarModels(ctx context.Context, brandRef string) ([]models.CarModel, error) {
+	carModels := []models.CarModel{}
+
+	brand, err := store.LookupBrand(ctx, s.db, brandRef)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx, "SELECT "+modelColumns+" FROM car_model WHERE brand_id = $1 ORDER BY slug", brand.ID)
+	if err != nil {
+		return nil, store.MapError(err, "model")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		model, err := scanModel(rows)
+		if err != nil {
+			return nil, store.MapError(err, "model")
+		}
+		carModels = append(carModels, model)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, store.MapError(err, "model")
+	}
+	return carModels, nil
+}
+
+func (s Store) GetCarModel(ctx context.Context, brandRef, ref string) (models.CarModel, error) {
+	brand, err := store.LookupBrand(ctx, s.db, brandRef)
+	if err != nil {
+		return models.CarModel{}, err
+	}
+	return store.LookupCarModel(ctx, s.db, brand.ID, ref)
+}
+
+func (s Store) CreateCarModel(ctx context.Context, brandRef string, modelReq *models.CarModelRequest) (_ models.CarModel, err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.CarModel{}, store.MapError(err, "model")
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				s.logger.ErrorContext(ctx, "rolling back catalog transaction", "error", rbErr)
+			}
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	brand, err := store.LookupBrand(ctx, tx, brandRef)
+	if err != nil {
+		return models.CarModel{}, err
+	}
+	createdAt := time.Now()
+	model, err := scanModel(tx.QueryRowContext(ctx,
+		`INSERT INTO car_model (id, brand_id, name, slug, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $5)
+		RETURNING `+modelColumns,
+		uuid.New(), brand.ID, modelReq.Name, models.Slug(modelReq.Name), createdAt,
+	))
+	if err != nil {
+		return models.CarModel{}, store.MapError(err, "model")
+	}
+	if err = store.Audit(ctx, tx, models.AuditCreate, "car_model", model.ID, nil, model); err != nil {
+		return models.CarModel{}, err
+	}
+	return model, nil
+}
+
+func (s Store) UpdateCarModel(ctx context.Context, brandRef, ref string, modelReq *models.CarModelRequest) (_ models.CarModel, err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.CarModel{}, store.MapError(err, "model")
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				s.logger.ErrorContext(ctx, "rolling back catalog transaction", "error", rbErr)
+			}
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	before, err := s.lookupModel(ctx, tx, brandRef, ref)
+	if err != nil {
+		return models.CarModel{}, err
+	}
+	model, err := scanModel(tx.QueryRowContext(ctx,
+		`UPDATE car_model SET name = $2, slug = $3, updated_at = $4 WHERE id = $1
+		RETURNING `+modelColumns,
+		before.ID, modelReq.Name, models.Slug(modelReq.Name), time.Now(),
+	))
+	if err != nil {
+		return models.CarModel{}, store.MapError(err, "model")
+	}
+	if err = store.Audit(ctx, tx, models.AuditUpdate, "car_model", model.ID, before, model); err != nil {
+		return models.CarModel{}, err
+	}
+	if err = s.renameCars(ctx, tx, "name", model.ID, before.Name, model.Name); err != nil {
+		return models.CarModel{}, err
+	}
+	return model, nil
+}
+
+func (s Store) DeleteCarModel(ctx context.Context, brandRef, ref string) (_ models.CarModel, err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.CarModel{}, store.MapError(err, "model")
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				s.logger.ErrorContext(ctx, "rolling back catalog transaction", "error", rbErr)
+			}
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	model, err := s.lookupModel(ctx, tx, brandRef, ref)
+	if err != nil {
+		return models.CarModel{}, err
+	}
+	// Cars in the trash keep their model, they could still be restored.
+	var hasCars bool
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM car WHERE model_id = $1)", model.ID).Scan(&hasCars)
+	if err != nil {
+		return models.CarModel{}, store.MapError(err, "model")
+	}
+	if hasCars {
+		err = apperrors.Conflict("model still has cars, delete and purge them first", nil)
+		return models.CarModel{}, err
+	}
+	if _, err = tx.ExecContext(ctx, "DELETE FROM car_model WHERE id = $1", model.ID); err != nil {
+		return models.CarModel{}, store.MapError(err, "model")
+	}
+	if err = store.Audit(ctx, tx, models.AuditDelete, "car_model", model.ID, model, nil); err != nil {
+		return models.CarModel{}, err
+	}
+	return model, nil
+}
+
+func (s Store) lookupModel(ctx context.Context, tx *sql.Tx, brandRef, ref string) (models.CarModel, error) {
+	brand, err := store.LookupBrand(ctx, tx, brandRef)
+	if err != nil {
+		return models.CarModel{}, err
+	}
+	return store.LookupCarModel(ctx, tx, brand.ID, ref)
+}
+
+// renameCars sets the brand or the name column of the cars of a brand or a
+// model, which keep the catalog name next to its id, and audits every car it
+// changed. Cars in the trash are renamed too.
+func (s Store) renameCars(ctx context.Context, tx *sql.Tx, column string, id uuid.UUID, from, to string) error {
+	if from == to {
+		return nil
+	}
+	idColumn := "model_id"
+	if column == "brand" {
+		idColumn = "brand_id"
+	}
+	rows, err := tx.QueryContext(ctx, `UPDATE car SET `+column+` = $2, version = version + 1 WHERE `+idColumn+` = $1
+	RETURNING id, vin, name, year, brand, brand_id, model_id, fuel_type, engine_id, price, currency, created_at, updated_at, version, deleted_at`,
+		id, to)
+	if err != nil {
+		return store.MapError(err, "car")
+	}
+	defer rows.Close()
+
+	var cars []models.Car
+	for rows.Next() {
+		var car models.Car
+		err := rows.Scan(&car.ID, &car.VIN, &car.Name, &car.Year, &car.Brand, &car.BrandID, &car.ModelID, &car.FuelType,
+			&car.Engine.EngineID, &car.Price, &car.Currency, &car.CreatedAt, &car.UpdatedAt, &car.Version, &car.DeletedAt)
+		if err != nil {
+			return store.MapError(err, "car")
+		}
+		cars = append(cars, car)
+	}
+	if err = rows.Err(); err != nil {
+		return store.MapError(err, "car")
+	}
+	rows.Close()
+
+	for _, car := range cars {
+		before := car
+		before.Version--
+		if column == "brand" {
+			before.Brand = from
+		} else {
+			before.Name = from
+		}
+		if err := store.Audit(ctx, tx, models.AuditUpdate, "car", car.ID, before, car); err != nil {
+			return err
+		}
+	}
+	return nil
+}
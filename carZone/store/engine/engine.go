@@ -5,17 +5,24 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/ayushi-khandal09/carZone/apperrors"
 	"github.com/ayushi-khandal09/carZone/models"
+	"github.com/ayushi-khandal09/carZone/store"
 	"github.com/google/uuid"
 )
 
 type EngineStore struct {
-	db *sql.DB
+	db     *sql.DB
+	logger *slog.Logger
 }
 
-func New(db *sql.DB) *EngineStore {
-	return &EngineStore{db: db}
+func New(db *sql.DB, logger *slog.Logger) *EngineStore {
+	return &EngineStore{db: db, logger: logger}
 }
 
 func (e EngineStore) EngineById(ctx context.Context, id string) (models.Engine, error) {
@@ -23,48 +30,43 @@ func (e EngineStore) EngineById(ctx context.Context, id string) (models.Engine,
 
 	tx, err := e.db.BeginTx(ctx, nil)
 	if err != nil {
-		return engine, err
+		return engine, store.MapError(err, "engine")
 	}
 	defer func() {
 		if err != nil {
 			if rbErr := tx.Rollback(); rbErr != nil {
-				fmt.Printf("Transaction rollback error: %v\n", rbErr)
+				e.logger.ErrorContext(ctx, "rolling back engine transaction", "error", rbErr)
 			}
 		} else {
 			if cmErr := tx.Commit(); cmErr != nil {
-				fmt.Printf("Transaction commit error: %v\n", cmErr)
+				e.logger.ErrorContext(ctx, "committing engine transaction", "error", cmErr)
 			}
 		}
 	}()
 
-	err = tx.QueryRowContext(ctx, "SELECT id, displacement, no_of_cylinders, car_range FROM engine WHERE id = $1", id).Scan(
-		&engine.EngineID, &engine.Displacement, &engine.NoOfCyclinders, &engine.CarRange,
+	err = tx.QueryRowContext(ctx, "SELECT id, displacement, no_of_cylinders, car_range, version FROM engine WHERE id = $1 AND deleted_at IS NULL", id).Scan(
+		&engine.EngineID, &engine.Displacement, &engine.NoOfCyclinders, &engine.CarRange, &engine.Version,
 	)
 
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return engine, nil
-		}
-		return engine, err
+		return engine, store.MapError(err, "engine")
 	}
 	return engine, err
 }
 
-func (e EngineStore) EngineCreate(ctx context.Context, engineReq *models.EngineRequest) (models.Engine, error) {
+func (e EngineStore) EngineCreate(ctx context.Context, engineReq *models.EngineRequest) (_ models.Engine, err error) {
 	tx, err := e.db.BeginTx(ctx, nil)
 	if err != nil {
-		return models.Engine{}, err
+		return models.Engine{}, store.MapError(err, "engine")
 	}
 	defer func() {
 		if err != nil {
 			if rbErr := tx.Rollback(); rbErr != nil {
-				fmt.Printf("Transaction rollback error: %v\n", rbErr)
-			}
-		} else {
-			if cmErr := tx.Commit(); cmErr != nil {
-				fmt.Printf("Transaction commit error: %v\n", cmErr)
+				e.logger.ErrorContext(ctx, "rolling back engine transaction", "error", rbErr)
 			}
+			return
 		}
+		err = tx.Commit()
 	}()
 
 	engineID := uuid.New()
@@ -74,53 +76,58 @@ func (e EngineStore) EngineCreate(ctx context.Context, engineReq *models.EngineR
 		engineReq.NoOfCyclinders, engineReq.CarRange)
 
 	if err != nil {
-		return models.Engine{}, err
+		return models.Engine{}, store.MapError(err, "engine")
 	}
 	engine := models.Engine{
 		EngineID:       engineID,
 		Displacement:   engineReq.Displacement,
 		NoOfCyclinders: engineReq.NoOfCyclinders,
 		CarRange:       engineReq.CarRange,
+		Version:        1,
+	}
+	if err = store.Audit(ctx, tx, models.AuditCreate, "engine", engineID, nil, engine); err != nil {
+		return models.Engine{}, err
 	}
 	return engine, nil
 }
 
-func (e EngineStore) EngineUpdate(ctx context.Context, id string, engineReq *models.EngineRequest) (models.Engine, error) {
+func (e EngineStore) EngineUpdate(ctx context.Context, id string, engineReq *models.EngineRequest, version int64) (_ models.Engine, err error) {
 	engineID, err := uuid.Parse(id)
 	if err != nil {
-		return models.Engine{}, fmt.Errorf("Invalid Engine ID: %w", err)
+		return models.Engine{}, apperrors.BadRequest("Invalid Engine ID", err)
 	}
 
 	tx, err := e.db.BeginTx(ctx, nil)
 	if err != nil {
-		return models.Engine{}, err
+		return models.Engine{}, store.MapError(err, "engine")
 	}
 	defer func() {
 		if err != nil {
 			if rbErr := tx.Rollback(); rbErr != nil {
-				fmt.Printf("Transaction rollback error: %v\n", rbErr)
-			}
-		} else {
-			if cmErr := tx.Commit(); cmErr != nil {
-				fmt.Printf("Transaction commit error: %v\n", cmErr)
+				e.logger.ErrorContext(ctx, "rolling back engine transaction", "error", rbErr)
 			}
+			return
 		}
+		err = tx.Commit()
 	}()
 
-	results, err := tx.ExecContext(ctx,
-		"UPDATE engine SET displacement = $1, no_of_cylinders = $2, car_range = $3 WHERE id = $4", engineReq.Displacement,
-		engineReq.NoOfCyclinders, engineReq.CarRange, engineID)
-
+	before, err := e.lockEngine(ctx, tx, id, false)
 	if err != nil {
 		return models.Engine{}, err
 	}
 
-	rowAffected, err := results.RowsAffected()
-	if err != nil {
+	var newVersion int64
+	err = tx.QueryRowContext(ctx,
+		`UPDATE engine SET displacement = $1, no_of_cylinders = $2, car_range = $3, version = version + 1
+		WHERE id = $4 AND deleted_at IS NULL AND ($5::bigint = 0 OR version = $5) RETURNING version`, engineReq.Displacement,
+		engineReq.NoOfCyclinders, engineReq.CarRange, engineID, version).Scan(&newVersion)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		err = e.versionMismatch(ctx, tx, engineID)
 		return models.Engine{}, err
 	}
-	if rowAffected == 0 {
-		return models.Engine{}, errors.New("No rows were updated")
+	if err != nil {
+		return models.Engine{}, store.MapError(err, "engine")
 	}
 
 	engine := models.Engine{
@@ -128,52 +135,342 @@ func (e EngineStore) EngineUpdate(ctx context.Context, id string, engineReq *mod
 		Displacement:   engineReq.Displacement,
 		NoOfCyclinders: engineReq.NoOfCyclinders,
 		CarRange:       engineReq.CarRange,
+		Version:        newVersion,
+	}
+	if err = store.Audit(ctx, tx, models.AuditUpdate, "engine", engineID, before, engine); err != nil {
+		return models.Engine{}, err
 	}
-
 	return engine, nil
 }
 
-func (e EngineStore) EngineDelete(ctx context.Context, id string) (models.Engine, error) {
+func (e EngineStore) EngineDelete(ctx context.Context, id string, version int64) (_ models.Engine, err error) {
 	var engine models.Engine
 	tx, err := e.db.BeginTx(ctx, nil)
 	if err != nil {
-		return models.Engine{}, err
+		return models.Engine{}, store.MapError(err, "engine")
 	}
 	defer func() {
 		if err != nil {
 			if rbErr := tx.Rollback(); rbErr != nil {
-				fmt.Printf("Transaction rollback error: %v\n", rbErr)
+				e.logger.ErrorContext(ctx, "rolling back engine transaction", "error", rbErr)
 			}
-		} else {
-			if cmErr := tx.Commit(); cmErr != nil {
-				fmt.Printf("Transaction commit error: %v\n", cmErr)
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	engine, err = e.lockEngine(ctx, tx, id, false)
+	if err != nil {
+		return models.Engine{}, err
+	}
+	if version != 0 && engine.Version != version {
+		err = apperrors.PreconditionFailed("engine has been modified, it is now at version " + strconv.FormatInt(engine.Version, 10))
+		return models.Engine{}, err
+	}
+
+	// Like the ON DELETE CASCADE foreign key, the cars of the engine go to the
+	// trash with it. They share its deleted_at so EngineRestore can bring
+	// back exactly those cars.
+	deletedAt := time.Now()
+	err = e.moveCars(ctx, tx, id, nil, &deletedAt)
+	if err != nil {
+		return models.Engine{}, err
+	}
+
+	result, err := tx.ExecContext(ctx,
+		"UPDATE engine SET deleted_at = $2, version = version + 1 WHERE id = $1", id, deletedAt)
+	if err != nil {
+		return models.Engine{}, store.MapError(err, "engine")
+	}
+
+	rowAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Engine{}, store.MapError(err, "engine")
+	}
+	if rowAffected == 0 {
+		err = apperrors.NotFound("engine not found")
+		return models.Engine{}, err
+	}
+	before := engine
+	engine.DeletedAt = &deletedAt
+	engine.Version++
+	if err = store.Audit(ctx, tx, models.AuditDelete, "engine", engine.EngineID, before, engine); err != nil {
+		return models.Engine{}, err
+	}
+	return engine, nil
+}
+
+// EnginePatch builds an UPDATE that only sets the columns present in patch.
+func (e EngineStore) EnginePatch(ctx context.Context, id string, patch models.EnginePatch, version int64) (_ models.Engine, err error) {
+	engineID, err := uuid.Parse(id)
+	if err != nil {
+		return models.Engine{}, apperrors.BadRequest("Invalid Engine ID", err)
+	}
+
+	sets := []string{"version = version + 1"}
+	args := []interface{}{engineID, version}
+	addSet := func(column string, value interface{}) {
+		args = append(args, value)
+		sets = append(sets, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+	if patch.Displacement != nil {
+		addSet("displacement", *patch.Displacement)
+	}
+	if patch.NoOfCyclinders != nil {
+		addSet("no_of_cylinders", *patch.NoOfCyclinders)
+	}
+	if patch.CarRange != nil {
+		addSet("car_range", *patch.CarRange)
+	}
+
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Engine{}, store.MapError(err, "engine")
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				e.logger.ErrorContext(ctx, "rolling back engine transaction", "error", rbErr)
 			}
+			return
 		}
+		err = tx.Commit()
 	}()
 
-	err = tx.QueryRowContext(ctx, "SELECT id, displacement, no_of_cylinders, car_range FROM engine WHERE id = $1", id).Scan(
-		&engine.EngineID, &engine.Displacement, &engine.NoOfCyclinders, &engine.CarRange,
+	before, err := e.lockEngine(ctx, tx, id, false)
+	if err != nil {
+		return models.Engine{}, err
+	}
+
+	var engine models.Engine
+	err = tx.QueryRowContext(ctx, `UPDATE engine SET `+strings.Join(sets, ", ")+`
+		WHERE id = $1 AND deleted_at IS NULL AND ($2::bigint = 0 OR version = $2)
+		RETURNING id, displacement, no_of_cylinders, car_range, version`, args...).Scan(
+		&engine.EngineID, &engine.Displacement, &engine.NoOfCyclinders, &engine.CarRange, &engine.Version,
 	)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = e.versionMismatch(ctx, tx, engineID)
+		return models.Engine{}, err
+	}
+	if err != nil {
+		return models.Engine{}, store.MapError(err, "engine")
+	}
+	if err = store.Audit(ctx, tx, models.AuditPatch, "engine", engineID, before, engine); err != nil {
+		return models.Engine{}, err
+	}
+	return engine, nil
+}
 
+// DeletedEngines lists the engines in the trash, most recently deleted first.
+func (e EngineStore) DeletedEngines(ctx context.Context) ([]models.Engine, error) {
+	engines := []models.Engine{}
+
+	rows, err := e.db.QueryContext(ctx, `SELECT id, displacement, no_of_cylinders, car_range, version, deleted_at
+	FROM engine WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC, id`)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return engine, nil
+		return nil, store.MapError(err, "engine")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var engine models.Engine
+		err := rows.Scan(&engine.EngineID, &engine.Displacement, &engine.NoOfCyclinders, &engine.CarRange, &engine.Version, &engine.DeletedAt)
+		if err != nil {
+			return nil, store.MapError(err, "engine")
 		}
-		return engine, err
+		engines = append(engines, engine)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, store.MapError(err, "engine")
 	}
+	return engines, nil
+}
 
-	result, err := tx.ExecContext(ctx,
-		"DELETE FROM engine WHERE id = $1", id)
+// EngineRestore takes an engine out of the trash together with the cars that
+// were deleted along with it.
+func (e EngineStore) EngineRestore(ctx context.Context, id string) (_ models.Engine, err error) {
+	var engine models.Engine
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Engine{}, store.MapError(err, "engine")
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				e.logger.ErrorContext(ctx, "rolling back engine transaction", "error", rbErr)
+			}
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	before, err := e.lockEngine(ctx, tx, id, true)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		err = apperrors.NotFound("engine not found in trash")
+		return models.Engine{}, err
+	}
 	if err != nil {
 		return models.Engine{}, err
 	}
 
-	rowAffected, err := result.RowsAffected()
+	err = e.moveCars(ctx, tx, id, before.DeletedAt, nil)
 	if err != nil {
 		return models.Engine{}, err
 	}
-	if rowAffected == 0 {
-		return models.Engine{}, errors.New("No rows were deleted")
+
+	err = tx.QueryRowContext(ctx, `UPDATE engine SET deleted_at = NULL, version = version + 1 WHERE id = $1
+	RETURNING id, displacement, no_of_cylinders, car_range, version`, id).Scan(
+		&engine.EngineID, &engine.Displacement, &engine.NoOfCyclinders, &engine.CarRange, &engine.Version,
+	)
+	if err != nil {
+		return models.Engine{}, store.MapError(err, "engine")
+	}
+	if err = store.Audit(ctx, tx, models.AuditRestore, "engine", engine.EngineID, before, engine); err != nil {
+		return models.Engine{}, err
+	}
+	return engine, nil
+}
+
+// EnginePurge permanently removes the engines deleted before the given time,
+// and through the foreign key every car that still refers to them.
+func (e EngineStore) EnginePurge(ctx context.Context, before time.Time) (int64, error) {
+	result, err := e.db.ExecContext(ctx, "DELETE FROM engine WHERE deleted_at < $1", before)
+	if err != nil {
+		return 0, store.MapError(err, "engine")
+	}
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, store.MapError(err, "engine")
+	}
+	return purged, nil
+}
+
+// EngineImport writes every row in a savepoint of one transaction, like
+// ImportCars of the car store.
+func (e EngineStore) EngineImport(ctx context.Context, rows []models.EngineImportRow, options models.ImportOptions) (_ []models.EngineImportRow, err error) {
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, store.MapError(err, "engine")
+	}
+	keep := false
+	defer func() {
+		if err != nil || !keep {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				e.logger.ErrorContext(ctx, "rolling back engine transaction", "error", rbErr)
+			}
+			return
+		}
+		err = store.MapError(tx.Commit(), "engine")
+	}()
+
+	failed := false
+	for i := range rows {
+		if rows[i].Err == nil {
+			if _, err = tx.ExecContext(ctx, "SAVEPOINT import_row"); err != nil {
+				return nil, store.MapError(err, "engine")
+			}
+			end := "RELEASE SAVEPOINT import_row"
+			if rows[i].Err = e.importEngine(ctx, tx, rows[i]); rows[i].Err != nil {
+				end = "ROLLBACK TO SAVEPOINT import_row"
+			}
+			if _, err = tx.ExecContext(ctx, end); err != nil {
+				return nil, store.MapError(err, "engine")
+			}
+		}
+		failed = failed || rows[i].Err != nil
+	}
+	keep = !options.DryRun && !(failed && options.Mode == models.ImportAllOrNothing)
+	return rows, nil
+}
+
+func (e EngineStore) importEngine(ctx context.Context, tx *sql.Tx, row models.EngineImportRow) error {
+	_, err := tx.ExecContext(ctx, "INSERT INTO engine (id, displacement, no_of_cylinders, car_range) VALUES ($1, $2, $3, $4)",
+		row.ID, row.Engine.Displacement, row.Engine.NoOfCyclinders, row.Engine.CarRange)
+	if err != nil {
+		return store.MapError(err, "engine")
+	}
+	engine := models.Engine{
+		EngineID:       row.ID,
+		Displacement:   row.Engine.Displacement,
+		NoOfCyclinders: row.Engine.NoOfCyclinders,
+		CarRange:       row.Engine.CarRange,
+		Version:        1,
+	}
+	return store.Audit(ctx, tx, models.AuditCreate, "engine", row.ID, nil, engine)
+}
+
+// lockEngine reads the engine with the given id, from the trash when inTrash
+// is set, and locks its row until tx ends.
+func (e EngineStore) lockEngine(ctx context.Context, tx *sql.Tx, id string, inTrash bool) (models.Engine, error) {
+	var engine models.Engine
+
+	condition := "deleted_at IS NULL"
+	if inTrash {
+		condition = "deleted_at IS NOT NULL"
+	}
+	err := tx.QueryRowContext(ctx, `SELECT id, displacement, no_of_cylinders, car_range, version, deleted_at
+	FROM engine WHERE id = $1 AND `+condition+` FOR UPDATE`, id).Scan(
+		&engine.EngineID, &engine.Displacement, &engine.NoOfCyclinders, &engine.CarRange, &engine.Version, &engine.DeletedAt,
+	)
+	if err != nil {
+		return engine, store.MapError(err, "engine")
 	}
 	return engine, nil
 }
+
+// moveCars sets deleted_at of the cars of an engine from the value in from
+// to the one in to, moving them in or out of the trash along with it, and
+// audits every car it touched.
+func (e EngineStore) moveCars(ctx context.Context, tx *sql.Tx, engineID string, from, to *time.Time) error {
+	action := models.AuditDelete
+	query := "UPDATE car SET deleted_at = $2, version = version + 1 WHERE engine_id = $1 AND deleted_at IS NULL"
+	args := []interface{}{engineID, to}
+	if to == nil {
+		action = models.AuditRestore
+		query = "UPDATE car SET deleted_at = NULL, version = version + 1 WHERE engine_id = $1 AND deleted_at = $2"
+		args = []interface{}{engineID, from}
+	}
+
+	rows, err := tx.QueryContext(ctx, query+`
+	RETURNING id, vin, name, year, brand, brand_id, model_id, fuel_type, engine_id, price, currency, created_at, updated_at, version, deleted_at`, args...)
+	if err != nil {
+		return store.MapError(err, "car")
+	}
+	defer rows.Close()
+
+	var cars []models.Car
+	for rows.Next() {
+		var car models.Car
+		err := rows.Scan(&car.ID, &car.VIN, &car.Name, &car.Year, &car.Brand, &car.BrandID, &car.ModelID, &car.FuelType, &car.Engine.EngineID,
+			&car.Price, &car.Currency, &car.CreatedAt, &car.UpdatedAt, &car.Version, &car.DeletedAt)
+		if err != nil {
+			return store.MapError(err, "car")
+		}
+		cars = append(cars, car)
+	}
+	if err = rows.Err(); err != nil {
+		return store.MapError(err, "car")
+	}
+	rows.Close()
+
+	for _, car := range cars {
+		before := car
+		before.Version--
+		before.DeletedAt = from
+		if err := store.Audit(ctx, tx, action, "car", car.ID, before, car); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// versionMismatch tells a missing engine apart from one whose version moved
+// on after an update matched no rows.
+func (e EngineStore) versionMismatch(ctx context.Context, tx *sql.Tx, id uuid.UUID) error {
+	var current int64
+	err := tx.QueryRowContext(ctx, "SELECT version FROM engine WHERE id = $1 AND deleted_at IS NULL", id).Scan(&current)
+	if err != nil {
+		return store.MapError(err, "engine")
+	}
+	return apperrors.PreconditionFailed("engine has been modified, it is now at version " + strconv.FormatInt(current, 10))
+}
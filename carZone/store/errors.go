@@ -0,0 +1,62 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"regexp"
+
+	"github.com/ayushi-khandal09/carZone/apperrors"
+	"github.com/lib/pq"
+)
+
+// Postgres error codes we translate into domain errors.
+const (
+	pqForeignKeyViolation = "23503"
+	pqUniqueViolation     = "23505"
+	pqInvalidText         = "22P02"
+)
+
+// keyDetail matches the column in messages like
+// `Key (engine_id)=(...) is not present in table "engine".`
+var keyDetail = regexp.MustCompile(`^Key \((\w+)\)=`)
+
+// MapError converts database errors into apperrors so the handlers can pick an
+// appropriate status code. entity names the resource in not found messages.
+func MapError(err error, entity string) error {
+	if err == nil {
+		return nil
+	}
+
+	var appErr *apperrors.Error
+	if errors.As(err, &appErr) {
+		return err
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return apperrors.NotFound(entity + " not found")
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case pqForeignKeyViolation:
+			return apperrors.ForeignKey(foreignKeyColumn(pqErr), pqErr.Detail)
+		case pqUniqueViolation:
+			return apperrors.Conflict(entity+" already exists", err)
+		case pqInvalidText:
+			return apperrors.BadRequest("invalid "+entity+" id", err)
+		}
+	}
+
+	return apperrors.Internal(err)
+}
+
+func foreignKeyColumn(pqErr *pq.Error) string {
+	if pqErr.Column != "" {
+		return pqErr.Column
+	}
+	if match := keyDetail.FindStringSubmatch(pqErr.Detail); match != nil {
+		return match[1]
+	}
+	return ""
+}
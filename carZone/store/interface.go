@@ -2,21 +2,108 @@ package store
 
 import (
 	"context"
+	"time"
 
 	"github.com/ayushi-khandal09/carZone/models"
 )
 
 type CarStoreInterface interface {
 	GetCarById(ctx context.Context, id string) (models.Car, error)
+	// GetCarByVIN finds a car by its VIN, which is unique among all cars,
+	// including those in the trash.
+	GetCarByVIN(ctx context.Context, vin string) (models.Car, error)
 	GetCarByBrand(ctx context.Context, brand string, isEngine bool) ([]models.Car, error)
+	ListCars(ctx context.Context, filter models.CarFilter) (models.CarPage, error)
+	SearchCars(ctx context.Context, query string, limit int) ([]models.CarSearchResult, error)
+	// ExportCars calls fn with every car ListCars would return for filter,
+	// without paging. It stops at the first error fn returns.
+	ExportCars(ctx context.Context, filter models.CarFilter, fn func(models.Car) error) error
 	CreateCar(ctx context.Context, carReq *models.CarRequest) (models.Car, error)
-	UpdateCar(ctx context.Context, id string, carReq *models.CarRequest) (models.Car, error)
-	DeleteCar(ctx context.Context, id string) (models.Car, error)
+	// UpdateCar and DeleteCar fail with apperrors.ErrPrecondition when version
+	// is not 0 and the car is at a different version. DeleteCar moves the car
+	// to the trash, which every other read leaves out.
+	UpdateCar(ctx context.Context, id string, carReq *models.CarRequest, version int64) (models.Car, error)
+	DeleteCar(ctx context.Context, id string, version int64) (models.Car, error)
+	// PatchCar updates only the fields set in patch, under the same version
+	// rules as UpdateCar.
+	PatchCar(ctx context.Context, id string, patch models.CarPatch, version int64) (models.Car, error)
+	DeletedCars(ctx context.Context) ([]models.Car, error)
+	RestoreCar(ctx context.Context, id string) (models.Car, error)
+	// PurgeCars permanently removes the cars deleted before the given time
+	// and returns how many there were.
+	PurgeCars(ctx context.Context, before time.Time) (int64, error)
+	// PriceHistory returns the current price of a car and every change that
+	// UpdateCar and PatchCar made to it.
+	PriceHistory(ctx context.Context, id string) (models.PriceHistory, error)
+	// PriceStats aggregates the current prices of the cars matching filter.
+	PriceStats(ctx context.Context, filter models.PriceStatsFilter) ([]models.PriceStats, error)
+	// ImportCars creates the cars of the rows that have no Err, first
+	// creating the engine of rows with NewEngine, and returns the rows with
+	// the Err of those that failed. Nothing is kept in a dry run, or in
+	// all_or_nothing mode once a row failed.
+	ImportCars(ctx context.Context, rows []models.CarImportRow, options models.ImportOptions) ([]models.CarImportRow, error)
+	// BatchCars applies the items that have no Err in order, under the rules
+	// of CreateCar, UpdateCar and DeleteCar, and returns them with the car
+	// each left behind or the Err that stopped it. When atomic is set nothing
+	// is kept once an item failed.
+	BatchCars(ctx context.Context, items []models.CarBatchItem, atomic bool) ([]models.CarBatchItem, error)
 }
 
 type EngineStoreInterface interface {
 	EngineById(ctx context.Context, id string) (models.Engine, error)
 	EngineCreate(ctx context.Context, engineReq *models.EngineRequest) (models.Engine, error)
-	EngineUpdate(ctx context.Context, id string, engineReq *models.EngineRequest) (models.Engine, error)
-	EngineDelete(ctx context.Context, id string) (models.Engine, error)
+	// EngineUpdate and EngineDelete fail with apperrors.ErrPrecondition when
+	// version is not 0 and the engine is at a different version. EngineDelete
+	// moves the engine and its cars to the trash.
+	EngineUpdate(ctx context.Context, id string, engineReq *models.EngineRequest, version int64) (models.Engine, error)
+	EngineDelete(ctx context.Context, id string, version int64) (models.Engine, error)
+	// EnginePatch updates only the fields set in patch, under the same
+	// version rules as EngineUpdate.
+	EnginePatch(ctx context.Context, id string, patch models.EnginePatch, version int64) (models.Engine, error)
+	DeletedEngines(ctx context.Context) ([]models.Engine, error)
+	EngineRestore(ctx context.Context, id string) (models.Engine, error)
+	EnginePurge(ctx context.Context, before time.Time) (int64, error)
+	// EngineImport creates engines under the same rules as ImportCars.
+	EngineImport(ctx context.Context, rows []models.EngineImportRow, options models.ImportOptions) ([]models.EngineImportRow, error)
+}
+
+// CatalogStoreInterface manages the brands and models cars refer to. Brands
+// and models are found by id or by the slug of their name.
+type CatalogStoreInterface interface {
+	ListBrands(ctx context.Context) ([]models.Brand, error)
+	GetBrand(ctx context.Context, ref string) (models.Brand, error)
+	CreateBrand(ctx context.Context, brandReq *models.BrandRequest) (models.Brand, error)
+	// UpdateBrand renames a brand and the brand of its cars.
+	UpdateBrand(ctx context.Context, ref string, brandReq *models.BrandRequest) (models.Brand, error)
+	// DeleteBrand fails with apperrors.ErrConflict while the brand has
+	// models.
+	DeleteBrand(ctx context.Context, ref string) (models.Brand, error)
+	ListCarModels(ctx context.Context, brandRef string) ([]models.CarModel, error)
+	GetCarModel(ctx context.Context, brandRef, ref string) (models.CarModel, error)
+	CreateCarModel(ctx context.Context, brandRef string, modelReq *models.CarModelRequest) (models.CarModel, error)
+	// UpdateCarModel renames a model and the name of its cars.
+	UpdateCarModel(ctx context.Context, brandRef, ref string, modelReq *models.CarModelRequest) (models.CarModel, error)
+	// DeleteCarModel fails with apperrors.ErrConflict while cars, including
+	// those in the trash, are of the model.
+	DeleteCarModel(ctx context.Context, brandRef, ref string) (models.CarModel, error)
+}
+
+// AuditStoreInterface reads the audit log the car, engine and catalog stores
+// write along with every mutation.
+type AuditStoreInterface interface {
+	ListAuditEntries(ctx context.Context, filter models.AuditFilter) (models.AuditPage, error)
+}
+
+type UserStoreInterface interface {
+	GetUserByUsername(ctx context.Context, username string) (models.User, error)
+	CreateUser(ctx context.Context, user *models.User) (models.User, error)
+}
+
+type APIKeyStoreInterface interface {
+	CreateAPIKey(ctx context.Context, key *models.APIKey) (models.APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]models.APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, hash string) (models.APIKey, error)
+	RotateAPIKey(ctx context.Context, id string, prefix string, hash string) (models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id string) (models.APIKey, error)
+	TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error
 }
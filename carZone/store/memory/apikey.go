@@ -0,0 +1,133 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/ayushi-khandal09/carZone/apperrors"
+	"github.com/ayushi-khandal09/carZone/models"
+	"github.com/google/uuid"
+)
+
+type APIKeyStore struct {
+	db *DB
+}
+
+func NewAPIKeyStore(db *DB) *APIKeyStore {
+	return &APIKeyStore{db: db}
+}
+
+func (a *APIKeyStore) CreateAPIKey(ctx context.Context, key *models.APIKey) (models.APIKey, error) {
+	a.db.mu.Lock()
+	defer a.db.mu.Unlock()
+
+	for _, existing := range a.db.apiKeys {
+		if existing.KeyHash == key.KeyHash {
+			return models.APIKey{}, apperrors.Conflict("api key already exists", nil)
+		}
+	}
+
+	createdAt := now()
+	created := models.APIKey{
+		ID:        uuid.New(),
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		KeyHash:   key.KeyHash,
+		Scopes:    append([]string(nil), key.Scopes...),
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+	}
+	a.db.apiKeys[created.ID] = created
+	return created, nil
+}
+
+func (a *APIKeyStore) ListAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	a.db.mu.RLock()
+	defer a.db.mu.RUnlock()
+
+	keys := []models.APIKey{}
+	for _, key := range a.db.apiKeys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+		}
+		return keys[i].ID.String() < keys[j].ID.String()
+	})
+	return keys, nil
+}
+
+func (a *APIKeyStore) GetAPIKeyByHash(ctx context.Context, hash string) (models.APIKey, error) {
+	a.db.mu.RLock()
+	defer a.db.mu.RUnlock()
+
+	for _, key := range a.db.apiKeys {
+		if key.KeyHash == hash {
+			return key, nil
+		}
+	}
+	return models.APIKey{}, apperrors.NotFound("api key not found")
+}
+
+func (a *APIKeyStore) RotateAPIKey(ctx context.Context, id string, prefix string, hash string) (models.APIKey, error) {
+	keyID, err := parseID(id, "api key")
+	if err != nil {
+		return models.APIKey{}, err
+	}
+
+	a.db.mu.Lock()
+	defer a.db.mu.Unlock()
+
+	key, ok := a.db.apiKeys[keyID]
+	if !ok || key.Revoked() {
+		return models.APIKey{}, apperrors.NotFound("active api key not found")
+	}
+	key.Prefix = prefix
+	key.KeyHash = hash
+	key.UpdatedAt = now()
+	a.db.apiKeys[keyID] = key
+	return key, nil
+}
+
+func (a *APIKeyStore) RevokeAPIKey(ctx context.Context, id string) (models.APIKey, error) {
+	keyID, err := parseID(id, "api key")
+	if err != nil {
+		return models.APIKey{}, err
+	}
+
+	a.db.mu.Lock()
+	defer a.db.mu.Unlock()
+
+	key, ok := a.db.apiKeys[keyID]
+	if !ok {
+		return models.APIKey{}, apperrors.NotFound("api key not found")
+	}
+	revokedAt := now()
+	if key.RevokedAt == nil {
+		key.RevokedAt = &revokedAt
+	}
+	key.UpdatedAt = revokedAt
+	a.db.apiKeys[keyID] = key
+	return key, nil
+}
+
+func (a *APIKeyStore) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	keyID, err := parseID(id, "api key")
+	if err != nil {
+		return err
+	}
+
+	a.db.mu.Lock()
+	defer a.db.mu.Unlock()
+
+	key, ok := a.db.apiKeys[keyID]
+	if !ok {
+		return nil
+	}
+	usedAt = usedAt.UTC().Truncate(time.Microsecond)
+	key.LastUsedAt = &usedAt
+	a.db.apiKeys[keyID] = key
+	return nil
+}
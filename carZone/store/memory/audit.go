@@ -0,0 +1,75 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/ayushi-khandal09/carZone/models"
+	"github.com/google/uuid"
+)
+
+type AuditStore struct {
+	db *DB
+}
+
+func NewAuditStore(db *DB) *AuditStore {
+	return &AuditStore{db: db}
+}
+
+func (a *AuditStore) ListAuditEntries(ctx context.Context, filter models.AuditFilter) (models.AuditPage, error) {
+	page := models.AuditPage{Entries: []models.AuditEntry{}}
+
+	cursor, err := models.DecodeAuditCursor(filter)
+	if err != nil {
+		return page, err
+	}
+
+	a.db.mu.RLock()
+	var entries []models.AuditEntry
+	for _, entry := range a.db.audit {
+		if matchesAuditFilter(entry, filter) {
+			entries = append(entries, entry)
+		}
+	}
+	a.db.mu.RUnlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		return newerEntry(entries[i], entries[j].CreatedAt, entries[j].ID.String())
+	})
+	for _, entry := range entries {
+		if cursor != nil && !newerEntry(models.AuditEntry{CreatedAt: cursor.CreatedAt, ID: cursor.ID}, entry.CreatedAt, entry.ID.String()) {
+			continue
+		}
+		page.Entries = append(page.Entries, entry)
+		if len(page.Entries) > filter.Limit {
+			break
+		}
+	}
+	return models.FinishAuditPage(page, filter), nil
+}
+
+func matchesAuditFilter(entry models.AuditEntry, filter models.AuditFilter) bool {
+	switch {
+	case filter.Entity != "" && entry.Entity != filter.Entity:
+		return false
+	case filter.EntityID != uuid.Nil && entry.EntityID != filter.EntityID:
+		return false
+	case filter.Actor != "" && entry.Actor != filter.Actor:
+		return false
+	case !filter.From.IsZero() && entry.CreatedAt.Before(filter.From):
+		return false
+	case !filter.To.IsZero() && !entry.CreatedAt.Before(filter.To):
+		return false
+	}
+	return true
+}
+
+// newerEntry reports whether entry comes before the entry at createdAt and id
+// in the audit log order, newest first.
+func newerEntry(entry models.AuditEntry, createdAt time.Time, id string) bool {
+	if !entry.CreatedAt.Equal(createdAt) {
+		return entry.CreatedAt.After(createdAt)
+	}
+	return entry.ID.String() > id
+}
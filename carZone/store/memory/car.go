@@ -0,0 +1,800 @@
+package memory
+
+import (
+	"context"
+	"html"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/ayushi-khandal09/carZone/apperrors"
+	"github.com/ayushi-khandal09/carZone/models"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+type CarStore struct {
+	db *DB
+}
+
+func NewCarStore(db *DB) *CarStore {
+	return &CarStore{db: db}
+}
+
+func (s *CarStore) GetCarById(ctx context.Context, id string) (models.Car, error) {
+	carID, err := parseID(id, "car")
+	if err != nil {
+		return models.Car{}, err
+	}
+
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	car, ok := s.db.liveCar(carID)
+	if !ok {
+		return models.Car{}, apperrors.NotFound("car not found")
+	}
+	return s.db.withEngine(car), nil
+}
+
+func (s *CarStore) GetCarByVIN(ctx context.Context, vin string) (models.Car, error) {
+	if vin == "" {
+		return models.Car{}, apperrors.NotFound("car not found")
+	}
+
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	for _, car := range s.db.cars {
+		if car.VIN == vin && car.DeletedAt == nil {
+			return s.db.withEngine(car), nil
+		}
+	}
+	return models.Car{}, apperrors.NotFound("car not found")
+}
+
+func (s *CarStore) GetCarByBrand(ctx context.Context, brand string, isEngine bool) ([]models.Car, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	var cars []models.Car
+	for _, car := range s.db.cars {
+		if car.DeletedAt != nil {
+			continue
+		}
+		if !s.db.ofBrand(car, brand) {
+			continue
+		}
+		if isEngine {
+			car = s.db.withEngine(car)
+		}
+		cars = append(cars, car)
+	}
+	sortCars(cars, "created_at", false)
+	return cars, nil
+}
+
+func (s *CarStore) ListCars(ctx context.Context, filter models.CarFilter) (models.CarPage, error) {
+	page := models.CarPage{Cars: []models.Car{}}
+
+	cursor, err := models.DecodeCarCursor(filter)
+	if err != nil {
+		return page, err
+	}
+
+	s.db.mu.RLock()
+	var cars []models.Car
+	for _, car := range s.db.cars {
+		if car.DeletedAt != nil {
+			continue
+		}
+		car = s.db.withEngine(car)
+		if s.db.matchesFilter(car, filter) {
+			cars = append(cars, car)
+		}
+	}
+	s.db.mu.RUnlock()
+
+	page.Total = int64(len(cars))
+	sortCars(cars, filter.SortBy, filter.SortDesc)
+
+	for _, car := range cars {
+		if cursor != nil && !afterCursor(car, cursor) {
+			continue
+		}
+		page.Cars = append(page.Cars, car)
+		if len(page.Cars) > filter.Limit {
+			break
+		}
+	}
+	return models.FinishCarPage(page, filter), nil
+}
+
+// ExportCars calls fn with every car matching filter, in its order. The lock
+// is released before the first call so a slow reader does not block writers.
+func (s *CarStore) ExportCars(ctx context.Context, filter models.CarFilter, fn func(models.Car) error) error {
+	s.db.mu.RLock()
+	var cars []models.Car
+	for _, car := range s.db.cars {
+		if car.DeletedAt != nil {
+			continue
+		}
+		car = s.db.withEngine(car)
+		if s.db.matchesFilter(car, filter) {
+			cars = append(cars, car)
+		}
+	}
+	s.db.mu.RUnlock()
+
+	sortCars(cars, filter.SortBy, filter.SortDesc)
+	for _, car := range cars {
+		if !filter.IsEngine {
+			car.Engine = models.Engine{EngineID: car.Engine.EngineID}
+		}
+		if err := fn(car); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *CarStore) SearchCars(ctx context.Context, query string, limit int) ([]models.CarSearchResult, error) {
+	results := []models.CarSearchResult{}
+
+	terms := models.SearchTerms(query)
+	if len(terms) == 0 {
+		return results, nil
+	}
+
+	s.db.mu.RLock()
+	for _, car := range s.db.cars {
+		if car.DeletedAt != nil {
+			continue
+		}
+		fields := map[string]string{
+			"name":      car.Name,
+			"brand":     car.Brand,
+			"fuel_type": car.FuelType,
+			"year":      car.Year,
+		}
+		var rank float64
+		highlights := map[string]string{}
+		for field, value := range fields {
+			highlighted, score := highlightMatches(value, terms)
+			rank += score
+			if field != "year" {
+				highlights[field] = highlighted
+			}
+		}
+		if rank == 0 {
+			continue
+		}
+		results = append(results, models.CarSearchResult{
+			Car:        s.db.withEngine(car),
+			Rank:       rank / float64(len(terms)),
+			Highlights: models.CarHighlights(highlights),
+		})
+	}
+	s.db.mu.RUnlock()
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Rank != results[j].Rank {
+			return results[i].Rank > results[j].Rank
+		}
+		return results[i].Car.ID.String() < results[j].Car.ID.String()
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+func (s *CarStore) CreateCar(ctx context.Context, carReq *models.CarRequest) (models.Car, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	return s.createCar(ctx, uuid.New(), carReq)
+}
+
+// createCar, updateCar and deleteCar are called with the write lock held.
+func (s *CarStore) createCar(ctx context.Context, id uuid.UUID, carReq *models.CarRequest) (models.Car, error) {
+	if _, ok := s.db.liveEngine(carReq.Engine.EngineID); !ok {
+		return models.Car{}, apperrors.ForeignKey("engine_id", "engine_id does not exists in the engine table")
+	}
+	brand, model, err := s.db.resolveCarModel(carReq.Brand, carReq.Name)
+	if err != nil {
+		return models.Car{}, err
+	}
+	if s.db.vinTaken(carReq.VIN, id) {
+		return models.Car{}, apperrors.Conflict("car already exists", nil)
+	}
+
+	createdAt := now()
+	car := models.Car{
+		ID:        id,
+		VIN:       carReq.VIN,
+		Name:      model.Name,
+		Year:      carReq.Year,
+		Brand:     brand.Name,
+		BrandID:   brand.ID,
+		ModelID:   model.ID,
+		FuelType:  carReq.FuelType,
+		Engine:    models.Engine{EngineID: carReq.Engine.EngineID},
+		Price:     carReq.Price,
+		Currency:  carReq.Currency,
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+		Version:   1,
+	}
+	if err := s.db.record(ctx, models.AuditCreate, "car", car.ID, nil, car); err != nil {
+		return models.Car{}, err
+	}
+	s.db.cars[car.ID] = car
+	return car, nil
+}
+
+func (s *CarStore) UpdateCar(ctx context.Context, id string, carReq *models.CarRequest, version int64) (models.Car, error) {
+	carID, err := parseID(id, "car")
+	if err != nil {
+		return models.Car{}, err
+	}
+
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	return s.updateCar(ctx, carID, carReq, version)
+}
+
+func (s *CarStore) updateCar(ctx context.Context, carID uuid.UUID, carReq *models.CarRequest, version int64) (models.Car, error) {
+	car, ok := s.db.liveCar(carID)
+	if !ok {
+		return models.Car{}, apperrors.NotFound("car not found")
+	}
+	if err := checkVersion("car", car.Version, version); err != nil {
+		return models.Car{}, err
+	}
+	if _, ok := s.db.liveEngine(carReq.Engine.EngineID); !ok {
+		return models.Car{}, apperrors.ForeignKey("engine_id", "engine_id does not exists in the engine table")
+	}
+	brand, model, err := s.db.resolveCarModel(carReq.Brand, carReq.Name)
+	if err != nil {
+		return models.Car{}, err
+	}
+	if s.db.vinTaken(carReq.VIN, carID) {
+		return models.Car{}, apperrors.Conflict("car already exists", nil)
+	}
+
+	before := car
+	car.VIN = carReq.VIN
+	car.Name = model.Name
+	car.Year = carReq.Year
+	car.Brand = brand.Name
+	car.BrandID = brand.ID
+	car.ModelID = model.ID
+	car.FuelType = carReq.FuelType
+	car.Engine = models.Engine{EngineID: carReq.Engine.EngineID}
+	car.Price = carReq.Price
+	car.Currency = carReq.Currency
+	car.UpdatedAt = now()
+	car.Version++
+	if err := s.db.record(ctx, models.AuditUpdate, "car", carID, before, car); err != nil {
+		return models.Car{}, err
+	}
+	s.db.recordPrice(before, car)
+	s.db.cars[carID] = car
+	return car, nil
+}
+
+func (s *CarStore) DeleteCar(ctx context.Context, id string, version int64) (models.Car, error) {
+	carID, err := parseID(id, "car")
+	if err != nil {
+		return models.Car{}, err
+	}
+
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	return s.deleteCar(ctx, carID, version)
+}
+
+func (s *CarStore) deleteCar(ctx context.Context, carID uuid.UUID, version int64) (models.Car, error) {
+	car, ok := s.db.liveCar(carID)
+	if !ok {
+		return models.Car{}, apperrors.NotFound("car not found")
+	}
+	if err := checkVersion("car", car.Version, version); err != nil {
+		return models.Car{}, err
+	}
+	before := car
+	deletedAt := now()
+	car.DeletedAt = &deletedAt
+	car.Version++
+	if err := s.db.record(ctx, models.AuditDelete, "car", carID, before, car); err != nil {
+		return models.Car{}, err
+	}
+	s.db.cars[carID] = car
+	return car, nil
+}
+
+func (s *CarStore) PatchCar(ctx context.Context, id string, patch models.CarPatch, version int64) (models.Car, error) {
+	carID, err := parseID(id, "car")
+	if err != nil {
+		return models.Car{}, err
+	}
+
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	car, ok := s.db.liveCar(carID)
+	if !ok {
+		return models.Car{}, apperrors.NotFound("car not found")
+	}
+	if err := checkVersion("car", car.Version, version); err != nil {
+		return models.Car{}, err
+	}
+	before := car
+	if patch.EngineID != nil {
+		if _, ok := s.db.liveEngine(*patch.EngineID); !ok {
+			return models.Car{}, apperrors.ForeignKey("engine_id", "engine_id does not exists in the engine table")
+		}
+		car.Engine = models.Engine{EngineID: *patch.EngineID}
+	}
+	if patch.VIN != nil {
+		if s.db.vinTaken(*patch.VIN, carID) {
+			return models.Car{}, apperrors.Conflict("car already exists", nil)
+		}
+		car.VIN = *patch.VIN
+	}
+	if patch.Brand != nil || patch.Name != nil {
+		brandRef, modelRef := car.BrandID.String(), car.Name
+		if patch.Brand != nil {
+			brandRef = *patch.Brand
+		}
+		if patch.Name != nil {
+			modelRef = *patch.Name
+		}
+		brand, model, err := s.db.resolveCarModel(brandRef, modelRef)
+		if err != nil {
+			return models.Car{}, err
+		}
+		car.Brand, car.BrandID = brand.Name, brand.ID
+		car.Name, car.ModelID = model.Name, model.ID
+	}
+
+	if patch.Year != nil {
+		car.Year = *patch.Year
+	}
+	if patch.FuelType != nil {
+		car.FuelType = *patch.FuelType
+	}
+	if patch.Price != nil {
+		car.Price = *patch.Price
+	}
+	if patch.Currency != nil {
+		car.Currency = *patch.Currency
+	}
+	car.UpdatedAt = now()
+	car.Version++
+	if err := s.db.record(ctx, models.AuditPatch, "car", carID, before, car); err != nil {
+		return models.Car{}, err
+	}
+	s.db.recordPrice(before, car)
+	s.db.cars[carID] = car
+	return car, nil
+}
+
+func (s *CarStore) DeletedCars(ctx context.Context) ([]models.Car, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	cars := []models.Car{}
+	for _, car := range s.db.cars {
+		if car.DeletedAt != nil {
+			cars = append(cars, car)
+		}
+	}
+	sort.Slice(cars, func(i, j int) bool {
+		if !cars[i].DeletedAt.Equal(*cars[j].DeletedAt) {
+			return cars[i].DeletedAt.After(*cars[j].DeletedAt)
+		}
+		return cars[i].ID.String() < cars[j].ID.String()
+	})
+	return cars, nil
+}
+
+func (s *CarStore) RestoreCar(ctx context.Context, id string) (models.Car, error) {
+	carID, err := parseID(id, "car")
+	if err != nil {
+		return models.Car{}, err
+	}
+
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	car, ok := s.db.cars[carID]
+	if !ok || car.DeletedAt == nil {
+		return models.Car{}, apperrors.NotFound("car not found in trash")
+	}
+	if _, ok := s.db.liveEngine(car.Engine.EngineID); !ok {
+		return models.Car{}, apperrors.ForeignKey("engine_id", "the engine of the car is deleted, restore it first")
+	}
+	before := car
+	car.DeletedAt = nil
+	car.Version++
+	if err := s.db.record(ctx, models.AuditRestore, "car", carID, before, car); err != nil {
+		return models.Car{}, err
+	}
+	s.db.cars[carID] = car
+	return car, nil
+}
+
+func (s *CarStore) PurgeCars(ctx context.Context, before time.Time) (int64, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	var purged int64
+	for carID, car := range s.db.cars {
+		if car.DeletedAt != nil && car.DeletedAt.Before(before) {
+			delete(s.db.cars, carID)
+			delete(s.db.prices, carID)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+func (s *CarStore) PriceHistory(ctx context.Context, id string) (models.PriceHistory, error) {
+	carID, err := parseID(id, "car")
+	if err != nil {
+		return models.PriceHistory{}, err
+	}
+
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	car, ok := s.db.liveCar(carID)
+	if !ok {
+		return models.PriceHistory{}, apperrors.NotFound("car not found")
+	}
+	return models.PriceHistory{
+		CarID:    carID,
+		Price:    car.Price,
+		Currency: car.Currency,
+		Changes:  append([]models.PriceChange{}, s.db.prices[carID]...),
+	}, nil
+}
+
+func (s *CarStore) PriceStats(ctx context.Context, filter models.PriceStatsFilter) ([]models.PriceStats, error) {
+	if err := models.ValidatePriceStatsFilter(filter); err != nil {
+		return nil, err
+	}
+
+	type key struct{ group, currency string }
+	s.db.mu.RLock()
+	groups := map[key]*models.PriceStats{}
+	sums := map[key]decimal.Decimal{}
+	for _, car := range s.db.cars {
+		if car.DeletedAt != nil {
+			continue
+		}
+		if (filter.Brand != "" && !s.db.ofBrand(car, filter.Brand)) || (filter.FuelType != "" && car.FuelType != filter.FuelType) {
+			continue
+		}
+		var group string
+		switch filter.GroupBy {
+		case "brand":
+			group = car.Brand
+		case "fuel_type":
+			group = car.FuelType
+		case "year":
+			group = car.Year
+		}
+		k := key{group, car.Currency}
+		stat, ok := groups[k]
+		if !ok {
+			stat = &models.PriceStats{Group: group, Currency: car.Currency, Min: car.Price, Max: car.Price}
+			groups[k] = stat
+		}
+		stat.Count++
+		stat.Min = decimal.Min(stat.Min, car.Price)
+		stat.Max = decimal.Max(stat.Max, car.Price)
+		sums[k] = sums[k].Add(car.Price)
+	}
+	s.db.mu.RUnlock()
+
+	stats := []models.PriceStats{}
+	for k, stat := range groups {
+		stat.Average = models.RoundPrice(sums[k].Div(decimal.NewFromInt(stat.Count)), stat.Currency)
+		stats = append(stats, *stat)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Group != stats[j].Group {
+			return stats[i].Group < stats[j].Group
+		}
+		return stats[i].Currency < stats[j].Currency
+	})
+	return stats, nil
+}
+
+// ImportCars checks every row before it writes any, so a dry run or a failed
+// all_or_nothing import leaves the database as it was.
+func (s *CarStore) ImportCars(ctx context.Context, rows []models.CarImportRow, options models.ImportOptions) ([]models.CarImportRow, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	failed := false
+	vins := map[string]bool{}
+	for i := range rows {
+		if rows[i].Err == nil && !rows[i].NewEngine {
+			if _, ok := s.db.liveEngine(rows[i].Car.Engine.EngineID); !ok {
+				rows[i].Err = apperrors.ForeignKey("engine_id", "engine_id does not exists in the engine table")
+			}
+		}
+		if rows[i].Err == nil {
+			_, _, rows[i].Err = s.db.resolveCarModel(rows[i].Car.Brand, rows[i].Car.Name)
+		}
+		if vin := rows[i].Car.VIN; rows[i].Err == nil && vin != "" {
+			if vins[vin] || s.db.vinTaken(vin, rows[i].ID) {
+				rows[i].Err = apperrors.Conflict("car already exists", nil)
+			}
+			vins[vin] = true
+		}
+		failed = failed || rows[i].Err != nil
+	}
+	if options.DryRun || (failed && options.Mode == models.ImportAllOrNothing) {
+		return rows, nil
+	}
+
+	createdAt := now()
+	for _, row := range rows {
+		if row.Err != nil {
+			continue
+		}
+		if row.NewEngine {
+			engine := row.Car.Engine
+			engine.Version = 1
+			if err := s.db.record(ctx, models.AuditCreate, "engine", engine.EngineID, nil, engine); err != nil {
+				return nil, err
+			}
+			s.db.engines[engine.EngineID] = engine
+		}
+		brand, model, err := s.db.resolveCarModel(row.Car.Brand, row.Car.Name)
+		if err != nil {
+			return nil, err
+		}
+		car := models.Car{
+			ID:        row.ID,
+			VIN:       row.Car.VIN,
+			Name:      model.Name,
+			Year:      row.Car.Year,
+			Brand:     brand.Name,
+			BrandID:   brand.ID,
+			ModelID:   model.ID,
+			FuelType:  row.Car.FuelType,
+			Engine:    models.Engine{EngineID: row.Car.Engine.EngineID},
+			Price:     row.Car.Price,
+			Currency:  row.Car.Currency,
+			CreatedAt: createdAt,
+			UpdatedAt: createdAt,
+			Version:   1,
+		}
+		if err := s.db.record(ctx, models.AuditCreate, "car", car.ID, nil, car); err != nil {
+			return nil, err
+		}
+		s.db.cars[car.ID] = car
+	}
+	return rows, nil
+}
+
+// BatchCars applies the items one by one under the write lock. What an item
+// changes is remembered, so an atomic batch with a failed item can be undone
+// the way a rolled back transaction would be.
+func (s *CarStore) BatchCars(ctx context.Context, items []models.CarBatchItem, atomic bool) ([]models.CarBatchItem, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	type saved struct {
+		car    models.Car
+		exists bool
+		prices int
+	}
+	undo := map[uuid.UUID]saved{}
+	audit := len(s.db.audit)
+
+	failed := false
+	for i := range items {
+		item := &items[i]
+		if item.Err == nil {
+			if _, ok := undo[item.ID]; !ok {
+				car, exists := s.db.cars[item.ID]
+				undo[item.ID] = saved{car: car, exists: exists, prices: len(s.db.prices[item.ID])}
+			}
+			switch item.Op {
+			case models.BatchCreate:
+				item.Result, item.Err = s.createCar(ctx, item.ID, &item.Car)
+			case models.BatchUpdate:
+				item.Result, item.Err = s.updateCar(ctx, item.ID, &item.Car, item.Version)
+			case models.BatchDelete:
+				item.Result, item.Err = s.deleteCar(ctx, item.ID, item.Version)
+			default:
+				item.Err = apperrors.Validation("op", "op must be create, update or delete")
+			}
+		}
+		failed = failed || item.Err != nil
+	}
+	if !failed || !atomic {
+		return items, nil
+	}
+
+	for id, saved := range undo {
+		if saved.exists {
+			s.db.cars[id] = saved.car
+		} else {
+			delete(s.db.cars, id)
+		}
+		if saved.prices == 0 {
+			delete(s.db.prices, id)
+		} else {
+			s.db.prices[id] = s.db.prices[id][:saved.prices]
+		}
+	}
+	s.db.audit = s.db.audit[:audit]
+	return items, nil
+}
+
+func (db *DB) matchesFilter(car models.Car, filter models.CarFilter) bool {
+	year, _ := strconv.Atoi(car.Year)
+	switch {
+	case filter.Brand != "" && !db.ofBrand(car, filter.Brand):
+		return false
+	case filter.FuelType != "" && car.FuelType != filter.FuelType:
+		return false
+	case filter.YearFrom != 0 && year < filter.YearFrom:
+		return false
+	case filter.YearTo != 0 && year > filter.YearTo:
+		return false
+	case !filter.PriceMin.IsZero() && car.Price.LessThan(filter.PriceMin):
+		return false
+	case !filter.PriceMax.IsZero() && car.Price.GreaterThan(filter.PriceMax):
+		return false
+	case filter.DisplacementMin != 0 && car.Engine.Displacement < filter.DisplacementMin:
+		return false
+	case filter.DisplacementMax != 0 && car.Engine.Displacement > filter.DisplacementMax:
+		return false
+	case filter.Cylinders != 0 && car.Engine.NoOfCyclinders != filter.Cylinders:
+		return false
+	}
+	return true
+}
+
+// sortCars orders cars the way the SQL store does: by the sort field and then
+// by id, both in the same direction.
+func sortCars(cars []models.Car, field string, desc bool) {
+	sort.Slice(cars, func(i, j int) bool {
+		c := compareKeys(field, models.CarSortValue(cars[i], field), models.CarSortValue(cars[j], field))
+		if c == 0 {
+			c = strings.Compare(cars[i].ID.String(), cars[j].ID.String())
+		}
+		if desc {
+			return c > 0
+		}
+		return c < 0
+	})
+}
+
+func afterCursor(car models.Car, cursor *models.CarCursor) bool {
+	c := compareKeys(cursor.SortBy, models.CarSortValue(car, cursor.SortBy), cursor.Value)
+	if c == 0 {
+		c = strings.Compare(car.ID.String(), cursor.ID.String())
+	}
+	if cursor.SortDesc {
+		return c < 0
+	}
+	return c > 0
+}
+
+// compareKeys compares two sort values in their textual cursor form using the
+// type of the sort column.
+func compareKeys(field, a, b string) int {
+	switch field {
+	case "year", "price", "displacement", "no_of_cylinders", "car_range":
+		x, _ := strconv.ParseFloat(a, 64)
+		y, _ := strconv.ParseFloat(b, 64)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	case "created_at", "updated_at":
+		x, _ := time.Parse(time.RFC3339Nano, a)
+		y, _ := time.Parse(time.RFC3339Nano, b)
+		return x.Compare(y)
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+// highlightMatches wraps every word of value that starts with one of terms in
+// highlight markers and escapes the rest as HTML, like models.EscapeHighlight.
+// The score is the number of terms that matched, with near misses of a single
+// typo counting half.
+func highlightMatches(value string, terms []string) (string, float64) {
+	matched := map[string]float64{}
+	var b strings.Builder
+	start := -1
+	flush := func(end int) {
+		word := value[start:end]
+		lower := strings.ToLower(word)
+		hit := false
+		for _, term := range terms {
+			switch {
+			case strings.HasPrefix(lower, term):
+				matched[term] = 1
+				hit = true
+			case len(term) >= 4 && withinOneEdit(lower, term) && matched[term] < 1:
+				matched[term] = 0.5
+				hit = true
+			}
+		}
+		if hit {
+			b.WriteString(models.HighlightStart + word + models.HighlightStop)
+		} else {
+			b.WriteString(word)
+		}
+		start = -1
+	}
+
+	for i, r := range value {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if isWord && start < 0 {
+			start = i
+		}
+		if !isWord {
+			if start >= 0 {
+				flush(i)
+			}
+			b.WriteString(html.EscapeString(string(r)))
+		}
+	}
+	if start >= 0 {
+		flush(len(value))
+	}
+
+	var score float64
+	for _, s := range matched {
+		score += s
+	}
+	return b.String(), score
+}
+
+// withinOneEdit reports whether a and b differ by at most one insertion,
+// deletion or substitution.
+func withinOneEdit(a, b string) bool {
+	if len(a) < len(b) {
+		a, b = b, a
+	}
+	if len(a)-len(b) > 1 {
+		return false
+	}
+	edits := 0
+	for i, j := 0, 0; i < len(a); {
+		if j < len(b) && a[i] == b[j] {
+			i++
+			j++
+			continue
+		}
+		edits++
+		if edits > 1 {
+			return false
+		}
+		if len(a) == len(b) {
+			j++
+		}
+		i++
+	}
+	return true
+}
@@ -0,0 +1,251 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/ayushi-khandal09/carZone/apperrors"
+	"github.com/ayushi-khandal09/carZone/models"
+	"github.com/google/uuid"
+)
+
+type CatalogStore struct {
+	db *DB
+}
+
+func NewCatalogStore(db *DB) *CatalogStore {
+	return &CatalogStore{db: db}
+}
+
+func (c *CatalogStore) ListBrands(ctx context.Context) ([]models.Brand, error) {
+	c.db.mu.RLock()
+	defer c.db.mu.RUnlock()
+
+	brands := []models.Brand{}
+	for _, brand := range c.db.brands {
+		brands = append(brands, brand)
+	}
+	sort.Slice(brands, func(i, j int) bool { return brands[i].Slug < brands[j].Slug })
+	return brands, nil
+}
+
+func (c *CatalogStore) GetBrand(ctx context.Context, ref string) (models.Brand, error) {
+	c.db.mu.RLock()
+	defer c.db.mu.RUnlock()
+
+	brand, ok := c.db.lookupBrand(ref)
+	if !ok {
+		return models.Brand{}, apperrors.NotFound("brand not found")
+	}
+	return brand, nil
+}
+
+func (c *CatalogStore) CreateBrand(ctx context.Context, brandReq *models.BrandRequest) (models.Brand, error) {
+	createdAt := now()
+	brand := models.Brand{
+		ID:        uuid.New(),
+		Name:      brandReq.Name,
+		Slug:      models.Slug(brandReq.Name),
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+	}
+
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	if _, ok := c.db.lookupBrand(brand.Slug); ok {
+		return models.Brand{}, apperrors.Conflict("brand already exists", nil)
+	}
+	if err := c.db.record(ctx, models.AuditCreate, "brand", brand.ID, nil, brand); err != nil {
+		return models.Brand{}, err
+	}
+	c.db.brands[brand.ID] = brand
+	return brand, nil
+}
+
+func (c *CatalogStore) UpdateBrand(ctx context.Context, ref string, brandReq *models.BrandRequest) (models.Brand, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	before, ok := c.db.lookupBrand(ref)
+	if !ok {
+		return models.Brand{}, apperrors.NotFound("brand not found")
+	}
+	brand := before
+	brand.Name = brandReq.Name
+	brand.Slug = models.Slug(brandReq.Name)
+	brand.UpdatedAt = now()
+	if other, ok := c.db.lookupBrand(brand.Slug); ok && other.ID != brand.ID {
+		return models.Brand{}, apperrors.Conflict("brand already exists", nil)
+	}
+	if err := c.db.record(ctx, models.AuditUpdate, "brand", brand.ID, before, brand); err != nil {
+		return models.Brand{}, err
+	}
+	err := c.renameCars(ctx, func(car *models.Car) bool {
+		if car.BrandID != brand.ID || car.Brand == brand.Name {
+			return false
+		}
+		car.Brand = brand.Name
+		return true
+	})
+	if err != nil {
+		return models.Brand{}, err
+	}
+	c.db.brands[brand.ID] = brand
+	return brand, nil
+}
+
+func (c *CatalogStore) DeleteBrand(ctx context.Context, ref string) (models.Brand, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	brand, ok := c.db.lookupBrand(ref)
+	if !ok {
+		return models.Brand{}, apperrors.NotFound("brand not found")
+	}
+	for _, model := range c.db.carModels {
+		if model.BrandID == brand.ID {
+			return models.Brand{}, apperrors.Conflict("brand still has models, delete them first", nil)
+		}
+	}
+	if err := c.db.record(ctx, models.AuditDelete, "brand", brand.ID, brand, nil); err != nil {
+		return models.Brand{}, err
+	}
+	delete(c.db.brands, brand.ID)
+	return brand, nil
+}
+
+func (c *CatalogStore) ListCarModels(ctx context.Context, brandRef string) ([]models.CarModel, error) {
+	c.db.mu.RLock()
+	defer c.db.mu.RUnlock()
+
+	brand, ok := c.db.lookupBrand(brandRef)
+	if !ok {
+		return nil, apperrors.NotFound("brand not found")
+	}
+	carModels := []models.CarModel{}
+	for _, model := range c.db.carModels {
+		if model.BrandID == brand.ID {
+			carModels = append(carModels, model)
+		}
+	}
+	sort.Slice(carModels, func(i, j int) bool { return carModels[i].Slug < carModels[j].Slug })
+	return carModels, nil
+}
+
+func (c *CatalogStore) GetCarModel(ctx context.Context, brandRef, ref string) (models.CarModel, error) {
+	c.db.mu.RLock()
+	defer c.db.mu.RUnlock()
+
+	return c.lookupModel(brandRef, ref)
+}
+
+func (c *CatalogStore) CreateCarModel(ctx context.Context, brandRef string, modelReq *models.CarModelRequest) (models.CarModel, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	brand, ok := c.db.lookupBrand(brandRef)
+	if !ok {
+		return models.CarModel{}, apperrors.NotFound("brand not found")
+	}
+	createdAt := now()
+	model := models.CarModel{
+		ID:        uuid.New(),
+		BrandID:   brand.ID,
+		Name:      modelReq.Name,
+		Slug:      models.Slug(modelReq.Name),
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+	}
+	if _, ok := c.db.lookupCarModel(brand.ID, model.Slug); ok {
+		return models.CarModel{}, apperrors.Conflict("model already exists", nil)
+	}
+	if err := c.db.record(ctx, models.AuditCreate, "car_model", model.ID, nil, model); err != nil {
+		return models.CarModel{}, err
+	}
+	c.db.carModels[model.ID] = model
+	return model, nil
+}
+
+func (c *CatalogStore) UpdateCarModel(ctx context.Context, brandRef, ref string, modelReq *models.CarModelRequest) (models.CarModel, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	before, err := c.lookupModel(brandRef, ref)
+	if err != nil {
+		return models.CarModel{}, err
+	}
+	model := before
+	model.Name = modelReq.Name
+	model.Slug = models.Slug(modelReq.Name)
+	model.UpdatedAt = now()
+	if other, ok := c.db.lookupCarModel(model.BrandID, model.Slug); ok && other.ID != model.ID {
+		return models.CarModel{}, apperrors.Conflict("model already exists", nil)
+	}
+	if err := c.db.record(ctx, models.AuditUpdate, "car_model", model.ID, before, model); err != nil {
+		return models.CarModel{}, err
+	}
+	err = c.renameCars(ctx, func(car *models.Car) bool {
+		if car.ModelID != model.ID || car.Name == model.Name {
+			return false
+		}
+		car.Name = model.Name
+		return true
+	})
+	if err != nil {
+		return models.CarModel{}, err
+	}
+	c.db.carModels[model.ID] = model
+	return model, nil
+}
+
+// DeleteCarModel refuses to delete a model while any car, in the trash or
+// not, is of it, like the foreign key on car.model_id.
+func (c *CatalogStore) DeleteCarModel(ctx context.Context, brandRef, ref string) (models.CarModel, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	model, err := c.lookupModel(brandRef, ref)
+	if err != nil {
+		return models.CarModel{}, err
+	}
+	for _, car := range c.db.cars {
+		if car.ModelID == model.ID {
+			return models.CarModel{}, apperrors.Conflict("model still has cars, delete and purge them first", nil)
+		}
+	}
+	if err := c.db.record(ctx, models.AuditDelete, "car_model", model.ID, model, nil); err != nil {
+		return models.CarModel{}, err
+	}
+	delete(c.db.carModels, model.ID)
+	return model, nil
+}
+
+func (c *CatalogStore) lookupModel(brandRef, ref string) (models.CarModel, error) {
+	brand, ok := c.db.lookupBrand(brandRef)
+	if !ok {
+		return models.CarModel{}, apperrors.NotFound("brand not found")
+	}
+	model, ok := c.db.lookupCarModel(brand.ID, ref)
+	if !ok {
+		return models.CarModel{}, apperrors.NotFound("model not found")
+	}
+	return model, nil
+}
+
+// renameCars applies rename to every car, in the trash or not, and audits
+// those it changed.
+func (c *CatalogStore) renameCars(ctx context.Context, rename func(car *models.Car) bool) error {
+	for carID, car := range c.db.cars {
+		before := car
+		if !rename(&car) {
+			continue
+		}
+		car.Version++
+		if err := c.db.record(ctx, models.AuditUpdate, "car", carID, before, car); err != nil {
+			return err
+		}
+		c.db.cars[carID] = car
+	}
+	return nil
+}
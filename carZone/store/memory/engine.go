@@ -0,0 +1,269 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/ayushi-khandal09/carZone/apperrors"
+	"github.com/ayushi-khandal09/carZone/models"
+	"github.com/google/uuid"
+)
+
+type EngineStore struct {
+	db *DB
+}
+
+func NewEngineStore(db *DB) *EngineStore {
+	return &EngineStore{db: db}
+}
+
+func (e *EngineStore) EngineById(ctx context.Context, id string) (models.Engine, error) {
+	engineID, err := parseID(id, "engine")
+	if err != nil {
+		return models.Engine{}, err
+	}
+
+	e.db.mu.RLock()
+	defer e.db.mu.RUnlock()
+
+	engine, ok := e.db.liveEngine(engineID)
+	if !ok {
+		return models.Engine{}, apperrors.NotFound("engine not found")
+	}
+	return engine, nil
+}
+
+func (e *EngineStore) EngineCreate(ctx context.Context, engineReq *models.EngineRequest) (models.Engine, error) {
+	engine := models.Engine{
+		EngineID:       uuid.New(),
+		Displacement:   engineReq.Displacement,
+		NoOfCyclinders: engineReq.NoOfCyclinders,
+		CarRange:       engineReq.CarRange,
+		Version:        1,
+	}
+
+	e.db.mu.Lock()
+	defer e.db.mu.Unlock()
+
+	if err := e.db.record(ctx, models.AuditCreate, "engine", engine.EngineID, nil, engine); err != nil {
+		return models.Engine{}, err
+	}
+	e.db.engines[engine.EngineID] = engine
+	return engine, nil
+}
+
+func (e *EngineStore) EngineUpdate(ctx context.Context, id string, engineReq *models.EngineRequest, version int64) (models.Engine, error) {
+	engineID, err := parseID(id, "engine")
+	if err != nil {
+		return models.Engine{}, err
+	}
+
+	e.db.mu.Lock()
+	defer e.db.mu.Unlock()
+
+	current, ok := e.db.liveEngine(engineID)
+	if !ok {
+		return models.Engine{}, apperrors.NotFound("engine not found")
+	}
+	if err := checkVersion("engine", current.Version, version); err != nil {
+		return models.Engine{}, err
+	}
+	engine := models.Engine{
+		EngineID:       engineID,
+		Displacement:   engineReq.Displacement,
+		NoOfCyclinders: engineReq.NoOfCyclinders,
+		CarRange:       engineReq.CarRange,
+		Version:        current.Version + 1,
+	}
+	if err := e.db.record(ctx, models.AuditUpdate, "engine", engineID, current, engine); err != nil {
+		return models.Engine{}, err
+	}
+	e.db.engines[engineID] = engine
+	return engine, nil
+}
+
+// EngineDelete moves the engine and, like the ON DELETE CASCADE foreign key
+// in Postgres, every car that uses it to the trash.
+func (e *EngineStore) EngineDelete(ctx context.Context, id string, version int64) (models.Engine, error) {
+	engineID, err := parseID(id, "engine")
+	if err != nil {
+		return models.Engine{}, err
+	}
+
+	e.db.mu.Lock()
+	defer e.db.mu.Unlock()
+
+	engine, ok := e.db.liveEngine(engineID)
+	if !ok {
+		return models.Engine{}, apperrors.NotFound("engine not found")
+	}
+	if err := checkVersion("engine", engine.Version, version); err != nil {
+		return models.Engine{}, err
+	}
+	deletedAt := now()
+	for carID, car := range e.db.cars {
+		if car.Engine.EngineID == engineID && car.DeletedAt == nil {
+			before := car
+			car.DeletedAt = &deletedAt
+			car.Version++
+			if err := e.db.record(ctx, models.AuditDelete, "car", carID, before, car); err != nil {
+				return models.Engine{}, err
+			}
+			e.db.cars[carID] = car
+		}
+	}
+	before := engine
+	engine.DeletedAt = &deletedAt
+	engine.Version++
+	if err := e.db.record(ctx, models.AuditDelete, "engine", engineID, before, engine); err != nil {
+		return models.Engine{}, err
+	}
+	e.db.engines[engineID] = engine
+	return engine, nil
+}
+
+func (e *EngineStore) EnginePatch(ctx context.Context, id string, patch models.EnginePatch, version int64) (models.Engine, error) {
+	engineID, err := parseID(id, "engine")
+	if err != nil {
+		return models.Engine{}, err
+	}
+
+	e.db.mu.Lock()
+	defer e.db.mu.Unlock()
+
+	engine, ok := e.db.liveEngine(engineID)
+	if !ok {
+		return models.Engine{}, apperrors.NotFound("engine not found")
+	}
+	if err := checkVersion("engine", engine.Version, version); err != nil {
+		return models.Engine{}, err
+	}
+	before := engine
+	if patch.Displacement != nil {
+		engine.Displacement = *patch.Displacement
+	}
+	if patch.NoOfCyclinders != nil {
+		engine.NoOfCyclinders = *patch.NoOfCyclinders
+	}
+	if patch.CarRange != nil {
+		engine.CarRange = *patch.CarRange
+	}
+	engine.Version++
+	if err := e.db.record(ctx, models.AuditPatch, "engine", engineID, before, engine); err != nil {
+		return models.Engine{}, err
+	}
+	e.db.engines[engineID] = engine
+	return engine, nil
+}
+
+func (e *EngineStore) DeletedEngines(ctx context.Context) ([]models.Engine, error) {
+	e.db.mu.RLock()
+	defer e.db.mu.RUnlock()
+
+	engines := []models.Engine{}
+	for _, engine := range e.db.engines {
+		if engine.DeletedAt != nil {
+			engines = append(engines, engine)
+		}
+	}
+	sort.Slice(engines, func(i, j int) bool {
+		if !engines[i].DeletedAt.Equal(*engines[j].DeletedAt) {
+			return engines[i].DeletedAt.After(*engines[j].DeletedAt)
+		}
+		return engines[i].EngineID.String() < engines[j].EngineID.String()
+	})
+	return engines, nil
+}
+
+// EngineRestore takes the engine out of the trash along with the cars that
+// were deleted with it.
+func (e *EngineStore) EngineRestore(ctx context.Context, id string) (models.Engine, error) {
+	engineID, err := parseID(id, "engine")
+	if err != nil {
+		return models.Engine{}, err
+	}
+
+	e.db.mu.Lock()
+	defer e.db.mu.Unlock()
+
+	engine, ok := e.db.engines[engineID]
+	if !ok || engine.DeletedAt == nil {
+		return models.Engine{}, apperrors.NotFound("engine not found in trash")
+	}
+	for carID, car := range e.db.cars {
+		if car.Engine.EngineID == engineID && car.DeletedAt != nil && car.DeletedAt.Equal(*engine.DeletedAt) {
+			before := car
+			car.DeletedAt = nil
+			car.Version++
+			if err := e.db.record(ctx, models.AuditRestore, "car", carID, before, car); err != nil {
+				return models.Engine{}, err
+			}
+			e.db.cars[carID] = car
+		}
+	}
+	before := engine
+	engine.DeletedAt = nil
+	engine.Version++
+	if err := e.db.record(ctx, models.AuditRestore, "engine", engineID, before, engine); err != nil {
+		return models.Engine{}, err
+	}
+	e.db.engines[engineID] = engine
+	return engine, nil
+}
+
+// EnginePurge removes the engines deleted before the given time together with
+// their cars.
+func (e *EngineStore) EnginePurge(ctx context.Context, before time.Time) (int64, error) {
+	e.db.mu.Lock()
+	defer e.db.mu.Unlock()
+
+	var purged int64
+	for engineID, engine := range e.db.engines {
+		if engine.DeletedAt == nil || !engine.DeletedAt.Before(before) {
+			continue
+		}
+		delete(e.db.engines, engineID)
+		for carID, car := range e.db.cars {
+			if car.Engine.EngineID == engineID {
+				delete(e.db.cars, carID)
+				delete(e.db.prices, carID)
+			}
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// EngineImport writes the rows that have no Err, unless it is a dry run or an
+// all_or_nothing import with a failed row.
+func (e *EngineStore) EngineImport(ctx context.Context, rows []models.EngineImportRow, options models.ImportOptions) ([]models.EngineImportRow, error) {
+	failed := false
+	for _, row := range rows {
+		failed = failed || row.Err != nil
+	}
+	if options.DryRun || (failed && options.Mode == models.ImportAllOrNothing) {
+		return rows, nil
+	}
+
+	e.db.mu.Lock()
+	defer e.db.mu.Unlock()
+
+	for _, row := range rows {
+		if row.Err != nil {
+			continue
+		}
+		engine := models.Engine{
+			EngineID:       row.ID,
+			Displacement:   row.Engine.Displacement,
+			NoOfCyclinders: row.Engine.NoOfCyclinders,
+			CarRange:       row.Engine.CarRange,
+			Version:        1,
+		}
+		if err := e.db.record(ctx, models.AuditCreate, "engine", engine.EngineID, nil, engine); err != nil {
+			return nil, err
+		}
+		e.db.engines[engine.EngineID] = engine
+	}
+	return rows, nil
+}
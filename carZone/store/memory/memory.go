@@ -0,0 +1,166 @@
+// Package memory implements the store interfaces on top of in-process maps.
+// It mirrors the behaviour of the Postgres stores, including the engine
+// foreign key on cars, and is safe for concurrent use.
+package memory
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ayushi-khandal09/carZone/apperrors"
+	"github.com/ayushi-khandal09/carZone/models"
+	"github.com/ayushi-khandal09/carZone/store"
+	"github.com/google/uuid"
+)
+
+// DB holds the rows shared by the stores.
+type DB struct {
+	mu        sync.RWMutex
+	cars      map[uuid.UUID]models.Car
+	engines   map[uuid.UUID]models.Engine
+	brands    map[uuid.UUID]models.Brand
+	carModels map[uuid.UUID]models.CarModel
+	users     map[string]models.User
+	apiKeys   map[uuid.UUID]models.APIKey
+	audit     []models.AuditEntry
+	prices    map[uuid.UUID][]models.PriceChange
+}
+
+func NewDB() *DB {
+	return &DB{
+		cars:      map[uuid.UUID]models.Car{},
+		engines:   map[uuid.UUID]models.Engine{},
+		brands:    map[uuid.UUID]models.Brand{},
+		carModels: map[uuid.UUID]models.CarModel{},
+		users:     map[string]models.User{},
+		apiKeys:   map[uuid.UUID]models.APIKey{},
+		prices:    map[uuid.UUID][]models.PriceChange{},
+	}
+}
+
+// now returns the current time at the microsecond precision Postgres keeps.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+func parseID(id, entity string) (uuid.UUID, error) {
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, apperrors.BadRequest("invalid "+entity+" id", err)
+	}
+	return parsed, nil
+}
+
+// withEngine returns car joined with its engine row.
+func (db *DB) withEngine(car models.Car) models.Car {
+	if engine, ok := db.engines[car.Engine.EngineID]; ok {
+		car.Engine = engine
+	}
+	return car
+}
+
+// liveCar returns the car with the given id unless it is in the trash.
+func (db *DB) liveCar(id uuid.UUID) (models.Car, bool) {
+	car, ok := db.cars[id]
+	return car, ok && car.DeletedAt == nil
+}
+
+// vinTaken reports whether another car than the one with the given id has
+// vin, like the unique index on car.vin does. Cars without a VIN never clash.
+func (db *DB) vinTaken(vin string, id uuid.UUID) bool {
+	if vin == "" {
+		return false
+	}
+	for _, car := range db.cars {
+		if car.VIN == vin && car.ID != id {
+			return true
+		}
+	}
+	return false
+}
+
+// liveEngine returns the engine with the given id unless it is in the trash.
+func (db *DB) liveEngine(id uuid.UUID) (models.Engine, bool) {
+	engine, ok := db.engines[id]
+	return engine, ok && engine.DeletedAt == nil
+}
+
+// lookupBrand finds the brand ref refers to, by id or by slug, like
+// store.LookupBrand.
+func (db *DB) lookupBrand(ref string) (models.Brand, bool) {
+	slug := models.Slug(ref)
+	for _, brand := range db.brands {
+		if brand.Slug == slug || brand.ID.String() == slug {
+			return brand, true
+		}
+	}
+	return models.Brand{}, false
+}
+
+// lookupCarModel finds the model of a brand ref refers to.
+func (db *DB) lookupCarModel(brandID uuid.UUID, ref string) (models.CarModel, bool) {
+	slug := models.Slug(ref)
+	for _, model := range db.carModels {
+		if model.BrandID == brandID && (model.Slug == slug || model.ID.String() == slug) {
+			return model, true
+		}
+	}
+	return models.CarModel{}, false
+}
+
+// resolveCarModel finds the brand and model a car refers to, like
+// store.ResolveCarModel.
+func (db *DB) resolveCarModel(brandRef, modelRef string) (models.Brand, models.CarModel, error) {
+	brand, ok := db.lookupBrand(brandRef)
+	if !ok {
+		return brand, models.CarModel{}, store.UnknownBrand(brandRef)
+	}
+	model, ok := db.lookupCarModel(brand.ID, modelRef)
+	if !ok {
+		return brand, model, store.UnknownCarModel(brand, modelRef)
+	}
+	return brand, model, nil
+}
+
+// ofBrand reports whether car belongs to the brand ref refers to.
+func (db *DB) ofBrand(car models.Car, ref string) bool {
+	brand, ok := db.lookupBrand(ref)
+	return ok && car.BrandID == brand.ID
+}
+
+// checkVersion fails with a precondition error when want is not 0 and the row
+// is at a different version.
+func checkVersion(entity string, current, want int64) error {
+	if want != 0 && current != want {
+		return apperrors.PreconditionFailed(entity + " has been modified, it is now at version " + strconv.FormatInt(current, 10))
+	}
+	return nil
+}
+
+// recordPrice adds an entry to the price history of the car when an update
+// changed its price or currency.
+func (db *DB) recordPrice(before, after models.Car) {
+	if before.Price.Equal(after.Price) && before.Currency == after.Currency {
+		return
+	}
+	db.prices[after.ID] = append(db.prices[after.ID], models.PriceChange{
+		OldPrice:    before.Price,
+		OldCurrency: before.Currency,
+		NewPrice:    after.Price,
+		NewCurrency: after.Currency,
+		ChangedAt:   after.UpdatedAt,
+	})
+}
+
+// record appends an entry for a mutation to the audit log. Callers hold the
+// write lock, so the entry is written together with the mutation.
+func (db *DB) record(ctx context.Context, action, entity string, id uuid.UUID, before, after interface{}) error {
+	entry, err := store.NewAuditEntry(ctx, action, entity, id, before, after)
+	if err != nil {
+		return err
+	}
+	db.audit = append(db.audit, entry)
+	return nil
+}
@@ -0,0 +1,22 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/ayushi-khandal09/carZone/store/memory"
+	"github.com/ayushi-khandal09/carZone/store/storetest"
+)
+
+func TestConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) storetest.Stores {
+		db := memory.NewDB()
+		return storetest.Stores{
+			Cars:    memory.NewCarStore(db),
+			Engines: memory.NewEngineStore(db),
+			Catalog: memory.NewCatalogStore(db),
+			Users:   memory.NewUserStore(db),
+			APIKeys: memory.NewAPIKeyStore(db),
+			Audit:   memory.NewAuditStore(db),
+		}
+	})
+}
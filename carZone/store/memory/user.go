@@ -0,0 +1,51 @@
+package memory
+
+import (
+	"context"
+	"strings"
+
+	"github.com/ayushi-khandal09/carZone/apperrors"
+	"github.com/ayushi-khandal09/carZone/models"
+	"github.com/google/uuid"
+)
+
+type UserStore struct {
+	db *DB
+}
+
+func NewUserStore(db *DB) *UserStore {
+	return &UserStore{db: db}
+}
+
+func (u *UserStore) GetUserByUsername(ctx context.Context, username string) (models.User, error) {
+	u.db.mu.RLock()
+	defer u.db.mu.RUnlock()
+
+	user, ok := u.db.users[strings.ToLower(username)]
+	if !ok {
+		return models.User{}, apperrors.NotFound("user not found")
+	}
+	return user, nil
+}
+
+func (u *UserStore) CreateUser(ctx context.Context, user *models.User) (models.User, error) {
+	u.db.mu.Lock()
+	defer u.db.mu.Unlock()
+
+	key := strings.ToLower(user.Username)
+	if _, ok := u.db.users[key]; ok {
+		return models.User{}, apperrors.Conflict("user already exists", nil)
+	}
+
+	createdAt := now()
+	created := models.User{
+		ID:           uuid.New(),
+		Username:     user.Username,
+		PasswordHash: user.PasswordHash,
+		Role:         user.Role,
+		CreatedAt:    createdAt,
+		UpdatedAt:    createdAt,
+	}
+	u.db.users[key] = created
+	return created, nil
+}
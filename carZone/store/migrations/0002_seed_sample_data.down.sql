@@ -0,0 +1,13 @@
+DELETE FROM car WHERE id IN (
+    'c7c1a6d5-1ec4-4c64-a59a-8a2f6f3d2bf3',
+    '9d6a56f8-79c3-4931-a5c0-6b290c84ba2f',
+    '9b9437c4-3ed1-45a5-b240-0fe3e24e0e4e',
+    '5e9df51a-8d7a-4d84-9c58-4ccfe5c7db06'
+);
+
+DELETE FROM engine WHERE id IN (
+    'e1f86b1a-0873-4c19-bae2-fc60329d0140',
+    'f4a9c66b-8e38-419b-93c4-215d5cefb318',
+    'cc2c2a7d-2e21-4f59-b7b8-bd9e5e4cf04c',
+    '9746be12-07b7-42a3-b8ab-7d1f209b63d7'
+);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_car_search_trgm;
+DROP INDEX IF EXISTS idx_car_search_vector;
+ALTER TABLE car DROP COLUMN IF EXISTS search_vector;
@@ -0,0 +1,11 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE car ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        to_tsvector('simple', name || ' ' || brand || ' ' || fuel_type || ' ' || year)
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_car_search_vector ON car USING GIN (search_vector);
+
+CREATE INDEX IF NOT EXISTS idx_car_search_trgm ON car
+    USING GIN ((name || ' ' || brand || ' ' || fuel_type) gin_trgm_ops);
@@ -0,0 +1,2 @@
+ALTER TABLE engine ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE car ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
@@ -0,0 +1,5 @@
+ALTER TABLE engine ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE car ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_engine_deleted_at ON engine (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_car_deleted_at ON car (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+    id UUID PRIMARY KEY,
+    actor VARCHAR(255) NOT NULL,
+    actor_name VARCHAR(255) NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    entity VARCHAR(16) NOT NULL,
+    entity_id UUID NOT NULL,
+    before JSONB,
+    after JSONB,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- No foreign keys: entries outlive the rows they describe once those are
+-- purged from the trash.
+CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log (entity, entity_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log (created_at DESC, id DESC);
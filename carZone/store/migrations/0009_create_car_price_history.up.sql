@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS car_price_history (
+    id UUID PRIMARY KEY,
+    car_id UUID NOT NULL,
+    old_price DECIMAL(10, 2) NOT NULL,
+    new_price DECIMAL(10, 2) NOT NULL,
+    changed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_car_id FOREIGN KEY (car_id) REFERENCES car(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_car_price_history_car ON car_price_history (car_id, changed_at);
@@ -0,0 +1,8 @@
+ALTER TABLE car_price_history
+    DROP COLUMN IF EXISTS new_currency,
+    DROP COLUMN IF EXISTS old_currency,
+    ALTER COLUMN new_price TYPE DECIMAL(10, 2),
+    ALTER COLUMN old_price TYPE DECIMAL(10, 2);
+
+ALTER TABLE car DROP COLUMN IF EXISTS currency;
+ALTER TABLE car ALTER COLUMN price TYPE DECIMAL(10, 2);
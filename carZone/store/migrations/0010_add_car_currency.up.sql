@@ -0,0 +1,10 @@
+-- Prices are exact amounts in the currency of the car. Three decimal places
+-- fit currencies such as KWD, existing prices were in US dollars.
+ALTER TABLE car ALTER COLUMN price TYPE NUMERIC(13, 3);
+ALTER TABLE car ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'USD';
+
+ALTER TABLE car_price_history
+    ALTER COLUMN old_price TYPE NUMERIC(13, 3),
+    ALTER COLUMN new_price TYPE NUMERIC(13, 3),
+    ADD COLUMN IF NOT EXISTS old_currency CHAR(3) NOT NULL DEFAULT 'USD',
+    ADD COLUMN IF NOT EXISTS new_currency CHAR(3) NOT NULL DEFAULT 'USD';
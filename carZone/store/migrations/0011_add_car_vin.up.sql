@@ -0,0 +1,4 @@
+-- Cars listed before VINs were recorded have an empty one, which the unique
+-- index leaves out.
+ALTER TABLE car ADD COLUMN IF NOT EXISTS vin VARCHAR(17) NOT NULL DEFAULT '';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_car_vin ON car (vin) WHERE vin <> '';
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_car_model_id;
+DROP INDEX IF EXISTS idx_car_brand_id;
+ALTER TABLE car DROP COLUMN IF EXISTS model_id, DROP COLUMN IF EXISTS brand_id;
+DROP TABLE IF EXISTS car_model;
+DROP TABLE IF EXISTS brand;
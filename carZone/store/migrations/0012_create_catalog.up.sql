@@ -0,0 +1,65 @@
+-- Brands and models used to be free text on every car, which made "Toyota",
+-- "toyota" and "TOYOTA " three brands. The catalog keeps one row per slug
+-- and cars reference it, with brand and name holding the catalog names.
+CREATE TABLE IF NOT EXISTS brand (
+    id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    slug VARCHAR(255) NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS car_model (
+    id UUID PRIMARY KEY,
+    brand_id UUID NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    slug VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_car_model_brand_id FOREIGN KEY (brand_id) REFERENCES brand(id),
+    CONSTRAINT uq_car_model_brand_slug UNIQUE (brand_id, slug)
+);
+
+ALTER TABLE car
+    ADD COLUMN IF NOT EXISTS brand_id UUID,
+    ADD COLUMN IF NOT EXISTS model_id UUID,
+    ADD COLUMN brand_slug TEXT,
+    ADD COLUMN model_slug TEXT;
+
+-- Slugs are made like models.Slug does. Names without a letter or digit
+-- end up as "unknown".
+UPDATE car SET
+    brand_slug = COALESCE(NULLIF(trim(both '-' from regexp_replace(lower(brand), '[^[:alnum:]]+', '-', 'g')), ''), 'unknown'),
+    model_slug = COALESCE(NULLIF(trim(both '-' from regexp_replace(lower(name), '[^[:alnum:]]+', '-', 'g')), ''), 'unknown');
+
+-- Every slug becomes a catalog entry named after its most common spelling.
+-- The ids are derived from the slugs so the cars can be pointed at them.
+INSERT INTO brand (id, name, slug)
+SELECT md5('brand/' || brand_slug)::uuid, mode() WITHIN GROUP (ORDER BY trim(brand)), brand_slug
+FROM car
+GROUP BY brand_slug;
+
+INSERT INTO car_model (id, brand_id, name, slug)
+SELECT md5('car_model/' || brand_slug || '/' || model_slug)::uuid, md5('brand/' || brand_slug)::uuid,
+    mode() WITHIN GROUP (ORDER BY trim(name)), model_slug
+FROM car
+GROUP BY brand_slug, model_slug;
+
+UPDATE car SET
+    brand_id = md5('brand/' || brand_slug)::uuid,
+    model_id = md5('car_model/' || brand_slug || '/' || model_slug)::uuid;
+
+UPDATE car SET brand = b.name, name = m.name, version = car.version + 1
+FROM brand b, car_model m
+WHERE b.id = car.brand_id AND m.id = car.model_id AND (car.brand <> b.name OR car.name <> m.name);
+
+ALTER TABLE car
+    DROP COLUMN brand_slug,
+    DROP COLUMN model_slug,
+    ALTER COLUMN brand_id SET NOT NULL,
+    ALTER COLUMN model_id SET NOT NULL,
+    ADD CONSTRAINT fk_car_brand_id FOREIGN KEY (brand_id) REFERENCES brand(id),
+    ADD CONSTRAINT fk_car_model_id FOREIGN KEY (model_id) REFERENCES car_model(id);
+
+CREATE INDEX IF NOT EXISTS idx_car_brand_id ON car (brand_id);
+CREATE INDEX IF NOT EXISTS idx_car_model_id ON car (model_id);
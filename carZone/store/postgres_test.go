@@ -0,0 +1,95 @@
+package store_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/ayushi-khandal09/carZone/migrate"
+	apiKeyStore "github.com/ayushi-khandal09/carZone/store/apikey"
+	auditStore "github.com/ayushi-khandal09/carZone/store/audit"
+	carStore "github.com/ayushi-khandal09/carZone/store/car"
+	catalogStore "github.com/ayushi-khandal09/carZone/store/catalog"
+	engineStore "github.com/ayushi-khandal09/carZone/store/engine"
+	"github.com/ayushi-khandal09/carZone/store/migrations"
+	"github.com/ayushi-khandal09/carZone/store/storetest"
+	userStore "github.com/ayushi-khandal09/carZone/store/user"
+	_ "github.com/lib/pq"
+)
+
+// TestPostgresConformance runs the store conformance suite against the
+// database described by the DB_* environment variables. Every test starts
+// from empty tables, so do not point it at a database you care about.
+func TestPostgresConformance(t *testing.T) {
+	if os.Getenv("DB_HOST") == "" {
+		t.Skip("DB_HOST is not set, skipping Postgres conformance tests")
+	}
+
+	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		os.Getenv("DB_HOST"),
+		os.Getenv("DB_PORT"),
+		os.Getenv("DB_USER"),
+		os.Getenv("DB_PASSWORD"),
+		os.Getenv("DB_NAME"),
+	)
+	db, err := sql.Open("postgres", connStr)
+	if err != nil {
+		t.Fatalf("Error opening database: %v", err)
+	}
+	defer db.Close()
+
+	migrator, err := migrate.New(db, migrations.FS)
+	if err != nil {
+		t.Fatalf("Error loading migrations: %v", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("Error applying migrations: %v", err)
+	}
+
+	storetest.Run(t, func(t *testing.T) storetest.Stores {
+		if _, err := db.Exec("TRUNCATE TABLE car, engine, car_model, brand, users, api_keys, audit_log CASCADE"); err != nil {
+			t.Fatalf("Error truncating tables: %v", err)
+		}
+		return storetest.Stores{
+			Cars:    carStore.New(db, slog.Default()),
+			Engines: engineStore.New(db, slog.Default()),
+			Catalog: catalogStore.New(db, slog.Default()),
+			Users:   userStore.New(db),
+			APIKeys: apiKeyStore.New(db),
+			Audit:   auditStore.New(db),
+			FailCommits: func(t *testing.T) {
+				failCommits(t, db)
+			},
+		}
+	})
+}
+
+// failCommits adds deferred constraint triggers that raise an error, so that
+// transactions writing a car or an engine fail at COMMIT rather than at the
+// statement.
+func failCommits(t *testing.T, db *sql.DB) {
+	t.Helper()
+	_, err := db.Exec(`CREATE OR REPLACE FUNCTION storetest_fail_commit() RETURNS trigger AS $$
+	BEGIN
+		RAISE EXCEPTION 'commit failed on purpose';
+	END
+	$$ LANGUAGE plpgsql`)
+	if err != nil {
+		t.Fatalf("Error creating the failing trigger function: %v", err)
+	}
+	for _, table := range []string{"car", "engine"} {
+		_, err := db.Exec(`CREATE CONSTRAINT TRIGGER storetest_fail_commit AFTER INSERT OR UPDATE ON ` + table + `
+		DEFERRABLE INITIALLY DEFERRED FOR EACH ROW EXECUTE PROCEDURE storetest_fail_commit()`)
+		if err != nil {
+			t.Fatalf("Error creating the failing trigger on %s: %v", table, err)
+		}
+		t.Cleanup(func() {
+			if _, err := db.Exec("DROP TRIGGER IF EXISTS storetest_fail_commit ON " + table); err != nil {
+				t.Errorf("Error dropping the failing trigger on %s: %v", table, err)
+			}
+		})
+	}
+}
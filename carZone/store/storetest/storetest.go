@@ -0,0 +1,1636 @@
+// Package storetest is a conformance suite for implementations of the store
+// interfaces. Every backend runs the same tests so that they can be swapped
+// without changing behaviour.
+package storetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ayushi-khandal09/carZone/apperrors"
+	"github.com/ayushi-khandal09/carZone/auth"
+	"github.com/ayushi-khandal09/carZone/models"
+	"github.com/ayushi-khandal09/carZone/store"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Stores is a set of stores backed by the same, initially empty, database.
+type Stores struct {
+	Cars    store.CarStoreInterface
+	Engines store.EngineStoreInterface
+	Catalog store.CatalogStoreInterface
+	Users   store.UserStoreInterface
+	APIKeys store.APIKeyStoreInterface
+	Audit   store.AuditStoreInterface
+
+	// FailCommits makes every later transaction that writes a car or an
+	// engine fail when it commits. Backends without transactions leave it
+	// nil and skip the tests that need it.
+	FailCommits func(t *testing.T)
+}
+
+// Factory returns fresh stores for a single test.
+type Factory func(t *testing.T) Stores
+
+// Run runs the whole suite against the stores returned by newStores.
+func Run(t *testing.T, newStores Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s Stores)
+	}{
+		{"EngineCRUD", testEngineCRUD},
+		{"EngineNotFound", testEngineNotFound},
+		{"CarCRUD", testCarCRUD},
+		{"CarNotFound", testCarNotFound},
+		{"CarForeignKey", testCarForeignKey},
+		{"EngineDeleteCascades", testEngineDeleteCascades},
+		{"Versions", testVersions},
+		{"Patch", testPatch},
+		{"FailedCommit", testFailedCommit},
+		{"Trash", testTrash},
+		{"EngineTrash", testEngineTrash},
+		{"Purge", testPurge},
+		{"PriceHistory", testPriceHistory},
+		{"PriceStats", testPriceStats},
+		{"ImportCars", testImportCars},
+		{"EngineImport", testEngineImport},
+		{"BatchCars", testBatchCars},
+		{"CarVIN", testCarVIN},
+		{"Catalog", testCatalog},
+		{"Audit", testAudit},
+		{"AuditPagination", testAuditPagination},
+		{"GetCarByBrand", testGetCarByBrand},
+		{"ListCarsFilters", testListCarsFilters},
+		{"ListCarsOrdering", testListCarsOrdering},
+		{"ListCarsPagination", testListCarsPagination},
+		{"ExportCars", testExportCars},
+		{"SearchCars", testSearchCars},
+		{"Users", testUsers},
+		{"APIKeys", testAPIKeys},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStores(t))
+		})
+	}
+}
+
+func testEngineCRUD(t *testing.T, s Stores) {
+	ctx := context.Background()
+
+	created, err := s.Engines.EngineCreate(ctx, &models.EngineRequest{Displacement: 2000, NoOfCyclinders: 4, CarRange: 600})
+	if err != nil {
+		t.Fatalf("EngineCreate: %v", err)
+	}
+	if created.EngineID == uuid.Nil {
+		t.Fatal("EngineCreate returned a nil id")
+	}
+
+	got, err := s.Engines.EngineById(ctx, created.EngineID.String())
+	if err != nil {
+		t.Fatalf("EngineById: %v", err)
+	}
+	if got != created {
+		t.Errorf("EngineById = %+v, want %+v", got, created)
+	}
+
+	updated, err := s.Engines.EngineUpdate(ctx, created.EngineID.String(), &models.EngineRequest{Displacement: 1600, NoOfCyclinders: 3, CarRange: 450}, 0)
+	if err != nil {
+		t.Fatalf("EngineUpdate: %v", err)
+	}
+	want := models.Engine{EngineID: created.EngineID, Displacement: 1600, NoOfCyclinders: 3, CarRange: 450, Version: 2}
+	if updated != want {
+		t.Errorf("EngineUpdate = %+v, want %+v", updated, want)
+	}
+	if got, _ := s.Engines.EngineById(ctx, created.EngineID.String()); got != want {
+		t.Errorf("EngineById after update = %+v, want %+v", got, want)
+	}
+
+	deleted, err := s.Engines.EngineDelete(ctx, created.EngineID.String(), 0)
+	if err != nil {
+		t.Fatalf("EngineDelete: %v", err)
+	}
+	if deleted.DeletedAt == nil {
+		t.Error("EngineDelete did not set deleted_at")
+	}
+	deleted.DeletedAt = nil
+	want.Version++
+	if deleted != want {
+		t.Errorf("EngineDelete = %+v, want %+v", deleted, want)
+	}
+	_, err = s.Engines.EngineById(ctx, created.EngineID.String())
+	assertKind(t, "EngineById after delete", err, apperrors.ErrNotFound)
+}
+
+func testEngineNotFound(t *testing.T, s Stores) {
+	ctx := context.Background()
+	missing := uuid.NewString()
+	req := &models.EngineRequest{Displacement: 2000, NoOfCyclinders: 4, CarRange: 600}
+
+	_, err := s.Engines.EngineById(ctx, missing)
+	assertKind(t, "EngineById", err, apperrors.ErrNotFound)
+	_, err = s.Engines.EngineUpdate(ctx, missing, req, 0)
+	assertKind(t, "EngineUpdate", err, apperrors.ErrNotFound)
+	_, err = s.Engines.EngineDelete(ctx, missing, 0)
+	assertKind(t, "EngineDelete", err, apperrors.ErrNotFound)
+
+	_, err = s.Engines.EngineById(ctx, "not-a-uuid")
+	assertKind(t, "EngineById with invalid id", err, apperrors.ErrBadRequest)
+	_, err = s.Engines.EngineUpdate(ctx, "not-a-uuid", req, 0)
+	assertKind(t, "EngineUpdate with invalid id", err, apperrors.ErrBadRequest)
+}
+
+func testCarCRUD(t *testing.T, s Stores) {
+	ctx := context.Background()
+	engine := createEngine(t, s, 2000, 4)
+
+	req := carRequest(t, s, "Honda Civic", "Honda", "2019", "Hybrid", 25000, engine)
+	created, err := s.Cars.CreateCar(ctx, &req)
+	if err != nil {
+		t.Fatalf("CreateCar: %v", err)
+	}
+	if created.ID == uuid.Nil {
+		t.Fatal("CreateCar returned a nil id")
+	}
+	assertCar(t, "CreateCar", created, req)
+	if created.CreatedAt.IsZero() || !created.UpdatedAt.Equal(created.CreatedAt) {
+		t.Errorf("CreateCar timestamps = %v / %v, want equal and set", created.CreatedAt, created.UpdatedAt)
+	}
+
+	got, err := s.Cars.GetCarById(ctx, created.ID.String())
+	if err != nil {
+		t.Fatalf("GetCarById: %v", err)
+	}
+	assertCar(t, "GetCarById", got, req)
+	if got.Engine != engine {
+		t.Errorf("GetCarById engine = %+v, want %+v", got.Engine, engine)
+	}
+
+	other := createEngine(t, s, 3000, 6)
+	updateReq := carRequest(t, s, "Honda Accord", "Honda Motors", "2021", "Petrol", 31000.5, other)
+	updated, err := s.Cars.UpdateCar(ctx, created.ID.String(), &updateReq, 0)
+	if err != nil {
+		t.Fatalf("UpdateCar: %v", err)
+	}
+	assertCar(t, "UpdateCar", updated, updateReq)
+	if updated.ID != created.ID {
+		t.Errorf("UpdateCar id = %v, want %v", updated.ID, created.ID)
+	}
+	if updated.UpdatedAt.Before(created.UpdatedAt) {
+		t.Errorf("UpdateCar updated_at = %v, want after %v", updated.UpdatedAt, created.UpdatedAt)
+	}
+	got, err = s.Cars.GetCarById(ctx, created.ID.String())
+	if err != nil {
+		t.Fatalf("GetCarById after update: %v", err)
+	}
+	assertCar(t, "GetCarById after update", got, updateReq)
+
+	deleted, err := s.Cars.DeleteCar(ctx, created.ID.String(), 0)
+	if err != nil {
+		t.Fatalf("DeleteCar: %v", err)
+	}
+	assertCar(t, "DeleteCar", deleted, updateReq)
+	_, err = s.Cars.GetCarById(ctx, created.ID.String())
+	assertKind(t, "GetCarById after delete", err, apperrors.ErrNotFound)
+}
+
+func testCarNotFound(t *testing.T, s Stores) {
+	ctx := context.Background()
+	engine := createEngine(t, s, 2000, 4)
+	req := carRequest(t, s, "Honda Civic", "Honda", "2019", "Hybrid", 25000, engine)
+	missing := uuid.NewString()
+
+	_, err := s.Cars.GetCarById(ctx, missing)
+	assertKind(t, "GetCarById", err, apperrors.ErrNotFound)
+	_, err = s.Cars.UpdateCar(ctx, missing, &req, 0)
+	assertKind(t, "UpdateCar", err, apperrors.ErrNotFound)
+	_, err = s.Cars.DeleteCar(ctx, missing, 0)
+	assertKind(t, "DeleteCar", err, apperrors.ErrNotFound)
+
+	_, err = s.Cars.GetCarById(ctx, "not-a-uuid")
+	assertKind(t, "GetCarById with invalid id", err, apperrors.ErrBadRequest)
+}
+
+func testCarForeignKey(t *testing.T, s Stores) {
+	ctx := context.Background()
+	missing := models.Engine{EngineID: uuid.New(), Displacement: 2000, NoOfCyclinders: 4, CarRange: 600}
+
+	req := carRequest(t, s, "Honda Civic", "Honda", "2019", "Hybrid", 25000, missing)
+	_, err := s.Cars.CreateCar(ctx, &req)
+	assertKind(t, "CreateCar with unknown engine", err, apperrors.ErrForeignKey)
+
+	engine := createEngine(t, s, 2000, 4)
+	car := createCar(t, s, "Honda Civic", "Honda", "2019", 25000, engine)
+	_, err = s.Cars.UpdateCar(ctx, car.ID.String(), &req, 0)
+	assertKind(t, "UpdateCar with unknown engine", err, apperrors.ErrForeignKey)
+
+	page, err := s.Cars.ListCars(ctx, listFilter(models.CarFilter{}))
+	if err != nil {
+		t.Fatalf("ListCars: %v", err)
+	}
+	if page.Total != 1 {
+		t.Errorf("ListCars total = %d, want 1", page.Total)
+	}
+}
+
+func testEngineDeleteCascades(t *testing.T, s Stores) {
+	ctx := context.Background()
+	engine := createEngine(t, s, 2000, 4)
+	car := createCar(t, s, "Honda Civic", "Honda", "2019", 25000, engine)
+
+	if _, err := s.Engines.EngineDelete(ctx, engine.EngineID.String(), 0); err != nil {
+		t.Fatalf("EngineDelete: %v", err)
+	}
+	_, err := s.Cars.GetCarById(ctx, car.ID.String())
+	assertKind(t, "GetCarById after engine delete", err, apperrors.ErrNotFound)
+}
+
+func testVersions(t *testing.T, s Stores) {
+	ctx := context.Background()
+	engine := createEngine(t, s, 2000, 4)
+	car := createCar(t, s, "Honda Civic", "Honda", "2019", 25000, engine)
+	if engine.Version != 1 || car.Version != 1 {
+		t.Fatalf("created versions = %d, %d, want 1, 1", engine.Version, car.Version)
+	}
+
+	req := carRequest(t, s, "Honda Civic", "Honda", "2020", "Hybrid", 26000, engine)
+	updated, err := s.Cars.UpdateCar(ctx, car.ID.String(), &req, 1)
+	if err != nil {
+		t.Fatalf("UpdateCar at version 1: %v", err)
+	}
+	if updated.Version != 2 {
+		t.Errorf("UpdateCar version = %d, want 2", updated.Version)
+	}
+	if got, _ := s.Cars.GetCarById(ctx, car.ID.String()); got.Version != 2 {
+		t.Errorf("GetCarById version = %d, want 2", got.Version)
+	}
+	_, err = s.Cars.UpdateCar(ctx, car.ID.String(), &req, 1)
+	assertKind(t, "UpdateCar at stale version", err, apperrors.ErrPrecondition)
+	// The version is checked before the references, so a stale write fails
+	// the same way whatever else is wrong with it.
+	stale := req
+	stale.Engine.EngineID = uuid.New()
+	stale.Brand = "Lamborghini"
+	_, err = s.Cars.UpdateCar(ctx, car.ID.String(), &stale, 1)
+	assertKind(t, "UpdateCar at stale version with unknown engine and brand", err, apperrors.ErrPrecondition)
+	missingBrand := "Lamborghini"
+	_, err = s.Cars.PatchCar(ctx, car.ID.String(), models.CarPatch{EngineID: &stale.Engine.EngineID, Brand: &missingBrand}, 1)
+	assertKind(t, "PatchCar at stale version with unknown engine and brand", err, apperrors.ErrPrecondition)
+	_, err = s.Cars.DeleteCar(ctx, car.ID.String(), 1)
+	assertKind(t, "DeleteCar at stale version", err, apperrors.ErrPrecondition)
+	_, err = s.Cars.UpdateCar(ctx, uuid.NewString(), &req, 1)
+	assertKind(t, "UpdateCar of missing car", err, apperrors.ErrNotFound)
+	if _, err := s.Cars.DeleteCar(ctx, car.ID.String(), 2); err != nil {
+		t.Fatalf("DeleteCar at version 2: %v", err)
+	}
+
+	engineReq := &models.EngineRequest{Displacement: 1600, NoOfCyclinders: 3, CarRange: 450}
+	updatedEngine, err := s.Engines.EngineUpdate(ctx, engine.EngineID.String(), engineReq, 1)
+	if err != nil {
+		t.Fatalf("EngineUpdate at version 1: %v", err)
+	}
+	if updatedEngine.Version != 2 {
+		t.Errorf("EngineUpdate version = %d, want 2", updatedEngine.Version)
+	}
+	_, err = s.Engines.EngineUpdate(ctx, engine.EngineID.String(), engineReq, 1)
+	assertKind(t, "EngineUpdate at stale version", err, apperrors.ErrPrecondition)
+	_, err = s.Engines.EngineDelete(ctx, engine.EngineID.String(), 1)
+	assertKind(t, "EngineDelete at stale version", err, apperrors.ErrPrecondition)
+	_, err = s.Engines.EngineUpdate(ctx, uuid.NewString(), engineReq, 1)
+	assertKind(t, "EngineUpdate of missing engine", err, apperrors.ErrNotFound)
+	if _, err := s.Engines.EngineDelete(ctx, engine.EngineID.String(), 2); err != nil {
+		t.Fatalf("EngineDelete at version 2: %v", err)
+	}
+}
+
+func testPatch(t *testing.T, s Stores) {
+	ctx := context.Background()
+	engine := createEngine(t, s, 2000, 4)
+	other := createEngine(t, s, 1600, 3)
+	car := createCar(t, s, "Honda Civic", "Honda", "2019", 25000, engine)
+
+	price := decimal.NewFromInt(23500)
+	patched, err := s.Cars.PatchCar(ctx, car.ID.String(), models.CarPatch{Price: &price}, car.Version)
+	if err != nil {
+		t.Fatalf("PatchCar price: %v", err)
+	}
+	want := carRequest(t, s, "Honda Civic", "Honda", "2019", "Hybrid", 23500, engine)
+	assertCar(t, "PatchCar price", patched, want)
+	if patched.Version != car.Version+1 {
+		t.Errorf("PatchCar version = %d, want %d", patched.Version, car.Version+1)
+	}
+	if !patched.CreatedAt.Equal(car.CreatedAt) {
+		t.Errorf("PatchCar created_at = %v, want %v", patched.CreatedAt, car.CreatedAt)
+	}
+
+	name := "Honda Civic Type R"
+	want = carRequest(t, s, name, "Honda", "2019", "Hybrid", 23500, other)
+	patched, err = s.Cars.PatchCar(ctx, car.ID.String(), models.CarPatch{Name: &name, EngineID: &other.EngineID}, 0)
+	if err != nil {
+		t.Fatalf("PatchCar name and engine: %v", err)
+	}
+	assertCar(t, "PatchCar name and engine", patched, want)
+	if got, _ := s.Cars.GetCarById(ctx, car.ID.String()); got.Engine.Displacement != 1600 {
+		t.Errorf("GetCarById after engine patch = %+v, want engine %+v", got.Engine, other)
+	}
+
+	_, err = s.Cars.PatchCar(ctx, car.ID.String(), models.CarPatch{Name: &name}, car.Version)
+	assertKind(t, "PatchCar at stale version", err, apperrors.ErrPrecondition)
+	missingEngine := uuid.New()
+	_, err = s.Cars.PatchCar(ctx, car.ID.String(), models.CarPatch{EngineID: &missingEngine}, 0)
+	assertKind(t, "PatchCar with unknown engine", err, apperrors.ErrForeignKey)
+	_, err = s.Cars.PatchCar(ctx, uuid.NewString(), models.CarPatch{Name: &name}, 0)
+	assertKind(t, "PatchCar of missing car", err, apperrors.ErrNotFound)
+
+	carRange := int64(650)
+	patchedEngine, err := s.Engines.EnginePatch(ctx, engine.EngineID.String(), models.EnginePatch{CarRange: &carRange}, engine.Version)
+	if err != nil {
+		t.Fatalf("EnginePatch: %v", err)
+	}
+	wantEngine := models.Engine{EngineID: engine.EngineID, Displacement: 2000, NoOfCyclinders: 4, CarRange: 650, Version: engine.Version + 1}
+	if patchedEngine != wantEngine {
+		t.Errorf("EnginePatch = %+v, want %+v", patchedEngine, wantEngine)
+	}
+	_, err = s.Engines.EnginePatch(ctx, engine.EngineID.String(), models.EnginePatch{CarRange: &carRange}, engine.Version)
+	assertKind(t, "EnginePatch at stale version", err, apperrors.ErrPrecondition)
+	_, err = s.Engines.EnginePatch(ctx, uuid.NewString(), models.EnginePatch{CarRange: &carRange}, 0)
+	assertKind(t, "EnginePatch of missing engine", err, apperrors.ErrNotFound)
+}
+
+// testFailedCommit checks that a mutation whose commit fails reports an
+// error instead of a change, price change or audit entry that was not saved.
+func testFailedCommit(t *testing.T, s Stores) {
+	if s.FailCommits == nil {
+		t.Skip("the stores have no transactions to fail")
+	}
+	ctx := context.Background()
+	engine := createEngine(t, s, 2000, 4)
+	car := createCar(t, s, "Honda Civic", "Honda", "2019", 25000, engine)
+	trashedCar := createCar(t, s, "Honda Jazz", "Honda", "2018", 15000, engine)
+	if _, err := s.Cars.DeleteCar(ctx, trashedCar.ID.String(), 0); err != nil {
+		t.Fatalf("DeleteCar: %v", err)
+	}
+	trashed := createEngine(t, s, 1600, 3)
+	if _, err := s.Engines.EngineDelete(ctx, trashed.EngineID.String(), 0); err != nil {
+		t.Fatalf("EngineDelete: %v", err)
+	}
+	s.FailCommits(t)
+
+	price := decimal.NewFromInt(23500)
+	if _, err := s.Cars.PatchCar(ctx, car.ID.String(), models.CarPatch{Price: &price}, 0); err == nil {
+		t.Error("PatchCar succeeded although its commit failed")
+	}
+	if _, err := s.Cars.RestoreCar(ctx, trashedCar.ID.String()); err == nil {
+		t.Error("RestoreCar succeeded although its commit failed")
+	}
+	carRange := int64(650)
+	if _, err := s.Engines.EnginePatch(ctx, engine.EngineID.String(), models.EnginePatch{CarRange: &carRange}, 0); err == nil {
+		t.Error("EnginePatch succeeded although its commit failed")
+	}
+	if _, err := s.Engines.EngineCreate(ctx, &models.EngineRequest{Displacement: 1200, NoOfCyclinders: 3, CarRange: 400}); err == nil {
+		t.Error("EngineCreate succeeded although its commit failed")
+	}
+	if _, err := s.Engines.EngineUpdate(ctx, engine.EngineID.String(), &models.EngineRequest{Displacement: 1800, NoOfCyclinders: 4, CarRange: 550}, 0); err == nil {
+		t.Error("EngineUpdate succeeded although its commit failed")
+	}
+	if _, err := s.Engines.EngineDelete(ctx, engine.EngineID.String(), 0); err == nil {
+		t.Error("EngineDelete succeeded although its commit failed")
+	}
+	if _, err := s.Engines.EngineRestore(ctx, trashed.EngineID.String()); err == nil {
+		t.Error("EngineRestore succeeded although its commit failed")
+	}
+
+	if got, _ := s.Cars.GetCarById(ctx, car.ID.String()); got.Version != car.Version || !got.Price.Equal(car.Price) {
+		t.Errorf("GetCarById after failed commits = %+v, want %+v", got, car)
+	}
+	if got, _ := s.Engines.EngineById(ctx, engine.EngineID.String()); got != engine {
+		t.Errorf("EngineById after failed commits = %+v, want %+v", got, engine)
+	}
+	if history, _ := s.Cars.PriceHistory(ctx, car.ID.String()); len(history.Changes) != 0 {
+		t.Errorf("PriceHistory after failed commits = %+v, want no changes", history.Changes)
+	}
+	if deleted, _ := s.Cars.DeletedCars(ctx); len(deleted) != 1 || deleted[0].ID != trashedCar.ID {
+		t.Errorf("DeletedCars after failed commits = %+v, want only %s", deleted, trashedCar.ID)
+	}
+	if deleted, _ := s.Engines.DeletedEngines(ctx); len(deleted) != 1 || deleted[0].EngineID != trashed.EngineID {
+		t.Errorf("DeletedEngines after failed commits = %+v, want only %s", deleted, trashed.EngineID)
+	}
+	if entries := listAudit(t, s, models.AuditFilter{Entity: "engine"}); len(entries) != 3 {
+		t.Errorf("engine audit entries after failed commits = %d, want 3", len(entries))
+	}
+	if entries := listAudit(t, s, models.AuditFilter{Entity: "car", EntityID: car.ID}); len(entries) != 1 {
+		t.Errorf("car audit entries after failed commits = %d, want only the create", len(entries))
+	}
+}
+
+func testTrash(t *testing.T, s Stores) {
+	ctx := context.Background()
+	engine := createEngine(t, s, 2000, 4)
+	car := createCar(t, s, "Honda Civic", "Honda", "2019", 25000, engine)
+	kept := createCar(t, s, "Honda Accord", "Honda", "2020", 30000, engine)
+
+	deleted, err := s.Cars.DeleteCar(ctx, car.ID.String(), 0)
+	if err != nil {
+		t.Fatalf("DeleteCar: %v", err)
+	}
+	if deleted.DeletedAt == nil || deleted.Version != car.Version+1 {
+		t.Errorf("DeleteCar = %+v, want deleted_at set and version %d", deleted, car.Version+1)
+	}
+
+	_, err = s.Cars.GetCarById(ctx, car.ID.String())
+	assertKind(t, "GetCarById of deleted car", err, apperrors.ErrNotFound)
+	req := carRequest(t, s, "Honda Civic", "Honda", "2019", "Hybrid", 25000, engine)
+	_, err = s.Cars.UpdateCar(ctx, car.ID.String(), &req, 0)
+	assertKind(t, "UpdateCar of deleted car", err, apperrors.ErrNotFound)
+	_, err = s.Cars.DeleteCar(ctx, car.ID.String(), 0)
+	assertKind(t, "DeleteCar of deleted car", err, apperrors.ErrNotFound)
+	byBrand, err := s.Cars.GetCarByBrand(ctx, "Honda", false)
+	if err != nil {
+		t.Fatalf("GetCarByBrand: %v", err)
+	}
+	assertIDs(t, "GetCarByBrand", byBrand, kept)
+	page, err := s.Cars.ListCars(ctx, listFilter(models.CarFilter{}))
+	if err != nil {
+		t.Fatalf("ListCars: %v", err)
+	}
+	assertIDs(t, "ListCars", page.Cars, kept)
+
+	trash, err := s.Cars.DeletedCars(ctx)
+	if err != nil {
+		t.Fatalf("DeletedCars: %v", err)
+	}
+	assertIDs(t, "DeletedCars", trash, car)
+
+	restored, err := s.Cars.RestoreCar(ctx, car.ID.String())
+	if err != nil {
+		t.Fatalf("RestoreCar: %v", err)
+	}
+	assertCar(t, "RestoreCar", restored, req)
+	if restored.DeletedAt != nil || restored.Version != deleted.Version+1 {
+		t.Errorf("RestoreCar = %+v, want deleted_at unset and version %d", restored, deleted.Version+1)
+	}
+	if _, err := s.Cars.GetCarById(ctx, car.ID.String()); err != nil {
+		t.Errorf("GetCarById after restore: %v", err)
+	}
+	_, err = s.Cars.RestoreCar(ctx, car.ID.String())
+	assertKind(t, "RestoreCar of live car", err, apperrors.ErrNotFound)
+}
+
+func testEngineTrash(t *testing.T, s Stores) {
+	ctx := context.Background()
+	engine := createEngine(t, s, 2000, 4)
+	earlier := createCar(t, s, "Honda Civic", "Honda", "2019", 25000, engine)
+	car := createCar(t, s, "Honda Accord", "Honda", "2020", 30000, engine)
+	if _, err := s.Cars.DeleteCar(ctx, earlier.ID.String(), 0); err != nil {
+		t.Fatalf("DeleteCar: %v", err)
+	}
+	// Deleted timestamps are compared, make sure they differ.
+	time.Sleep(time.Millisecond)
+
+	if _, err := s.Engines.EngineDelete(ctx, engine.EngineID.String(), 0); err != nil {
+		t.Fatalf("EngineDelete: %v", err)
+	}
+	_, err := s.Engines.EngineById(ctx, engine.EngineID.String())
+	assertKind(t, "EngineById of deleted engine", err, apperrors.ErrNotFound)
+	trash, err := s.Cars.DeletedCars(ctx)
+	if err != nil {
+		t.Fatalf("DeletedCars: %v", err)
+	}
+	assertIDs(t, "DeletedCars after engine delete", trash, car, earlier)
+	engines, err := s.Engines.DeletedEngines(ctx)
+	if err != nil {
+		t.Fatalf("DeletedEngines: %v", err)
+	}
+	if len(engines) != 1 || engines[0].EngineID != engine.EngineID || engines[0].DeletedAt == nil {
+		t.Errorf("DeletedEngines = %+v, want only %v", engines, engine.EngineID)
+	}
+
+	_, err = s.Cars.RestoreCar(ctx, car.ID.String())
+	assertKind(t, "RestoreCar with deleted engine", err, apperrors.ErrForeignKey)
+	req := carRequest(t, s, "Honda Jazz", "Honda", "2021", "Petrol", 20000, engine)
+	_, err = s.Cars.CreateCar(ctx, &req)
+	assertKind(t, "CreateCar with deleted engine", err, apperrors.ErrForeignKey)
+
+	restored, err := s.Engines.EngineRestore(ctx, engine.EngineID.String())
+	if err != nil {
+		t.Fatalf("EngineRestore: %v", err)
+	}
+	if restored.DeletedAt != nil || restored.Version != engine.Version+2 {
+		t.Errorf("EngineRestore = %+v, want deleted_at unset and version %d", restored, engine.Version+2)
+	}
+	if _, err := s.Cars.GetCarById(ctx, car.ID.String()); err != nil {
+		t.Errorf("GetCarById of car deleted with its engine after restore: %v", err)
+	}
+	trash, err = s.Cars.DeletedCars(ctx)
+	if err != nil {
+		t.Fatalf("DeletedCars: %v", err)
+	}
+	assertIDs(t, "DeletedCars after engine restore", trash, earlier)
+	_, err = s.Engines.EngineRestore(ctx, engine.EngineID.String())
+	assertKind(t, "EngineRestore of live engine", err, apperrors.ErrNotFound)
+}
+
+func testPurge(t *testing.T, s Stores) {
+	ctx := context.Background()
+	engine := createEngine(t, s, 2000, 4)
+	gone := createEngine(t, s, 1600, 3)
+	car := createCar(t, s, "Honda Civic", "Honda", "2019", 25000, engine)
+	live := createCar(t, s, "Honda Accord", "Honda", "2020", 30000, engine)
+	createCar(t, s, "Toyota Yaris", "Toyota", "2018", 15000, gone)
+
+	if _, err := s.Cars.DeleteCar(ctx, car.ID.String(), 0); err != nil {
+		t.Fatalf("DeleteCar: %v", err)
+	}
+	if _, err := s.Engines.EngineDelete(ctx, gone.EngineID.String(), 0); err != nil {
+		t.Fatalf("EngineDelete: %v", err)
+	}
+
+	purged, err := s.Cars.PurgeCars(ctx, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("PurgeCars: %v", err)
+	}
+	if purged != 0 {
+		t.Errorf("PurgeCars before the deletes purged %d cars, want 0", purged)
+	}
+
+	purged, err = s.Cars.PurgeCars(ctx, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("PurgeCars: %v", err)
+	}
+	if purged != 2 {
+		t.Errorf("PurgeCars purged %d cars, want 2", purged)
+	}
+	purged, err = s.Engines.EnginePurge(ctx, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("EnginePurge: %v", err)
+	}
+	if purged != 1 {
+		t.Errorf("EnginePurge purged %d engines, want 1", purged)
+	}
+
+	_, err = s.Cars.RestoreCar(ctx, car.ID.String())
+	assertKind(t, "RestoreCar after purge", err, apperrors.ErrNotFound)
+	_, err = s.Engines.EngineRestore(ctx, gone.EngineID.String())
+	assertKind(t, "EngineRestore after purge", err, apperrors.ErrNotFound)
+	if _, err := s.Cars.GetCarById(ctx, live.ID.String()); err != nil {
+		t.Errorf("GetCarById of live car after purge: %v", err)
+	}
+}
+
+func testPriceHistory(t *testing.T, s Stores) {
+	ctx := context.Background()
+	engine := createEngine(t, s, 2000, 4)
+	car := createCar(t, s, "Honda Civic", "Honda", "2019", 25000, engine)
+
+	history, err := s.Cars.PriceHistory(ctx, car.ID.String())
+	if err != nil {
+		t.Fatalf("PriceHistory: %v", err)
+	}
+	if history.CarID != car.ID || !history.Price.Equal(decimal.NewFromInt(25000)) || history.Currency != "USD" || len(history.Changes) != 0 {
+		t.Errorf("PriceHistory of a new car = %+v, want price 25000 USD and no changes", history)
+	}
+
+	req := carRequest(t, s, "Honda Civic", "Honda", "2019", "Petrol", 27000, engine)
+	if _, err := s.Cars.UpdateCar(ctx, car.ID.String(), &req, 0); err != nil {
+		t.Fatalf("UpdateCar: %v", err)
+	}
+	// Updates that leave the price alone are not part of the history.
+	req = carRequest(t, s, "Honda Civic Sport", "Honda", "2019", "Petrol", 27000, engine)
+	if _, err := s.Cars.UpdateCar(ctx, car.ID.String(), &req, 0); err != nil {
+		t.Fatalf("UpdateCar: %v", err)
+	}
+	price := decimal.RequireFromString("26500.5")
+	if _, err := s.Cars.PatchCar(ctx, car.ID.String(), models.CarPatch{Price: &price}, 0); err != nil {
+		t.Fatalf("PatchCar: %v", err)
+	}
+	// A change of currency alone is a price change too.
+	currency := "EUR"
+	if _, err := s.Cars.PatchCar(ctx, car.ID.String(), models.CarPatch{Currency: &currency}, 0); err != nil {
+		t.Fatalf("PatchCar: %v", err)
+	}
+
+	history, err = s.Cars.PriceHistory(ctx, car.ID.String())
+	if err != nil {
+		t.Fatalf("PriceHistory: %v", err)
+	}
+	want := [][4]string{{"25000", "USD", "27000", "USD"}, {"27000", "USD", "26500.5", "USD"}, {"26500.5", "USD", "26500.5", "EUR"}}
+	if !history.Price.Equal(price) || history.Currency != currency || len(history.Changes) != len(want) {
+		t.Fatalf("PriceHistory = %+v, want price %v %s and changes %v", history, price, currency, want)
+	}
+	for i, change := range history.Changes {
+		got := [4]string{change.OldPrice.String(), change.OldCurrency, change.NewPrice.String(), change.NewCurrency}
+		if got != want[i] || change.ChangedAt.IsZero() {
+			t.Errorf("PriceHistory change %d = %+v, want %v", i, change, want[i])
+		}
+	}
+
+	_, err = s.Cars.PriceHistory(ctx, uuid.NewString())
+	assertKind(t, "PriceHistory of missing car", err, apperrors.ErrNotFound)
+}
+
+func testPriceStats(t *testing.T, s Stores) {
+	ctx := context.Background()
+	engine := createEngine(t, s, 2000, 4)
+	createCar(t, s, "Honda Civic", "Honda", "2019", 20000, engine)
+	createCar(t, s, "Honda Accord", "Honda", "2020", 30000, engine)
+	createCar(t, s, "Honda Jazz", "Honda", "2020", 15000.5, engine)
+	createCar(t, s, "Toyota Corolla", "Toyota", "2020", 22000, engine)
+	deleted := createCar(t, s, "Toyota Supra", "Toyota", "2019", 90000, engine)
+	if _, err := s.Cars.DeleteCar(ctx, deleted.ID.String(), 0); err != nil {
+		t.Fatalf("DeleteCar: %v", err)
+	}
+
+	stats, err := s.Cars.PriceStats(ctx, models.PriceStatsFilter{GroupBy: "brand"})
+	if err != nil {
+		t.Fatalf("PriceStats: %v", err)
+	}
+	want := []models.PriceStats{
+		priceStats("Honda", "USD", 3, "21666.83", "15000.5", "30000"),
+		priceStats("Toyota", "USD", 1, "22000", "22000", "22000"),
+	}
+	if fmt.Sprint(stats) != fmt.Sprint(want) {
+		t.Errorf("PriceStats by brand = %+v, want %+v", stats, want)
+	}
+
+	stats, err = s.Cars.PriceStats(ctx, models.PriceStatsFilter{GroupBy: "year", Brand: "Honda"})
+	if err != nil {
+		t.Fatalf("PriceStats: %v", err)
+	}
+	want = []models.PriceStats{
+		priceStats("2019", "USD", 1, "20000", "20000", "20000"),
+		priceStats("2020", "USD", 2, "22500.25", "15000.5", "30000"),
+	}
+	if fmt.Sprint(stats) != fmt.Sprint(want) {
+		t.Errorf("PriceStats of Honda by year = %+v, want %+v", stats, want)
+	}
+
+	_, err = s.Cars.PriceStats(ctx, models.PriceStatsFilter{GroupBy: "name"})
+	assertKind(t, "PriceStats by name", err, apperrors.ErrValidation)
+}
+
+func testImportCars(t *testing.T, s Stores) {
+	ctx := context.Background()
+	engine := createEngine(t, s, 2000, 4)
+	newEngine := models.Engine{EngineID: uuid.New(), Displacement: 1500, NoOfCyclinders: 3, CarRange: 450}
+	importRows := func() []models.CarImportRow {
+		return []models.CarImportRow{
+			{ImportRow: models.ImportRow{Line: 2, ID: uuid.New()}, Car: carRequest(t, s, "Honda Civic", "Honda", "2019", "Petrol", 25000, engine)},
+			{ImportRow: models.ImportRow{Line: 3, ID: uuid.New()}, Car: carRequest(t, s, "Toyota Yaris", "Toyota", "2021", "Hybrid", 19000, newEngine), NewEngine: true},
+			{ImportRow: models.ImportRow{Line: 4, ID: uuid.New()}, Car: carRequest(t, s, "Missing", "Honda", "2020", "Petrol", 21000, models.Engine{EngineID: uuid.New()})},
+			{ImportRow: models.ImportRow{Line: 5, ID: uuid.New(), Err: apperrors.Validation("year", "Year is required")}},
+		}
+	}
+	countCars := func() int {
+		page, err := s.Cars.ListCars(ctx, listFilter(models.CarFilter{Limit: models.MaxPageSize}))
+		if err != nil {
+			t.Fatalf("ListCars: %v", err)
+		}
+		return len(page.Cars)
+	}
+
+	for _, options := range []models.ImportOptions{
+		{Mode: models.ImportAllOrNothing},
+		{Mode: models.ImportBestEffort, DryRun: true},
+	} {
+		rows, err := s.Cars.ImportCars(ctx, importRows(), options)
+		if err != nil {
+			t.Fatalf("ImportCars %+v: %v", options, err)
+		}
+		assertKind(t, "ImportCars of a missing engine", rows[2].Err, apperrors.ErrForeignKey)
+		if rows[0].Err != nil || rows[1].Err != nil {
+			t.Errorf("ImportCars %+v failed valid rows: %v, %v", options, rows[0].Err, rows[1].Err)
+		}
+		if n := countCars(); n != 0 {
+			t.Errorf("ImportCars %+v left %d cars, want none", options, n)
+		}
+		_, err = s.Engines.EngineById(ctx, newEngine.EngineID.String())
+		assertKind(t, "EngineById of an engine that was not imported", err, apperrors.ErrNotFound)
+	}
+
+	rows, err := s.Cars.ImportCars(ctx, importRows(), models.ImportOptions{Mode: models.ImportBestEffort})
+	if err != nil {
+		t.Fatalf("ImportCars: %v", err)
+	}
+	assertKind(t, "ImportCars of a missing engine", rows[2].Err, apperrors.ErrForeignKey)
+	for _, row := range rows[:2] {
+		car, err := s.Cars.GetCarById(ctx, row.ID.String())
+		if err != nil {
+			t.Fatalf("GetCarById of imported line %d: %v", row.Line, err)
+		}
+		assertCar(t, "imported car", car, row.Car)
+		if car.Version != 1 {
+			t.Errorf("imported car version = %d, want 1", car.Version)
+		}
+	}
+	if n := countCars(); n != 2 {
+		t.Errorf("ImportCars kept %d cars, want 2", n)
+	}
+	imported, err := s.Engines.EngineById(ctx, newEngine.EngineID.String())
+	if err != nil {
+		t.Fatalf("EngineById of the imported engine: %v", err)
+	}
+	if imported.Displacement != 1500 || imported.NoOfCyclinders != 3 || imported.CarRange != 450 {
+		t.Errorf("imported engine = %+v, want %+v", imported, newEngine)
+	}
+}
+
+func testBatchCars(t *testing.T, s Stores) {
+	ctx := context.Background()
+	engine := createEngine(t, s, 2000, 4)
+	repriced := createCar(t, s, "Honda Civic", "Honda", "2019", 25000, engine)
+	retired := createCar(t, s, "Honda Jazz", "Honda", "2015", 9000, engine)
+	newCar := carRequest(t, s, "Toyota Yaris", "Toyota", "2021", "Hybrid", 19000, engine)
+	update := carRequest(t, s, "Honda Civic", "Honda", "2019", "Hybrid", 23000, engine)
+	batch := func() []models.CarBatchItem {
+		return []models.CarBatchItem{
+			{Op: models.BatchCreate, ID: uuid.New(), Car: newCar},
+			{Op: models.BatchUpdate, ID: repriced.ID, Version: repriced.Version, Car: update},
+			{Op: models.BatchDelete, ID: retired.ID},
+			{Op: models.BatchDelete, ID: retired.ID, Version: retired.Version},
+			{Op: models.BatchUpdate, ID: uuid.New(), Car: update},
+			{Op: models.BatchCreate, ID: uuid.New(), Err: apperrors.Validation("year", "Year is required")},
+		}
+	}
+
+	items, err := s.Cars.BatchCars(ctx, batch(), true)
+	if err != nil {
+		t.Fatalf("BatchCars: %v", err)
+	}
+	for i, item := range items[:3] {
+		if item.Err != nil {
+			t.Errorf("BatchCars item %d failed: %v", i, item.Err)
+		}
+	}
+	// The first delete already moved the car to the trash.
+	assertKind(t, "BatchCars delete of a deleted car", items[3].Err, apperrors.ErrNotFound)
+	assertKind(t, "BatchCars update of a missing car", items[4].Err, apperrors.ErrNotFound)
+
+	_, err = s.Cars.GetCarById(ctx, items[0].ID.String())
+	assertKind(t, "GetCarById of a car created by a failed atomic batch", err, apperrors.ErrNotFound)
+	for _, want := range []models.Car{repriced, retired} {
+		got, err := s.Cars.GetCarById(ctx, want.ID.String())
+		if err != nil {
+			t.Fatalf("GetCarById after a failed atomic batch: %v", err)
+		}
+		if got.Version != want.Version || !got.Price.Equal(want.Price) {
+			t.Errorf("failed atomic batch changed car %s to %+v", want.Name, got)
+		}
+	}
+	history, err := s.Cars.PriceHistory(ctx, repriced.ID.String())
+	if err != nil {
+		t.Fatalf("PriceHistory: %v", err)
+	}
+	if len(history.Changes) != 0 {
+		t.Errorf("failed atomic batch recorded %d price changes", len(history.Changes))
+	}
+
+	items, err = s.Cars.BatchCars(ctx, batch(), false)
+	if err != nil {
+		t.Fatalf("BatchCars: %v", err)
+	}
+	created, err := s.Cars.GetCarById(ctx, items[0].ID.String())
+	if err != nil {
+		t.Fatalf("GetCarById of the created car: %v", err)
+	}
+	assertCar(t, "car created by a batch", created, newCar)
+	if items[0].Result.ID != created.ID {
+		t.Errorf("BatchCars create result id = %v, want %v", items[0].Result.ID, created.ID)
+	}
+	updated, err := s.Cars.GetCarById(ctx, repriced.ID.String())
+	if err != nil {
+		t.Fatalf("GetCarById of the updated car: %v", err)
+	}
+	assertCar(t, "car updated by a batch", updated, update)
+	if updated.Version != repriced.Version+1 {
+		t.Errorf("car updated by a batch version = %d, want %d", updated.Version, repriced.Version+1)
+	}
+	_, err = s.Cars.GetCarById(ctx, retired.ID.String())
+	assertKind(t, "GetCarById of a car deleted by a batch", err, apperrors.ErrNotFound)
+	history, err = s.Cars.PriceHistory(ctx, repriced.ID.String())
+	if err != nil {
+		t.Fatalf("PriceHistory: %v", err)
+	}
+	if len(history.Changes) != 1 {
+		t.Errorf("batch recorded %d price changes, want 1", len(history.Changes))
+	}
+}
+
+func testCarVIN(t *testing.T, s Stores) {
+	ctx := context.Background()
+	engine := createEngine(t, s, 2000, 4)
+	req := carRequest(t, s, "Honda Accord", "Honda", "2003", "Petrol", 8000, engine)
+	req.VIN = "1HGCM82633A004352"
+	car, err := s.Cars.CreateCar(ctx, &req)
+	if err != nil {
+		t.Fatalf("CreateCar with a VIN: %v", err)
+	}
+	assertCar(t, "created car", car, req)
+	// Cars without a VIN never clash with each other.
+	other := createCar(t, s, "Honda Civic", "Honda", "2019", 25000, engine)
+	createCar(t, s, "Honda Jazz", "Honda", "2015", 9000, engine)
+
+	found, err := s.Cars.GetCarByVIN(ctx, req.VIN)
+	if err != nil {
+		t.Fatalf("GetCarByVIN: %v", err)
+	}
+	if found.ID != car.ID || found.Engine.Displacement != engine.Displacement {
+		t.Errorf("GetCarByVIN = %+v, want car %v with its engine", found, car.ID)
+	}
+	_, err = s.Cars.GetCarByVIN(ctx, "1M8GDM9AXKP042788")
+	assertKind(t, "GetCarByVIN of an unknown VIN", err, apperrors.ErrNotFound)
+
+	duplicate := carRequest(t, s, "Honda Accord", "Honda", "2003", "Petrol", 7000, engine)
+	duplicate.VIN = req.VIN
+	_, err = s.Cars.CreateCar(ctx, &duplicate)
+	assertKind(t, "CreateCar with a VIN in use", err, apperrors.ErrConflict)
+	_, err = s.Cars.UpdateCar(ctx, other.ID.String(), &duplicate, 0)
+	assertKind(t, "UpdateCar to a VIN in use", err, apperrors.ErrConflict)
+	_, err = s.Cars.PatchCar(ctx, other.ID.String(), models.CarPatch{VIN: &req.VIN}, 0)
+	assertKind(t, "PatchCar to a VIN in use", err, apperrors.ErrConflict)
+
+	// A car in the trash keeps its VIN, so it can still be restored.
+	if _, err := s.Cars.DeleteCar(ctx, car.ID.String(), 0); err != nil {
+		t.Fatalf("DeleteCar: %v", err)
+	}
+	_, err = s.Cars.GetCarByVIN(ctx, req.VIN)
+	assertKind(t, "GetCarByVIN of a deleted car", err, apperrors.ErrNotFound)
+	_, err = s.Cars.CreateCar(ctx, &duplicate)
+	assertKind(t, "CreateCar with the VIN of a deleted car", err, apperrors.ErrConflict)
+
+	vin := "1M8GDM9AXKP042788"
+	patched, err := s.Cars.PatchCar(ctx, other.ID.String(), models.CarPatch{VIN: &vin}, 0)
+	if err != nil {
+		t.Fatalf("PatchCar of the VIN: %v", err)
+	}
+	if patched.VIN != vin {
+		t.Errorf("PatchCar VIN = %q, want %q", patched.VIN, vin)
+	}
+}
+
+func testCatalog(t *testing.T, s Stores) {
+	ctx := context.Background()
+	engine := createEngine(t, s, 1800, 4)
+
+	toyota, err := s.Catalog.CreateBrand(ctx, &models.BrandRequest{Name: "Toyota"})
+	if err != nil {
+		t.Fatalf("CreateBrand: %v", err)
+	}
+	if toyota.Slug != "toyota" {
+		t.Errorf("CreateBrand slug = %q, want toyota", toyota.Slug)
+	}
+	_, err = s.Catalog.CreateBrand(ctx, &models.BrandRequest{Name: "TOYOTA "})
+	assertKind(t, "CreateBrand with the slug of another brand", err, apperrors.ErrConflict)
+	for _, ref := range []string{"toyota", "TOYOTA ", toyota.ID.String()} {
+		if got, err := s.Catalog.GetBrand(ctx, ref); err != nil || got.ID != toyota.ID {
+			t.Errorf("GetBrand(%q) = %+v, %v, want Toyota", ref, got, err)
+		}
+	}
+	_, err = s.Catalog.GetBrand(ctx, "lexus")
+	assertKind(t, "GetBrand of a missing brand", err, apperrors.ErrNotFound)
+
+	corolla, err := s.Catalog.CreateCarModel(ctx, "toyota", &models.CarModelRequest{Name: "Corolla"})
+	if err != nil {
+		t.Fatalf("CreateCarModel: %v", err)
+	}
+	if corolla.BrandID != toyota.ID || corolla.Slug != "corolla" {
+		t.Errorf("CreateCarModel = %+v, want corolla of Toyota", corolla)
+	}
+	_, err = s.Catalog.CreateCarModel(ctx, toyota.ID.String(), &models.CarModelRequest{Name: "COROLLA"})
+	assertKind(t, "CreateCarModel with the slug of another model", err, apperrors.ErrConflict)
+	_, err = s.Catalog.CreateCarModel(ctx, "lexus", &models.CarModelRequest{Name: "IS"})
+	assertKind(t, "CreateCarModel of a missing brand", err, apperrors.ErrNotFound)
+	carModels, err := s.Catalog.ListCarModels(ctx, "Toyota")
+	if err != nil || len(carModels) != 1 || carModels[0].ID != corolla.ID {
+		t.Errorf("ListCarModels = %+v, %v, want Corolla", carModels, err)
+	}
+
+	// Cars refer to the catalog in any spelling and are stored with the
+	// catalog names.
+	req := carRequest(t, s, "corolla ", "TOYOTA", "2020", "Hybrid", 21000, engine)
+	car, err := s.Cars.CreateCar(ctx, &req)
+	if err != nil {
+		t.Fatalf("CreateCar: %v", err)
+	}
+	if car.Brand != "Toyota" || car.Name != "Corolla" || car.BrandID != toyota.ID || car.ModelID != corolla.ID {
+		t.Errorf("CreateCar = %+v, want the Corolla of Toyota", car)
+	}
+	byID := carRequest(t, s, corolla.ID.String(), toyota.ID.String(), "2021", "Hybrid", 22000, engine)
+	if _, err := s.Cars.CreateCar(ctx, &byID); err != nil {
+		t.Fatalf("CreateCar by catalog ids: %v", err)
+	}
+	byBrand, err := s.Cars.GetCarByBrand(ctx, "toyota ", false)
+	if err != nil || len(byBrand) != 2 {
+		t.Errorf("GetCarByBrand = %d cars, %v, want 2", len(byBrand), err)
+	}
+	page, err := s.Cars.ListCars(ctx, listFilter(models.CarFilter{Brand: "TOYOTA"}))
+	if err != nil || page.Total != 2 {
+		t.Errorf("ListCars by brand = %d cars, %v, want 2", page.Total, err)
+	}
+
+	unknown := req
+	unknown.Brand = "Lexus"
+	_, err = s.Cars.CreateCar(ctx, &unknown)
+	assertKind(t, "CreateCar of a brand missing from the catalog", err, apperrors.ErrForeignKey)
+	unknown = req
+	unknown.Name = "Yaris"
+	_, err = s.Cars.UpdateCar(ctx, car.ID.String(), &unknown, 0)
+	assertKind(t, "UpdateCar to a model missing from the catalog", err, apperrors.ErrForeignKey)
+
+	// A new brand keeps the model name, which has to exist under that brand.
+	lexus, err := s.Catalog.CreateBrand(ctx, &models.BrandRequest{Name: "Lexus"})
+	if err != nil {
+		t.Fatalf("CreateBrand: %v", err)
+	}
+	brand := "lexus"
+	_, err = s.Cars.PatchCar(ctx, car.ID.String(), models.CarPatch{Brand: &brand}, 0)
+	assertKind(t, "PatchCar to a brand without the model", err, apperrors.ErrForeignKey)
+	lexusCorolla := catalogModel(t, s, "Lexus", "Corolla")
+	patched, err := s.Cars.PatchCar(ctx, car.ID.String(), models.CarPatch{Brand: &brand}, 0)
+	if err != nil {
+		t.Fatalf("PatchCar brand: %v", err)
+	}
+	if patched.Brand != "Lexus" || patched.BrandID != lexus.ID || patched.ModelID != lexusCorolla.ID {
+		t.Errorf("PatchCar brand = %+v, want the Corolla of Lexus", patched)
+	}
+
+	// Renaming an entry renames its cars.
+	renamed, err := s.Catalog.UpdateBrand(ctx, "lexus", &models.BrandRequest{Name: "Lexus Motors"})
+	if err != nil {
+		t.Fatalf("UpdateBrand: %v", err)
+	}
+	if renamed.ID != lexus.ID || renamed.Slug != "lexus-motors" {
+		t.Errorf("UpdateBrand = %+v, want lexus-motors", renamed)
+	}
+	got, err := s.Cars.GetCarById(ctx, car.ID.String())
+	if err != nil || got.Brand != "Lexus Motors" || got.Version != patched.Version+1 {
+		t.Errorf("GetCarById after UpdateBrand = %+v, %v, want brand Lexus Motors at version %d", got, err, patched.Version+1)
+	}
+	_, err = s.Catalog.UpdateBrand(ctx, "lexus-motors", &models.BrandRequest{Name: "toyota"})
+	assertKind(t, "UpdateBrand to the slug of another brand", err, apperrors.ErrConflict)
+	if _, err := s.Catalog.UpdateCarModel(ctx, "lexus-motors", "corolla", &models.CarModelRequest{Name: "Corolla Hybrid"}); err != nil {
+		t.Fatalf("UpdateCarModel: %v", err)
+	}
+	if got, _ := s.Cars.GetCarById(ctx, car.ID.String()); got.Name != "Corolla Hybrid" {
+		t.Errorf("GetCarById after UpdateCarModel name = %q, want Corolla Hybrid", got.Name)
+	}
+
+	// Entries in use cannot be deleted, not even by cars in the trash.
+	_, err = s.Catalog.DeleteBrand(ctx, "lexus-motors")
+	assertKind(t, "DeleteBrand with models", err, apperrors.ErrConflict)
+	if _, err := s.Cars.DeleteCar(ctx, car.ID.String(), 0); err != nil {
+		t.Fatalf("DeleteCar: %v", err)
+	}
+	_, err = s.Catalog.DeleteCarModel(ctx, "lexus-motors", "corolla-hybrid")
+	assertKind(t, "DeleteCarModel with a car in the trash", err, apperrors.ErrConflict)
+	if _, err := s.Cars.PurgeCars(ctx, time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("PurgeCars: %v", err)
+	}
+	if _, err := s.Catalog.DeleteCarModel(ctx, "lexus-motors", "corolla-hybrid"); err != nil {
+		t.Fatalf("DeleteCarModel: %v", err)
+	}
+	if _, err := s.Catalog.DeleteBrand(ctx, lexus.ID.String()); err != nil {
+		t.Fatalf("DeleteBrand: %v", err)
+	}
+	brands, err := s.Catalog.ListBrands(ctx)
+	if err != nil || len(brands) != 1 || brands[0].ID != toyota.ID {
+		t.Errorf("ListBrands = %+v, %v, want Toyota", brands, err)
+	}
+	if entries := listAudit(t, s, models.AuditFilter{Entity: "brand", EntityID: lexus.ID}); len(entries) != 3 {
+		t.Errorf("audit of the brand has %d entries, want create, update and delete", len(entries))
+	}
+}
+
+func testEngineImport(t *testing.T, s Stores) {
+	ctx := context.Background()
+	importRows := func() []models.EngineImportRow {
+		return []models.EngineImportRow{
+			{ImportRow: models.ImportRow{Line: 1, ID: uuid.New()}, Engine: models.EngineRequest{Displacement: 2000, NoOfCyclinders: 4, CarRange: 600}},
+			{ImportRow: models.ImportRow{Line: 2, ID: uuid.New(), Err: apperrors.Validation("displacement", "displacement must be greater than zero")}},
+		}
+	}
+
+	for _, options := range []models.ImportOptions{
+		{Mode: models.ImportAllOrNothing},
+		{Mode: models.ImportBestEffort, DryRun: true},
+	} {
+		rows, err := s.Engines.EngineImport(ctx, importRows(), options)
+		if err != nil {
+			t.Fatalf("EngineImport %+v: %v", options, err)
+		}
+		_, err = s.Engines.EngineById(ctx, rows[0].ID.String())
+		assertKind(t, "EngineById of an engine that was not imported", err, apperrors.ErrNotFound)
+	}
+
+	rows, err := s.Engines.EngineImport(ctx, importRows(), models.ImportOptions{Mode: models.ImportBestEffort})
+	if err != nil {
+		t.Fatalf("EngineImport: %v", err)
+	}
+	engine, err := s.Engines.EngineById(ctx, rows[0].ID.String())
+	if err != nil {
+		t.Fatalf("EngineById of the imported engine: %v", err)
+	}
+	if engine.Displacement != 2000 || engine.NoOfCyclinders != 4 || engine.CarRange != 600 || engine.Version != 1 {
+		t.Errorf("imported engine = %+v", engine)
+	}
+	_, err = s.Engines.EngineById(ctx, rows[1].ID.String())
+	assertKind(t, "EngineById of a failed row", err, apperrors.ErrNotFound)
+}
+
+func testAudit(t *testing.T, s Stores) {
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "user-1", Username: "alice", Role: models.RoleAdmin})
+	start := time.Now().Add(-time.Second)
+
+	engine, err := s.Engines.EngineCreate(ctx, &models.EngineRequest{Displacement: 2000, NoOfCyclinders: 4, CarRange: 600})
+	if err != nil {
+		t.Fatalf("EngineCreate: %v", err)
+	}
+	req := carRequest(t, s, "Honda Civic", "Honda", "2019", "Petrol", 25000, engine)
+	car, err := s.Cars.CreateCar(ctx, &req)
+	if err != nil {
+		t.Fatalf("CreateCar: %v", err)
+	}
+	req.Price = decimal.NewFromInt(27000)
+	if _, err := s.Cars.UpdateCar(ctx, car.ID.String(), &req, car.Version); err != nil {
+		t.Fatalf("UpdateCar: %v", err)
+	}
+	// Failed mutations leave no trace.
+	_, err = s.Cars.UpdateCar(ctx, car.ID.String(), &req, car.Version)
+	assertKind(t, "UpdateCar with a stale version", err, apperrors.ErrPrecondition)
+	if _, err := s.Engines.EngineDelete(ctx, engine.EngineID.String(), 0); err != nil {
+		t.Fatalf("EngineDelete: %v", err)
+	}
+
+	entries := listAudit(t, s, models.AuditFilter{Entity: "car", EntityID: car.ID})
+	actions := map[string]models.AuditEntry{}
+	for _, entry := range entries {
+		if entry.Actor != "user-1" || entry.ActorName != "alice" || entry.Entity != "car" || entry.EntityID != car.ID {
+			t.Errorf("audit entry = %+v, want car %s by user-1", entry, car.ID)
+		}
+		actions[entry.Action] = entry
+	}
+	if len(entries) != 3 || len(actions) != 3 {
+		t.Fatalf("audit of the car has %d entries %v, want create, update and delete", len(entries), actions)
+	}
+
+	created := actions[models.AuditCreate]
+	if created.Before != nil || created.After == nil {
+		t.Errorf("create entry has before %s and after %s, want only after", created.Before, created.After)
+	}
+	updated := actions[models.AuditUpdate]
+	change, ok := updated.Changes["price"]
+	if !ok || string(change.From) != "25000" || string(change.To) != "27000" {
+		t.Errorf("update entry changes = %v, want price from 25000 to 27000", updated.Changes)
+	}
+	if _, ok := actions[models.AuditDelete].Changes["deleted_at"]; !ok {
+		t.Errorf("delete entry changes = %v, want deleted_at", actions[models.AuditDelete].Changes)
+	}
+
+	engineEntries := listAudit(t, s, models.AuditFilter{Entity: "engine", EntityID: engine.EngineID})
+	if len(engineEntries) != 2 {
+		t.Errorf("audit of the engine has %d entries, want create and delete", len(engineEntries))
+	}
+	// carRequest put the brand and the model of the car in the catalog,
+	// without a principal.
+	if got := listAudit(t, s, models.AuditFilter{}); len(got) != 7 {
+		t.Errorf("audit log has %d entries, want 7", len(got))
+	}
+	if got := listAudit(t, s, models.AuditFilter{Actor: "someone-else"}); len(got) != 0 {
+		t.Errorf("audit of another actor has %d entries, want 0", len(got))
+	}
+	if got := listAudit(t, s, models.AuditFilter{From: start, To: time.Now().Add(time.Second)}); len(got) != 7 {
+		t.Errorf("audit within the test has %d entries, want 7", len(got))
+	}
+	if got := listAudit(t, s, models.AuditFilter{To: start}); len(got) != 0 {
+		t.Errorf("audit before the test has %d entries, want 0", len(got))
+	}
+
+	// Without a principal the mutation is attributed to the system.
+	if _, err := s.Engines.EngineCreate(context.Background(), &models.EngineRequest{Displacement: 1500, NoOfCyclinders: 3, CarRange: 500}); err != nil {
+		t.Fatalf("EngineCreate: %v", err)
+	}
+	if got := listAudit(t, s, models.AuditFilter{Actor: store.SystemActor}); len(got) != 3 {
+		t.Errorf("audit of the system has %d entries, want 3", len(got))
+	}
+}
+
+func testAuditPagination(t *testing.T, s Stores) {
+	ctx := context.Background()
+	engine := createEngine(t, s, 2000, 4)
+	for i := 0; i < 4; i++ {
+		if _, err := s.Engines.EnginePatch(ctx, engine.EngineID.String(), models.EnginePatch{CarRange: &[]int64{int64(700 + i)}[0]}, 0); err != nil {
+			t.Fatalf("EnginePatch: %v", err)
+		}
+	}
+
+	filter := models.AuditFilter{Entity: "engine", EntityID: engine.EngineID, Limit: 2}
+	seen := map[uuid.UUID]bool{}
+	var previous time.Time
+	for pages := 1; ; pages++ {
+		if err := models.ValidateAuditFilter(&filter); err != nil {
+			t.Fatalf("ValidateAuditFilter: %v", err)
+		}
+		page, err := s.Audit.ListAuditEntries(ctx, filter)
+		if err != nil {
+			t.Fatalf("ListAuditEntries: %v", err)
+		}
+		for _, entry := range page.Entries {
+			if seen[entry.ID] {
+				t.Errorf("page %d repeats entry %s", pages, entry.ID)
+			}
+			if !previous.IsZero() && entry.CreatedAt.After(previous) {
+				t.Errorf("page %d is not ordered newest first", pages)
+			}
+			seen[entry.ID] = true
+			previous = entry.CreatedAt
+		}
+		if page.NextCursor == "" {
+			if pages != 3 {
+				t.Errorf("got %d pages, want 3", pages)
+			}
+			break
+		}
+		filter.Cursor = page.NextCursor
+	}
+	if len(seen) != 5 {
+		t.Errorf("pages returned %d entries, want 5", len(seen))
+	}
+}
+
+func testGetCarByBrand(t *testing.T, s Stores) {
+	ctx := context.Background()
+	engine := createEngine(t, s, 2000, 4)
+	civic := createCar(t, s, "Honda Civic", "Honda", "2019", 25000, engine)
+	accord := createCar(t, s, "Honda Accord", "Honda", "2020", 30000, engine)
+	createCar(t, s, "Toyota Corolla", "Toyota", "2019", 22000, engine)
+
+	cars, err := s.Cars.GetCarByBrand(ctx, "Honda", false)
+	if err != nil {
+		t.Fatalf("GetCarByBrand: %v", err)
+	}
+	assertIDs(t, "GetCarByBrand", cars, civic, accord)
+	for _, car := range cars {
+		if car.Brand != "Honda" || car.FuelType == "" {
+			t.Errorf("GetCarByBrand returned %+v", car)
+		}
+		if car.Engine.EngineID != engine.EngineID {
+			t.Errorf("GetCarByBrand engine id = %v, want %v", car.Engine.EngineID, engine.EngineID)
+		}
+	}
+
+	cars, err = s.Cars.GetCarByBrand(ctx, "Honda", true)
+	if err != nil {
+		t.Fatalf("GetCarByBrand with engine: %v", err)
+	}
+	assertIDs(t, "GetCarByBrand with engine", cars, civic, accord)
+	for _, car := range cars {
+		if car.Brand != "Honda" || car.Engine != engine {
+			t.Errorf("GetCarByBrand with engine returned %+v", car)
+		}
+	}
+
+	cars, err = s.Cars.GetCarByBrand(ctx, "Ford", false)
+	if err != nil {
+		t.Fatalf("GetCarByBrand for unknown brand: %v", err)
+	}
+	if len(cars) != 0 {
+		t.Errorf("GetCarByBrand for unknown brand returned %d cars", len(cars))
+	}
+}
+
+func testListCarsFilters(t *testing.T, s Stores) {
+	ctx := context.Background()
+	small := createEngine(t, s, 1600, 4)
+	big := createEngine(t, s, 3000, 6)
+	civic := createCar(t, s, "Honda Civic", "Honda", "2019", 25000, small)
+	accord := createCar(t, s, "Honda Accord", "Honda", "2022", 32000, big)
+	corolla := createCar(t, s, "Toyota Corolla", "Toyota", "2015", 18000, small)
+
+	tests := []struct {
+		name   string
+		filter models.CarFilter
+		want   []models.Car
+	}{
+		{"all", models.CarFilter{}, []models.Car{civic, accord, corolla}},
+		{"brand", models.CarFilter{Brand: "Honda"}, []models.Car{civic, accord}},
+		{"fuel type", models.CarFilter{FuelType: "Diesel"}, nil},
+		{"year range", models.CarFilter{YearFrom: 2016, YearTo: 2020}, []models.Car{civic}},
+		{"price range", models.CarFilter{PriceMin: decimal.NewFromInt(20000), PriceMax: decimal.NewFromInt(30000)}, []models.Car{civic}},
+		{"displacement", models.CarFilter{DisplacementMin: 2000}, []models.Car{accord}},
+		{"cylinders", models.CarFilter{Cylinders: 4}, []models.Car{civic, corolla}},
+	}
+	for _, tt := range tests {
+		page, err := s.Cars.ListCars(ctx, listFilter(tt.filter))
+		if err != nil {
+			t.Fatalf("ListCars %s: %v", tt.name, err)
+		}
+		assertIDs(t, "ListCars "+tt.name, page.Cars, tt.want...)
+		if page.Total != int64(len(tt.want)) {
+			t.Errorf("ListCars %s total = %d, want %d", tt.name, page.Total, len(tt.want))
+		}
+	}
+
+	page, err := s.Cars.ListCars(ctx, listFilter(models.CarFilter{Brand: "Toyota", IsEngine: true}))
+	if err != nil {
+		t.Fatalf("ListCars with engine: %v", err)
+	}
+	if len(page.Cars) != 1 || page.Cars[0].Engine != small {
+		t.Errorf("ListCars with engine = %+v, want engine %+v", page.Cars, small)
+	}
+}
+
+func testListCarsOrdering(t *testing.T, s Stores) {
+	ctx := context.Background()
+	engine := createEngine(t, s, 2000, 4)
+	a := createCar(t, s, "A", "Honda", "2019", 30000, engine)
+	b := createCar(t, s, "B", "Honda", "2015", 10000, engine)
+	c := createCar(t, s, "C", "Honda", "2022", 20000, engine)
+
+	tests := []struct {
+		sortBy string
+		desc   bool
+		want   []models.Car
+	}{
+		{"price", false, []models.Car{b, c, a}},
+		{"price", true, []models.Car{a, c, b}},
+		{"year", false, []models.Car{b, a, c}},
+		{"name", true, []models.Car{c, b, a}},
+	}
+	for _, tt := range tests {
+		filter := listFilter(models.CarFilter{SortBy: tt.sortBy, SortDesc: tt.desc})
+		page, err := s.Cars.ListCars(ctx, filter)
+		if err != nil {
+			t.Fatalf("ListCars sorted by %s: %v", tt.sortBy, err)
+		}
+		assertOrder(t, fmt.Sprintf("ListCars sorted by %s desc=%v", tt.sortBy, tt.desc), page.Cars, tt.want)
+	}
+}
+
+func testListCarsPagination(t *testing.T, s Stores) {
+	ctx := context.Background()
+	engine := createEngine(t, s, 2000, 4)
+
+	// Equal prices make the id the only thing that separates pages.
+	var want []models.Car
+	for i := 0; i < 7; i++ {
+		want = append(want, createCar(t, s, fmt.Sprintf("Car %d", i), "Honda", "2019", float64(10000+1000*(i%3)), engine))
+	}
+
+	for _, desc := range []bool{false, true} {
+		filter := listFilter(models.CarFilter{SortBy: "price", SortDesc: desc, Limit: 3})
+		var seen []models.Car
+		for pages := 0; ; pages++ {
+			if pages > len(want) {
+				t.Fatal("ListCars did not stop paging")
+			}
+			page, err := s.Cars.ListCars(ctx, filter)
+			if err != nil {
+				t.Fatalf("ListCars page %d: %v", pages, err)
+			}
+			if page.Total != int64(len(want)) {
+				t.Errorf("ListCars page %d total = %d, want %d", pages, page.Total, len(want))
+			}
+			if len(page.Cars) > 3 {
+				t.Fatalf("ListCars page %d has %d cars, limit is 3", pages, len(page.Cars))
+			}
+			seen = append(seen, page.Cars...)
+			if page.NextCursor == "" {
+				break
+			}
+			filter.Cursor = page.NextCursor
+		}
+
+		assertIDs(t, "ListCars pages", seen, want...)
+		for i := 1; i < len(seen); i++ {
+			prev, cur := seen[i-1].Price, seen[i].Price
+			if (!desc && prev.GreaterThan(cur)) || (desc && prev.LessThan(cur)) {
+				t.Errorf("ListCars pages out of order at %d: %v then %v (desc=%v)", i, prev, cur, desc)
+			}
+		}
+	}
+
+	filter := listFilter(models.CarFilter{SortBy: "price", Limit: 3})
+	page, err := s.Cars.ListCars(ctx, filter)
+	if err != nil {
+		t.Fatalf("ListCars: %v", err)
+	}
+	filter.SortBy = "name"
+	filter.Cursor = page.NextCursor
+	_, err = s.Cars.ListCars(ctx, filter)
+	assertKind(t, "ListCars with a cursor for another sort", err, apperrors.ErrValidation)
+}
+
+func testExportCars(t *testing.T, s Stores) {
+	ctx := context.Background()
+	engine := createEngine(t, s, 2000, 4)
+	a := createCar(t, s, "A", "Honda", "2019", 30000, engine)
+	b := createCar(t, s, "B", "Honda", "2015", 10000, engine)
+	createCar(t, s, "C", "Toyota", "2022", 20000, engine)
+
+	// The limit of the listing does not apply to an export.
+	filter := listFilter(models.CarFilter{Brand: "Honda", SortBy: "price", Limit: 1})
+	var got []models.Car
+	err := s.Cars.ExportCars(ctx, filter, func(car models.Car) error {
+		got = append(got, car)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ExportCars: %v", err)
+	}
+	assertOrder(t, "ExportCars", got, []models.Car{b, a})
+	if got[0].Engine.Displacement != 0 {
+		t.Errorf("ExportCars without isEngine returned engine details %+v", got[0].Engine)
+	}
+
+	filter.IsEngine = true
+	got = nil
+	err = s.Cars.ExportCars(ctx, filter, func(car models.Car) error {
+		got = append(got, car)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ExportCars with isEngine: %v", err)
+	}
+	if len(got) != 2 || got[0].Engine.Displacement != engine.Displacement {
+		t.Errorf("ExportCars with isEngine returned %+v, want the engine of %v", got, engine.EngineID)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err = s.Cars.ExportCars(ctx, filter, func(models.Car) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("ExportCars after an error = %v with %d calls, want %v after 1", err, calls, stop)
+	}
+}
+
+func testSearchCars(t *testing.T, s Stores) {
+	ctx := context.Background()
+	engine := createEngine(t, s, 2000, 4)
+	civic := createCar(t, s, "Honda Civic", "Honda", "2019", 25000, engine)
+	createCar(t, s, "Ford Mustang", "Ford", "2024", 40000, engine)
+
+	results, err := s.Cars.SearchCars(ctx, "civic hybrid 2019", 10)
+	if err != nil {
+		t.Fatalf("SearchCars: %v", err)
+	}
+	if len(results) == 0 || results[0].Car.ID != civic.ID {
+		t.Fatalf("SearchCars = %+v, want %v first", results, civic.ID)
+	}
+	if results[0].Highlights["name"] != "Honda "+models.HighlightStart+"Civic"+models.HighlightStop {
+		t.Errorf("SearchCars name highlight = %q", results[0].Highlights["name"])
+	}
+	for i := 1; i < len(results); i++ {
+		if results[i].Rank > results[i-1].Rank {
+			t.Errorf("SearchCars results are not ordered by rank: %v", results)
+		}
+	}
+
+	// Highlights are HTML, so the text around the matches is escaped.
+	createCar(t, s, `Jazz <img src=x onerror="alert(1)">`, "Honda", "2020", 18000, engine)
+	results, err = s.Cars.SearchCars(ctx, "jazz", 10)
+	if err != nil {
+		t.Fatalf("SearchCars with markup: %v", err)
+	}
+	want := models.HighlightStart + "Jazz" + models.HighlightStop + ` &lt;img src=x onerror=&#34;alert(1)&#34;&gt;`
+	if len(results) == 0 || results[0].Highlights["name"] != want {
+		t.Errorf("SearchCars with markup = %+v, want name highlight %q", results, want)
+	}
+
+	results, err = s.Cars.SearchCars(ctx, "lamborghini", 10)
+	if err != nil {
+		t.Fatalf("SearchCars without matches: %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("SearchCars without matches = %+v", results)
+	}
+}
+
+func testUsers(t *testing.T, s Stores) {
+	ctx := context.Background()
+
+	created, err := s.Users.CreateUser(ctx, &models.User{Username: "Dealer", PasswordHash: "hash", Role: models.RoleDealer})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if created.ID == uuid.Nil || created.Username != "Dealer" || created.Role != models.RoleDealer || created.PasswordHash != "hash" {
+		t.Errorf("CreateUser = %+v", created)
+	}
+
+	got, err := s.Users.GetUserByUsername(ctx, "dealer")
+	if err != nil {
+		t.Fatalf("GetUserByUsername: %v", err)
+	}
+	if got.ID != created.ID {
+		t.Errorf("GetUserByUsername id = %v, want %v", got.ID, created.ID)
+	}
+
+	_, err = s.Users.CreateUser(ctx, &models.User{Username: "DEALER", PasswordHash: "hash", Role: models.RoleViewer})
+	assertKind(t, "CreateUser with a taken username", err, apperrors.ErrConflict)
+	_, err = s.Users.GetUserByUsername(ctx, "nobody")
+	assertKind(t, "GetUserByUsername", err, apperrors.ErrNotFound)
+}
+
+func testAPIKeys(t *testing.T, s Stores) {
+	ctx := context.Background()
+
+	created, err := s.APIKeys.CreateAPIKey(ctx, &models.APIKey{
+		Name: "partner", Prefix: "aaaa", KeyHash: strings.Repeat("a", 64), Scopes: []string{models.ScopeCarsRead},
+	})
+	if err != nil {
+		t.Fatalf("CreateAPIKey: %v", err)
+	}
+	if created.ID == uuid.Nil || created.Revoked() || created.LastUsedAt != nil ||
+		len(created.Scopes) != 1 || created.Scopes[0] != models.ScopeCarsRead {
+		t.Errorf("CreateAPIKey = %+v", created)
+	}
+
+	got, err := s.APIKeys.GetAPIKeyByHash(ctx, strings.Repeat("a", 64))
+	if err != nil {
+		t.Fatalf("GetAPIKeyByHash: %v", err)
+	}
+	if got.ID != created.ID {
+		t.Errorf("GetAPIKeyByHash id = %v, want %v", got.ID, created.ID)
+	}
+
+	usedAt := time.Now().UTC().Truncate(time.Second)
+	if err := s.APIKeys.TouchAPIKey(ctx, created.ID.String(), usedAt); err != nil {
+		t.Fatalf("TouchAPIKey: %v", err)
+	}
+	rotated, err := s.APIKeys.RotateAPIKey(ctx, created.ID.String(), "bbbb", strings.Repeat("b", 64))
+	if err != nil {
+		t.Fatalf("RotateAPIKey: %v", err)
+	}
+	if rotated.Prefix != "bbbb" || rotated.LastUsedAt == nil || !rotated.LastUsedAt.Equal(usedAt) {
+		t.Errorf("RotateAPIKey = %+v", rotated)
+	}
+	_, err = s.APIKeys.GetAPIKeyByHash(ctx, strings.Repeat("a", 64))
+	assertKind(t, "GetAPIKeyByHash with the rotated hash", err, apperrors.ErrNotFound)
+
+	revoked, err := s.APIKeys.RevokeAPIKey(ctx, created.ID.String())
+	if err != nil {
+		t.Fatalf("RevokeAPIKey: %v", err)
+	}
+	if !revoked.Revoked() {
+		t.Errorf("RevokeAPIKey = %+v, want revoked", revoked)
+	}
+	_, err = s.APIKeys.RotateAPIKey(ctx, created.ID.String(), "cccc", strings.Repeat("c", 64))
+	assertKind(t, "RotateAPIKey on a revoked key", err, apperrors.ErrNotFound)
+
+	keys, err := s.APIKeys.ListAPIKeys(ctx)
+	if err != nil {
+		t.Fatalf("ListAPIKeys: %v", err)
+	}
+	if len(keys) != 1 || keys[0].ID != created.ID || !keys[0].Revoked() {
+		t.Errorf("ListAPIKeys = %+v", keys)
+	}
+	_, err = s.APIKeys.RevokeAPIKey(ctx, uuid.NewString())
+	assertKind(t, "RevokeAPIKey", err, apperrors.ErrNotFound)
+}
+
+func createEngine(t *testing.T, s Stores, displacement, cylinders int64) models.Engine {
+	t.Helper()
+	engine, err := s.Engines.EngineCreate(context.Background(), &models.EngineRequest{
+		Displacement:   displacement,
+		NoOfCyclinders: cylinders,
+		CarRange:       500,
+	})
+	if err != nil {
+		t.Fatalf("EngineCreate: %v", err)
+	}
+	return engine
+}
+
+func createCar(t *testing.T, s Stores, name, brand, year string, price float64, engine models.Engine) models.Car {
+	t.Helper()
+	req := carRequest(t, s, name, brand, year, "Hybrid", price, engine)
+	car, err := s.Cars.CreateCar(context.Background(), &req)
+	if err != nil {
+		t.Fatalf("CreateCar: %v", err)
+	}
+	return car
+}
+
+// carRequest builds a car request and puts its brand and model in the
+// catalog, which cars have to refer to.
+func carRequest(t *testing.T, s Stores, name, brand, year, fuelType string, price float64, engine models.Engine) models.CarRequest {
+	t.Helper()
+	catalogModel(t, s, brand, name)
+	return models.CarRequest{
+		Name:     name,
+		Year:     year,
+		Brand:    brand,
+		FuelType: fuelType,
+		Engine:   engine,
+		Price:    decimal.NewFromFloat(price),
+		Currency: models.DefaultCurrency,
+	}
+}
+
+// catalogModel returns the model with the given name of a brand, adding the
+// brand and the model to the catalog when they are missing.
+func catalogModel(t *testing.T, s Stores, brand, name string) models.CarModel {
+	t.Helper()
+	ctx := context.Background()
+	catalogBrand, err := s.Catalog.GetBrand(ctx, brand)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		catalogBrand, err = s.Catalog.CreateBrand(ctx, &models.BrandRequest{Name: brand})
+	}
+	if err != nil {
+		t.Fatalf("catalog brand %q: %v", brand, err)
+	}
+	model, err := s.Catalog.GetCarModel(ctx, catalogBrand.ID.String(), name)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		model, err = s.Catalog.CreateCarModel(ctx, catalogBrand.ID.String(), &models.CarModelRequest{Name: name})
+	}
+	if err != nil {
+		t.Fatalf("catalog model %q of %q: %v", name, brand, err)
+	}
+	return model
+}
+
+// priceStats builds the expected statistics of a group. fmt prints decimals
+// in their shortest form, so amounts compare the same whatever the scale the
+// store returns them in.
+func priceStats(group, currency string, count int64, average, min, max string) models.PriceStats {
+	return models.PriceStats{
+		Group:    group,
+		Currency: currency,
+		Count:    count,
+		Average:  decimal.RequireFromString(average),
+		Min:      decimal.RequireFromString(min),
+		Max:      decimal.RequireFromString(max),
+	}
+}
+
+// listFilter validates filter the way the service does before it reaches
+// the store.
+func listFilter(filter models.CarFilter) models.CarFilter {
+	if err := models.ValidateCarFilter(&filter); err != nil {
+		panic(err)
+	}
+	return filter
+}
+
+func listAudit(t *testing.T, s Stores, filter models.AuditFilter) []models.AuditEntry {
+	t.Helper()
+	filter.Limit = models.MaxPageSize
+	if err := models.ValidateAuditFilter(&filter); err != nil {
+		t.Fatalf("ValidateAuditFilter: %v", err)
+	}
+	page, err := s.Audit.ListAuditEntries(context.Background(), filter)
+	if err != nil {
+		t.Fatalf("ListAuditEntries: %v", err)
+	}
+	return page.Entries
+}
+
+func assertKind(t *testing.T, what string, err, kind error) {
+	t.Helper()
+	if !errors.Is(err, kind) {
+		t.Errorf("%s: error = %v, want %v", what, err, kind)
+	}
+}
+
+func assertCar(t *testing.T, what string, got models.Car, want models.CarRequest) {
+	t.Helper()
+	if got.VIN != want.VIN || got.Name != want.Name || got.Year != want.Year || got.Brand != want.Brand ||
+		got.FuelType != want.FuelType || !got.Price.Equal(want.Price) || got.Currency != want.Currency ||
+		got.Engine.EngineID != want.Engine.EngineID {
+		t.Errorf("%s = %+v, want %+v", what, got, want)
+	}
+}
+
+func assertIDs(t *testing.T, what string, got []models.Car, want ...models.Car) {
+	t.Helper()
+	ids := map[uuid.UUID]int{}
+	for _, car := range got {
+		ids[car.ID]++
+	}
+	for _, car := range want {
+		if ids[car.ID] != 1 {
+			t.Errorf("%s returned car %v %d times, want once", what, car.ID, ids[car.ID])
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("%s returned %d cars, want %d", what, len(got), len(want))
+	}
+}
+
+func assertOrder(t *testing.T, what string, got, want []models.Car) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s returned %d cars, want %d", what, len(got), len(want))
+	}
+	for i := range want {
+		if got[i].ID != want[i].ID {
+			t.Errorf("%s[%d] = %s, want %s", what, i, got[i].Name, want[i].Name)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/ayushi-khandal09/carZone/models"
+	"github.com/ayushi-khandal09/carZone/store"
+	"github.com/google/uuid"
+)
+
+type UserStore struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) *UserStore {
+	return &UserStore{db: db}
+}
+
+// GetUserByUsername looks the user up case-insensitively.
+func (u UserStore) GetUserByUsername(ctx context.Context, username string) (models.User, error) {
+	var user models.User
+
+	err := u.db.QueryRowContext(ctx,
+		"SELECT id, username, password_hash, role, created_at, updated_at FROM users WHERE LOWER(username) = LOWER($1)", username).Scan(
+		&user.ID, &user.Username, &user.PasswordHash, &user.Role, &user.CreatedAt, &user.UpdatedAt,
+	)
+	if err != nil {
+		return user, store.MapError(err, "user")
+	}
+	return user, nil
+}
+
+func (u UserStore) CreateUser(ctx context.Context, user *models.User) (models.User, error) {
+	var created models.User
+	createdAt := time.Now()
+
+	err := u.db.QueryRowContext(ctx,
+		`INSERT INTO users (id, username, password_hash, role, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, username, password_hash, role, created_at, updated_at`,
+		uuid.New(), user.Username, user.PasswordHash, user.Role, createdAt, createdAt,
+	).Scan(
+		&created.ID, &created.Username, &created.PasswordHash, &created.Role, &created.CreatedAt, &created.UpdatedAt,
+	)
+	if err != nil {
+		return created, store.MapError(err, "user")
+	}
+	return created, nil
+}
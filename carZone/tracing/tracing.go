@@ -0,0 +1,72 @@
+// Package tracing sets up OpenTelemetry tracing with W3C trace context
+// propagation and holds the helpers the layers use to record spans.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ayushi-khandal09/carZone/apperrors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// NewExporter returns the span exporter called name, using the values of
+// OTEL_TRACES_EXPORTER: "otlp" sends spans over OTLP/HTTP to the endpoint in
+// OTEL_EXPORTER_OTLP_ENDPOINT, "console" or "stdout" prints them to w, and ""
+// or "none" disables export while still propagating trace context.
+func NewExporter(ctx context.Context, name string, w io.Writer) (sdktrace.SpanExporter, error) {
+	switch strings.ToLower(name) {
+	case "", "none":
+		return nil, nil
+	case "otlp":
+		return otlptracehttp.New(ctx)
+	case "console", "stdout":
+		return stdouttrace.New(stdouttrace.WithWriter(w))
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q, expected otlp, console or none", name)
+	}
+}
+
+// NewProvider returns a tracer provider for serviceName that batches spans to
+// exporter, or records none when exporter is nil. It is installed as the
+// global provider together with the W3C traceparent and baggage propagators.
+// Tests can pass a tracetest.InMemoryExporter and call ForceFlush before
+// looking at the spans.
+func NewProvider(exporter sdktrace.SpanExporter, serviceName string) *sdktrace.TracerProvider {
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	}
+	if exporter != nil {
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	}
+
+	provider := sdktrace.NewTracerProvider(opts...)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+	return provider
+}
+
+// End records err on span, marking the span as failed for server errors only
+// since not found and validation errors are expected outcomes, and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		if apperrors.As(err).Kind == apperrors.ErrInternal {
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+	span.End()
+}
@@ -0,0 +1,99 @@
+// Package vin decodes vehicle identification numbers offline. Manufacturers
+// are looked up in a table of world manufacturer identifiers (WMI) embedded
+// in the binary, so decoding needs no outside service.
+package vin
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"strconv"
+	"strings"
+
+	"github.com/ayushi-khandal09/carZone/models"
+)
+
+//go:embed wmi.csv
+var wmiTable string
+
+// Manufacturer is an entry of the WMI table.
+type Manufacturer struct {
+	Name    string
+	Brand   string
+	Country string
+}
+
+var manufacturers = loadManufacturers(wmiTable)
+
+func loadManufacturers(table string) map[string]Manufacturer {
+	records, err := csv.NewReader(strings.NewReader(table)).ReadAll()
+	if err != nil {
+		panic("vin: reading wmi.csv: " + err.Error())
+	}
+	byWMI := make(map[string]Manufacturer, len(records)-1)
+	for _, record := range records[1:] {
+		byWMI[record[0]] = Manufacturer{Name: record[1], Brand: record[2], Country: record[3]}
+	}
+	return byWMI
+}
+
+// yearCodes are the model year codes of position 10, starting with 1980.
+// They repeat every 30 years.
+const yearCodes = "ABCDEFGHJKLMNPRSTVWXY123456789"
+
+// Info is what a VIN tells about a car. Manufacturer is empty when the WMI
+// is not in the table, and ModelYear is 0 when position 10 holds no year.
+type Info struct {
+	VIN          string
+	WMI          string
+	Manufacturer Manufacturer
+	ModelYear    int
+	// Plant is the code of the assembly plant, which only means something
+	// together with the manufacturer.
+	Plant  string
+	Serial string
+}
+
+// Decode validates vin with models.ValidateVIN and splits it into its parts.
+//
+// The model year code at position 10 is read in the 1980 to 2009 cycle when
+// position 7 is a digit and in the 2010 to 2039 cycle when it is a letter,
+// as North American passenger cars encode it.
+func Decode(vin string) (Info, error) {
+	vin = models.NormalizeVIN(vin)
+	if err := models.ValidateVIN(vin); err != nil {
+		return Info{}, err
+	}
+	info := Info{
+		VIN:          vin,
+		WMI:          vin[:3],
+		Manufacturer: manufacturers[vin[:3]],
+		Plant:        vin[10:11],
+		Serial:       vin[11:],
+	}
+	if i := strings.IndexByte(yearCodes, vin[9]); i >= 0 {
+		info.ModelYear = 1980 + i
+		if vin[6] >= 'A' && vin[6] <= 'Z' {
+			info.ModelYear += 30
+		}
+	}
+	return info, nil
+}
+
+// Prefill fills in the brand and year of a car from its VIN when they are
+// left out. Nothing is filled in from a VIN that does not decode, which
+// models.ValidateRequest reports.
+func Prefill(car *models.CarRequest) {
+	if car.VIN == "" {
+		return
+	}
+	info, err := Decode(car.VIN)
+	if err != nil {
+		return
+	}
+	if car.Brand == "" {
+		car.Brand = info.Manufacturer.Brand
+	}
+	if car.Year == "" && info.ModelYear != 0 {
+		car.Year = strconv.Itoa(info.ModelYear)
+	}
+}
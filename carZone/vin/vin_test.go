@@ -0,0 +1,129 @@
+package vin
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ayushi-khandal09/carZone/apperrors"
+	"github.com/ayushi-khandal09/carZone/models"
+)
+
+func TestDecode(t *testing.T) {
+	honda := Manufacturer{Name: "Honda of America", Brand: "Honda", Country: "United States"}
+	tests := []struct {
+		name string
+		vin  string
+		want Info
+	}{
+		{
+			name: "digit at position 7 reads the 1980 cycle",
+			vin:  "1HGCM82633A004352",
+			want: Info{VIN: "1HGCM82633A004352", WMI: "1HG", Manufacturer: honda, ModelYear: 2003, Plant: "A", Serial: "004352"},
+		},
+		{
+			name: "letter at position 7 reads the 2010 cycle",
+			vin:  "1HGCM8A613A004352",
+			want: Info{VIN: "1HGCM8A613A004352", WMI: "1HG", Manufacturer: honda, ModelYear: 2033, Plant: "A", Serial: "004352"},
+		},
+		{
+			name: "letter year code",
+			vin:  "5YJ3E1EA2KF317000",
+			want: Info{
+				VIN:          "5YJ3E1EA2KF317000",
+				WMI:          "5YJ",
+				Manufacturer: Manufacturer{Name: "Tesla", Brand: "Tesla", Country: "United States"},
+				ModelYear:    2019,
+				Plant:        "F",
+				Serial:       "317000",
+			},
+		},
+		{
+			name: "unknown WMI",
+			vin:  "1M8GDM9AXKP042788",
+			want: Info{VIN: "1M8GDM9AXKP042788", WMI: "1M8", ModelYear: 1989, Plant: "P", Serial: "042788"},
+		},
+		{
+			name: "no model year",
+			vin:  "ZZZZZZZZ9ZZZZZZZZ",
+			want: Info{VIN: "ZZZZZZZZ9ZZZZZZZZ", WMI: "ZZZ", Plant: "Z", Serial: "ZZZZZZ"},
+		},
+		{
+			name: "normalized first",
+			vin:  " 1hgcm82633a004352 ",
+			want: Info{VIN: "1HGCM82633A004352", WMI: "1HG", Manufacturer: honda, ModelYear: 2003, Plant: "A", Serial: "004352"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode(tt.vin)
+			if err != nil {
+				t.Fatalf("Decode(%q): %v", tt.vin, err)
+			}
+			if got != tt.want {
+				t.Errorf("Decode(%q) = %+v, want %+v", tt.vin, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	for _, vin := range []string{"", "1HGCM82633A00435", "1HGCM82643A004352", "1HGCM82633A00435O"} {
+		if _, err := Decode(vin); !errors.Is(err, apperrors.ErrValidation) {
+			t.Errorf("Decode(%q) = %v, want a validation error", vin, err)
+		}
+	}
+}
+
+func TestPrefill(t *testing.T) {
+	tests := []struct {
+		name string
+		car  models.CarRequest
+		want models.CarRequest
+	}{
+		{
+			name: "fills in brand and year",
+			car:  models.CarRequest{VIN: "1HGCM82633A004352"},
+			want: models.CarRequest{VIN: "1HGCM82633A004352", Brand: "Honda", Year: "2003"},
+		},
+		{
+			name: "keeps the brand and year given",
+			car:  models.CarRequest{VIN: "1HGCM82633A004352", Brand: "Acura", Year: "2004"},
+			want: models.CarRequest{VIN: "1HGCM82633A004352", Brand: "Acura", Year: "2004"},
+		},
+		{
+			name: "keeps the brand given and fills in the year",
+			car:  models.CarRequest{VIN: "1HGCM82633A004352", Brand: "Acura"},
+			want: models.CarRequest{VIN: "1HGCM82633A004352", Brand: "Acura", Year: "2003"},
+		},
+		{
+			name: "unknown WMI leaves the brand out",
+			car:  models.CarRequest{VIN: "1M8GDM9AXKP042788"},
+			want: models.CarRequest{VIN: "1M8GDM9AXKP042788", Year: "1989"},
+		},
+		{
+			name: "no model year leaves the year out",
+			car:  models.CarRequest{VIN: "ZZZZZZZZ9ZZZZZZZZ"},
+			want: models.CarRequest{VIN: "ZZZZZZZZ9ZZZZZZZZ"},
+		},
+		{
+			name: "invalid VIN fills in nothing",
+			car:  models.CarRequest{VIN: "1HGCM82643A004352"},
+			want: models.CarRequest{VIN: "1HGCM82643A004352"},
+		},
+		{
+			name: "no VIN",
+			car:  models.CarRequest{Brand: "Honda"},
+			want: models.CarRequest{Brand: "Honda"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			car := tt.car
+			Prefill(&car)
+			if car.VIN != tt.want.VIN || car.Brand != tt.want.Brand || car.Year != tt.want.Year {
+				t.Errorf("Prefill = vin %q brand %q year %q, want vin %q brand %q year %q",
+					car.VIN, car.Brand, car.Year, tt.want.VIN, tt.want.Brand, tt.want.Year)
+			}
+		})
+	}
+}